@@ -8,6 +8,10 @@ type Config struct {
 	Auth      AuthConfig
 	Telegram  TelegramConfig
 	RateLimit RateLimitConfig
+	Loot      LootConfig  `json:",optional"`
+	Shop      ShopConfig  `json:",optional"`
+	Bark      BarkConfig  `json:",optional"`
+	Email     EmailConfig `json:",optional"`
 }
 
 type RateLimitConfig struct {
@@ -15,6 +19,30 @@ type RateLimitConfig struct {
 	MaxDailyRegisters int `json:",default=10"` // Per IP per day
 }
 
+type LootConfig struct {
+	Seed int64 `json:",optional"` // Non-zero makes drop and craft rolls reproducible (tests)
+}
+
+type ShopConfig struct {
+	RefundWindowHours int `json:",optional"` // How long purchases stay refundable, defaults to 24
+	ExpiryNoticeHours int `json:",optional"` // How early expiring items are announced, defaults to 24
+}
+
+type BarkConfig struct {
+	ServerURL string `json:",optional"` // Used when a user sets no server, defaults to https://api.day.app
+}
+
+type EmailConfig struct {
+	Enabled  bool   `json:",optional"`
+	Host     string `json:",optional"`
+	Port     int    `json:",optional"` // Defaults to 587
+	Username string `json:",optional"`
+	Password string `json:",optional"`
+	From     string `json:",optional"` // e.g. "Life System <noreply@example.com>"
+	TLS      bool   `json:",optional"` // Implicit TLS (port 465); otherwise STARTTLS when offered
+	BaseURL  string `json:",optional"` // Public address of the API, used for unsubscribe links
+}
+
 type DatabaseConfig struct {
 	Path string
 }
@@ -25,6 +53,9 @@ type AuthConfig struct {
 }
 
 type TelegramConfig struct {
-	BotToken string
-	Enabled  bool
+	BotToken      string
+	Enabled       bool
+	WebhookURL    string `json:",optional"` // Public base address of the API; when set, updates are posted to <WebhookURL>/api/telegram/webhook instead of long polling
+	WebhookSecret string `json:",optional"` // Required with WebhookURL: 1-256 of A-Z a-z 0-9 _ -, checked against X-Telegram-Bot-Api-Secret-Token
+	APIEndpoint   string `json:",optional"` // Bot API endpoint format, e.g. a self-hosted Bot API server; defaults to https://api.telegram.org/bot%s/%s
 }
@@ -0,0 +1,338 @@
+package effect
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+)
+
+// NewDefaultRegistry returns a registry with every built-in effect.
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(noEffect{})
+	r.Register(fatigueRestore{})
+	r.Register(attributeBoost{})
+	// Single-attribute keys kept for items created before attribute_boost.
+	for _, key := range realm.AttrKeys {
+		r.Register(attributeBoost{fixedTarget: key})
+	}
+	r.Register(spiritStoneGain{})
+	r.Register(breakthroughAssist{})
+	r.Register(taskRewardMultiplier{})
+	r.Register(streakShield{})
+	r.Register(buffEffect{modifier: model.BuffModifierSpiritStones})
+	r.Register(buffEffect{modifier: model.BuffModifierAttrGain})
+	r.Register(buffEffect{modifier: model.BuffModifierFatigueCost})
+	return r
+}
+
+func attrName(key string) string {
+	if info, ok := realm.AttrDisplay[key]; ok {
+		return info.Name
+	}
+	return key
+}
+
+// noEffect is for real-world rewards that change no numbers.
+type noEffect struct{}
+
+func (noEffect) Info() Info {
+	return Info{Key: "none", Name: "无效果", Description: "使用后无数值变化，适合实物奖励"}
+}
+
+func (e noEffect) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e noEffect) Describe(spec model.EffectSpec) string {
+	return e.Info().Description
+}
+
+func (noEffect) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	return "", nil
+}
+
+// fatigueRestore lowers fatigue, never below zero.
+type fatigueRestore struct{}
+
+func (fatigueRestore) Info() Info {
+	return Info{Key: "fatigue_restore", Name: "恢复精力", Description: "降低 %d 点疲劳", NeedsValue: true}
+}
+
+func (e fatigueRestore) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e fatigueRestore) Describe(spec model.EffectSpec) string {
+	return describeValue(e.Info(), spec.Value)
+}
+
+func (fatigueRestore) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	amount := spec.Value * quantity
+	state.Stats.Fatigue -= amount
+	if state.Stats.Fatigue < 0 {
+		state.Stats.Fatigue = 0
+	}
+	return fmt.Sprintf("恢复了 %d 点精力（降低疲劳）", amount), nil
+}
+
+// attributeBoost raises an attribute through the normal realm rules. With
+// fixedTarget set it registers as the legacy "<attr>_boost" key.
+type attributeBoost struct {
+	fixedTarget string
+}
+
+func (e attributeBoost) Info() Info {
+	if e.fixedTarget != "" {
+		name := attrName(e.fixedTarget)
+		return Info{
+			Key:         e.fixedTarget + "_boost",
+			Name:        name + "提升",
+			Description: name + " +%d",
+			NeedsValue:  true,
+		}
+	}
+	return Info{
+		Key:         "attribute_boost",
+		Name:        "属性提升",
+		Description: "指定属性 +%d",
+		NeedsValue:  true,
+		NeedsTarget: true,
+		Targets:     realm.AllAttrKeys,
+	}
+}
+
+func (e attributeBoost) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e attributeBoost) target(spec model.EffectSpec) string {
+	if e.fixedTarget != "" {
+		return e.fixedTarget
+	}
+	return spec.Target
+}
+
+func (e attributeBoost) Describe(spec model.EffectSpec) string {
+	return fmt.Sprintf("%s +%d", attrName(e.target(spec)), spec.Value)
+}
+
+func (e attributeBoost) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	key := e.target(spec)
+	amount := spec.Value * quantity
+	message := fmt.Sprintf("%s提升了 %d 点", attrName(key), amount)
+
+	attr, ok := state.Attrs[key]
+	if !ok {
+		return message, nil
+	}
+
+	gain := float64(amount)
+	if !realm.AttrDisplay[key].HasRealm {
+		attr.Value += gain
+	} else {
+		result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
+		attr.Value = result.NewValue
+		attr.AccumulationPool = result.NewAccPool
+		attr.RealmExp = result.NewRealmExp
+		attr.IsBottleneck = result.NewIsBottleneck
+	}
+	state.MarkChanged(key)
+
+	return message, nil
+}
+
+// spiritStoneGain grants spirit stones via State.SpiritStones.
+type spiritStoneGain struct{}
+
+func (spiritStoneGain) Info() Info {
+	return Info{Key: "spirit_stone_gain", Name: "灵石", Description: "获得 %d 灵石", NeedsValue: true}
+}
+
+func (e spiritStoneGain) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e spiritStoneGain) Describe(spec model.EffectSpec) string {
+	return describeValue(e.Info(), spec.Value)
+}
+
+func (spiritStoneGain) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	amount := spec.Value * quantity
+	state.SpiritStones += amount
+	return fmt.Sprintf("获得了 %d 灵石", amount), nil
+}
+
+// breakthroughAssist adds realm exp to a bottlenecked attribute and breaks
+// through to the next realm once enough exp has accumulated.
+type breakthroughAssist struct{}
+
+func (breakthroughAssist) Info() Info {
+	return Info{
+		Key:         "breakthrough_assist",
+		Name:        "突破辅助",
+		Description: "瓶颈期属性增加 %d 点突破经验",
+		NeedsValue:  true,
+		NeedsTarget: true,
+		Targets:     realm.AttrKeys,
+	}
+}
+
+func (e breakthroughAssist) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e breakthroughAssist) Describe(spec model.EffectSpec) string {
+	return fmt.Sprintf("%s瓶颈期增加 %d 点突破经验", attrName(spec.Target), spec.Value)
+}
+
+func (breakthroughAssist) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	name := attrName(spec.Target)
+	attr, ok := state.Attrs[spec.Target]
+	if !ok || !attr.IsBottleneck {
+		return "", fmt.Errorf("%s尚未进入瓶颈期，无法使用", name)
+	}
+
+	amount := spec.Value * quantity
+	attr.RealmExp += amount
+	state.MarkChanged(spec.Target)
+
+	result, ok := realm.TryBreakthrough(attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
+	if !ok {
+		return fmt.Sprintf("%s突破经验 +%d（%d/%d）", name, amount, attr.RealmExp, realm.BreakthroughExpRequired(attr.Realm)), nil
+	}
+
+	attr.Realm = result.NewRealm
+	attr.SubRealm = realm.SubRealmChuQi
+	attr.Value = result.NewValue
+	attr.AccumulationPool = result.NewAccPool
+	attr.RealmExp = result.NewRealmExp
+	attr.IsBottleneck = result.NewIsBottleneck
+
+	return fmt.Sprintf("%s突破成功，晋入%s！", name, realm.GetRealmName(attr.Realm)), nil
+}
+
+// taskRewardMultiplier boosts the spirit stones of the next completed task.
+type taskRewardMultiplier struct{}
+
+func (taskRewardMultiplier) Info() Info {
+	return Info{Key: "task_reward_multiplier", Name: "奖励加成", Description: "下一个完成的任务灵石奖励 +%d%%", NeedsValue: true}
+}
+
+func (e taskRewardMultiplier) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e taskRewardMultiplier) Describe(spec model.EffectSpec) string {
+	return describeValue(e.Info(), spec.Value)
+}
+
+func (taskRewardMultiplier) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	state.Stats.NextRewardBonus += spec.Value * quantity
+	return fmt.Sprintf("下一个任务的灵石奖励 +%d%%", state.Stats.NextRewardBonus), nil
+}
+
+// streakShield stocks shields that each absorb one attribute decay.
+type streakShield struct{}
+
+func (streakShield) Info() Info {
+	return Info{Key: "streak_shield", Name: "护体", Description: "抵挡 %d 次属性衰减", NeedsValue: true}
+}
+
+func (e streakShield) Validate(spec model.EffectSpec) error {
+	return validateSpec(e.Info(), spec)
+}
+
+func (e streakShield) Describe(spec model.EffectSpec) string {
+	return describeValue(e.Info(), spec.Value)
+}
+
+func (streakShield) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	state.Stats.StreakShields += spec.Value * quantity
+	return fmt.Sprintf("护体次数 +%d（共 %d 次）", spec.Value*quantity, state.Stats.StreakShields), nil
+}
+
+// buffEffect grants a timed or use-limited buff. Value is a signed percentage,
+// so the same effect also expresses debuffs.
+type buffEffect struct {
+	modifier string
+}
+
+func (e buffEffect) Info() Info {
+	switch e.modifier {
+	case model.BuffModifierSpiritStones:
+		return Info{Key: "spirit_stone_buff", Name: "灵石增益", Description: "任务灵石奖励 %+d%%", NeedsValue: true}
+	case model.BuffModifierAttrGain:
+		return Info{Key: "attr_gain_buff", Name: "修炼增益", Description: "指定属性任务收益 %+d%%", NeedsValue: true, NeedsTarget: true, Targets: realm.AttrKeys}
+	default:
+		return Info{Key: "fatigue_cost_buff", Name: "精力增益", Description: "任务疲劳消耗 %+d%%", NeedsValue: true}
+	}
+}
+
+func (e buffEffect) Validate(spec model.EffectSpec) error {
+	info := e.Info()
+	if spec.Value == 0 || spec.Value < -100 {
+		return fmt.Errorf("效果「%s」的数值必须是不为0且不小于-100的百分比", info.Name)
+	}
+	if spec.Duration < 0 || spec.Uses < 0 || (spec.Duration == 0 && spec.Uses == 0) {
+		return fmt.Errorf("效果「%s」需要持续时间（小时）或生效次数", info.Name)
+	}
+	switch spec.Stacking {
+	case "", model.BuffStackingRefresh, model.BuffStackingExtend, model.BuffStackingStack:
+	default:
+		return fmt.Errorf("未知的叠加规则: %s", spec.Stacking)
+	}
+
+	// Reuse the target checks; the value was checked above
+	spec.Value = 1
+	return validateSpec(info, spec)
+}
+
+func (e buffEffect) Describe(spec model.EffectSpec) string {
+	var desc string
+	if e.modifier == model.BuffModifierAttrGain {
+		desc = fmt.Sprintf("%s任务收益 %+d%%", attrName(spec.Target), spec.Value)
+	} else {
+		desc = fmt.Sprintf(e.Info().Description, spec.Value)
+	}
+
+	if spec.Duration > 0 {
+		desc += fmt.Sprintf("，持续 %d 小时", spec.Duration)
+	}
+	if spec.Uses > 0 {
+		desc += fmt.Sprintf("，%d 次任务", spec.Uses)
+	}
+	return desc
+}
+
+func (e buffEffect) Apply(state *State, spec model.EffectSpec, quantity int) (string, error) {
+	stacking := spec.Stacking
+	if stacking == "" {
+		stacking = model.BuffStackingRefresh
+	}
+
+	var expiresAt sql.NullTime
+	if spec.Duration > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().Add(time.Duration(spec.Duration) * time.Hour), Valid: true}
+	}
+
+	desc := e.Describe(spec)
+	for i := 0; i < quantity; i++ {
+		state.Buffs = append(state.Buffs, &model.CharacterBuff{
+			UserID:        state.Stats.UserID,
+			Name:          e.Info().Name,
+			Modifier:      e.modifier,
+			Target:        spec.Target,
+			Value:         spec.Value,
+			Stacks:        1,
+			Stacking:      stacking,
+			RemainingUses: spec.Uses,
+			ExpiresAt:     expiresAt,
+		})
+	}
+
+	return fmt.Sprintf("获得状态「%s」：%s", e.Info().Name, desc), nil
+}
@@ -0,0 +1,193 @@
+// Package effect implements the effects applied when a shop item is used.
+//
+// Every effect is an ItemEffect registered under a unique key. An item stores
+// one or more model.EffectSpec entries which the Registry validates, describes
+// and applies against a character's in-memory State.
+package effect
+
+import (
+	"fmt"
+	"strings"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+)
+
+// Info describes an effect for the shop editor.
+type Info struct {
+	Key         string
+	Name        string
+	Description string   // %d is replaced by the effect value
+	NeedsValue  bool     // Value must be > 0
+	NeedsTarget bool     // Target must be one of Targets
+	Targets     []string // Allowed targets; empty when the effect has none
+}
+
+// ItemEffect is one kind of item effect.
+type ItemEffect interface {
+	Info() Info
+	// Validate checks that spec is well formed for this effect.
+	Validate(spec model.EffectSpec) error
+	// Describe renders spec as a short human readable line.
+	Describe(spec model.EffectSpec) string
+	// Apply mutates state for quantity uses of spec and returns a result message.
+	Apply(state *State, spec model.EffectSpec, quantity int) (string, error)
+}
+
+// State is the part of a character an effect may change. Effects only touch
+// memory; the caller persists Stats, ChangedAttrs and SpiritStones afterwards.
+type State struct {
+	Stats *model.CharacterStats
+	Attrs map[string]*model.CharacterAttribute
+
+	// SpiritStones is the net balance change. It is kept apart from
+	// Stats.SpiritStones so the caller can record it in the ledger.
+	SpiritStones int
+
+	// Buffs are granted buffs, in order. The caller persists them and applies
+	// stacking rules against buffs that are already active.
+	Buffs []*model.CharacterBuff
+
+	changed map[string]bool
+}
+
+func NewState(stats *model.CharacterStats, attrs []*model.CharacterAttribute) *State {
+	state := &State{
+		Stats:   stats,
+		Attrs:   make(map[string]*model.CharacterAttribute, len(attrs)),
+		changed: make(map[string]bool),
+	}
+	for _, a := range attrs {
+		state.Attrs[a.AttrKey] = a
+	}
+	return state
+}
+
+// MarkChanged flags an attribute for persisting.
+func (s *State) MarkChanged(key string) {
+	s.changed[key] = true
+}
+
+// ChangedAttrs returns the attributes modified by applied effects.
+func (s *State) ChangedAttrs() []*model.CharacterAttribute {
+	var attrs []*model.CharacterAttribute
+	for _, key := range realm.AllAttrKeys {
+		if s.changed[key] {
+			if attr, ok := s.Attrs[key]; ok {
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
+
+// Registry holds the known effects in registration order.
+type Registry struct {
+	effects map[string]ItemEffect
+	order   []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		effects: make(map[string]ItemEffect),
+	}
+}
+
+// Register adds an effect. Registering the same key twice panics.
+func (r *Registry) Register(e ItemEffect) {
+	key := e.Info().Key
+	if _, exists := r.effects[key]; exists {
+		panic(fmt.Sprintf("effect %q registered twice", key))
+	}
+	r.effects[key] = e
+	r.order = append(r.order, key)
+}
+
+// Lookup returns the effect registered under key.
+func (r *Registry) Lookup(key string) (ItemEffect, bool) {
+	e, ok := r.effects[key]
+	return e, ok
+}
+
+// All returns every registered effect's info in registration order.
+func (r *Registry) All() []Info {
+	infos := make([]Info, 0, len(r.order))
+	for _, key := range r.order {
+		infos = append(infos, r.effects[key].Info())
+	}
+	return infos
+}
+
+// Validate checks every spec of an item.
+func (r *Registry) Validate(specs []model.EffectSpec) error {
+	for _, spec := range specs {
+		e, ok := r.Lookup(spec.Type)
+		if !ok {
+			return fmt.Errorf("未知的物品效果: %s", spec.Type)
+		}
+		if err := e.Validate(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describe renders a single spec. Unknown effects fall back to their key.
+func (r *Registry) Describe(spec model.EffectSpec) (name, description string) {
+	e, ok := r.Lookup(spec.Type)
+	if !ok {
+		return spec.Type, ""
+	}
+	return e.Info().Name, e.Describe(spec)
+}
+
+// Apply applies every spec in order and collects the non-empty messages.
+// Nothing is applied when any spec fails validation.
+func (r *Registry) Apply(state *State, specs []model.EffectSpec, quantity int) ([]string, error) {
+	if err := r.Validate(specs); err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, spec := range specs {
+		e, _ := r.Lookup(spec.Type)
+		msg, err := e.Apply(state, spec, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// validateSpec applies the generic NeedsValue/NeedsTarget rules of info.
+func validateSpec(info Info, spec model.EffectSpec) error {
+	if info.NeedsValue && spec.Value <= 0 {
+		return fmt.Errorf("效果「%s」的数值必须大于0", info.Name)
+	}
+	if !info.NeedsValue && spec.Value != 0 {
+		return fmt.Errorf("效果「%s」不需要数值", info.Name)
+	}
+	if !info.NeedsTarget {
+		if spec.Target != "" {
+			return fmt.Errorf("效果「%s」不需要目标", info.Name)
+		}
+		return nil
+	}
+	for _, t := range info.Targets {
+		if t == spec.Target {
+			return nil
+		}
+	}
+	return fmt.Errorf("效果「%s」的目标必须是 %s 之一", info.Name, strings.Join(info.Targets, "/"))
+}
+
+// describeValue fills the %d placeholder of info.Description.
+func describeValue(info Info, value int) string {
+	if info.NeedsValue {
+		return fmt.Sprintf(info.Description, value)
+	}
+	return info.Description
+}
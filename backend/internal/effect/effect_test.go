@@ -0,0 +1,303 @@
+package effect
+
+import (
+	"strings"
+	"testing"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+)
+
+// newTestState returns a character with fatigue 50, physique at 100 in 凡人,
+// willpower bottlenecked at the 凡人 cap and luck at 5.
+func newTestState() *State {
+	stats := &model.CharacterStats{UserID: 1, SpiritStones: 100, Fatigue: 50, FatigueCap: 100}
+	attrs := []*model.CharacterAttribute{
+		{AttrKey: "physique", Value: 100},
+		{AttrKey: "willpower", Value: realm.AttrCap(0), IsBottleneck: true, RealmExp: 900, AccumulationPool: 30},
+		{AttrKey: "luck", Value: 5},
+	}
+	return NewState(stats, attrs)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    model.EffectSpec
+		wantErr string
+	}{
+		{"none", model.EffectSpec{Type: "none"}, ""},
+		{"none with value", model.EffectSpec{Type: "none", Value: 1}, "不需要数值"},
+		{"fatigue_restore", model.EffectSpec{Type: "fatigue_restore", Value: 10}, ""},
+		{"fatigue_restore without value", model.EffectSpec{Type: "fatigue_restore"}, "必须大于0"},
+		{"fatigue_restore with target", model.EffectSpec{Type: "fatigue_restore", Value: 10, Target: "physique"}, "不需要目标"},
+		{"attribute_boost", model.EffectSpec{Type: "attribute_boost", Target: "physique", Value: 5}, ""},
+		{"attribute_boost on luck", model.EffectSpec{Type: "attribute_boost", Target: "luck", Value: 5}, ""},
+		{"attribute_boost without target", model.EffectSpec{Type: "attribute_boost", Value: 5}, "目标必须是"},
+		{"attribute_boost unknown target", model.EffectSpec{Type: "attribute_boost", Target: "wisdom", Value: 5}, "目标必须是"},
+		{"attribute_boost negative value", model.EffectSpec{Type: "attribute_boost", Target: "physique", Value: -5}, "必须大于0"},
+		{"legacy boost", model.EffectSpec{Type: "physique_boost", Value: 5}, ""},
+		{"legacy boost with target", model.EffectSpec{Type: "physique_boost", Target: "willpower", Value: 5}, "不需要目标"},
+		{"spirit_stone_gain", model.EffectSpec{Type: "spirit_stone_gain", Value: 30}, ""},
+		{"spirit_stone_gain without value", model.EffectSpec{Type: "spirit_stone_gain"}, "必须大于0"},
+		{"breakthrough_assist", model.EffectSpec{Type: "breakthrough_assist", Target: "willpower", Value: 100}, ""},
+		{"breakthrough_assist on luck", model.EffectSpec{Type: "breakthrough_assist", Target: "luck", Value: 100}, "目标必须是"},
+		{"task_reward_multiplier", model.EffectSpec{Type: "task_reward_multiplier", Value: 20}, ""},
+		{"task_reward_multiplier without value", model.EffectSpec{Type: "task_reward_multiplier"}, "必须大于0"},
+		{"streak_shield", model.EffectSpec{Type: "streak_shield", Value: 1}, ""},
+		{"streak_shield without value", model.EffectSpec{Type: "streak_shield"}, "必须大于0"},
+		{"spirit_stone_buff", model.EffectSpec{Type: "spirit_stone_buff", Value: 50, Duration: 2}, ""},
+		{"spirit_stone_buff debuff", model.EffectSpec{Type: "spirit_stone_buff", Value: -100, Uses: 1}, ""},
+		{"spirit_stone_buff zero", model.EffectSpec{Type: "spirit_stone_buff", Duration: 2}, "不为0"},
+		{"spirit_stone_buff below -100", model.EffectSpec{Type: "spirit_stone_buff", Value: -101, Duration: 2}, "不为0"},
+		{"spirit_stone_buff without expiry", model.EffectSpec{Type: "spirit_stone_buff", Value: 50}, "持续时间"},
+		{"spirit_stone_buff negative duration", model.EffectSpec{Type: "spirit_stone_buff", Value: 50, Duration: -1, Uses: 1}, "持续时间"},
+		{"spirit_stone_buff unknown stacking", model.EffectSpec{Type: "spirit_stone_buff", Value: 50, Duration: 2, Stacking: "merge"}, "未知的叠加规则"},
+		{"attr_gain_buff", model.EffectSpec{Type: "attr_gain_buff", Target: "physique", Value: 20, Uses: 3, Stacking: model.BuffStackingStack}, ""},
+		{"attr_gain_buff without target", model.EffectSpec{Type: "attr_gain_buff", Value: 20, Uses: 3}, "目标必须是"},
+		{"fatigue_cost_buff", model.EffectSpec{Type: "fatigue_cost_buff", Value: -30, Duration: 1, Stacking: model.BuffStackingExtend}, ""},
+		{"fatigue_cost_buff with target", model.EffectSpec{Type: "fatigue_cost_buff", Target: "physique", Value: -30, Duration: 1}, "不需要目标"},
+		{"unknown effect", model.EffectSpec{Type: "teleport", Value: 1}, "未知的物品效果"},
+	}
+
+	registry := NewDefaultRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Validate([]model.EffectSpec{tt.spec})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     model.EffectSpec
+		quantity int
+		wantErr  string
+		check    func(t *testing.T, s *State)
+	}{
+		{
+			name: "fatigue_restore", spec: model.EffectSpec{Type: "fatigue_restore", Value: 20}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if s.Stats.Fatigue != 10 {
+					t.Errorf("fatigue = %d, want 10", s.Stats.Fatigue)
+				}
+			},
+		},
+		{
+			name: "fatigue_restore stops at zero", spec: model.EffectSpec{Type: "fatigue_restore", Value: 40}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if s.Stats.Fatigue != 0 {
+					t.Errorf("fatigue = %d, want 0", s.Stats.Fatigue)
+				}
+			},
+		},
+		{
+			name: "attribute_boost", spec: model.EffectSpec{Type: "attribute_boost", Target: "physique", Value: 10}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				if got := s.Attrs["physique"].Value; got != 110 {
+					t.Errorf("physique = %v, want 110", got)
+				}
+				if changed := s.ChangedAttrs(); len(changed) != 1 || changed[0].AttrKey != "physique" {
+					t.Errorf("changed attrs = %v, want physique", changed)
+				}
+			},
+		},
+		{
+			name: "attribute_boost clamps at the realm cap", spec: model.EffectSpec{Type: "attribute_boost", Target: "physique", Value: 150}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				attr := s.Attrs["physique"]
+				if attr.Value != realm.AttrCap(0) || !attr.IsBottleneck {
+					t.Errorf("physique = %v bottleneck %v, want %v at bottleneck", attr.Value, attr.IsBottleneck, realm.AttrCap(0))
+				}
+			},
+		},
+		{
+			name: "attribute_boost on an attribute without realms", spec: model.EffectSpec{Type: "attribute_boost", Target: "luck", Value: 3}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				if got := s.Attrs["luck"].Value; got != 8 {
+					t.Errorf("luck = %v, want 8", got)
+				}
+			},
+		},
+		{
+			name: "legacy boost", spec: model.EffectSpec{Type: "physique_boost", Value: 5}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if got := s.Attrs["physique"].Value; got != 110 {
+					t.Errorf("physique = %v, want 110", got)
+				}
+			},
+		},
+		{
+			name: "spirit_stone_gain", spec: model.EffectSpec{Type: "spirit_stone_gain", Value: 30}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if s.SpiritStones != 60 {
+					t.Errorf("spirit stone change = %d, want 60", s.SpiritStones)
+				}
+				if s.Stats.SpiritStones != 100 {
+					t.Errorf("stats balance = %d, want it left to the caller", s.Stats.SpiritStones)
+				}
+			},
+		},
+		{
+			name: "breakthrough_assist not at bottleneck", spec: model.EffectSpec{Type: "breakthrough_assist", Target: "physique", Value: 100}, quantity: 1,
+			wantErr: "尚未进入瓶颈期",
+		},
+		{
+			name: "breakthrough_assist without enough exp", spec: model.EffectSpec{Type: "breakthrough_assist", Target: "willpower", Value: 50}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				attr := s.Attrs["willpower"]
+				if attr.RealmExp != 950 || attr.Realm != 0 || !attr.IsBottleneck {
+					t.Errorf("willpower exp %d realm %d bottleneck %v, want 950 in realm 0 at bottleneck", attr.RealmExp, attr.Realm, attr.IsBottleneck)
+				}
+			},
+		},
+		{
+			name: "breakthrough_assist breaks through", spec: model.EffectSpec{Type: "breakthrough_assist", Target: "willpower", Value: 100}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				attr := s.Attrs["willpower"]
+				if attr.Realm != 1 || attr.IsBottleneck || attr.RealmExp != 0 {
+					t.Errorf("willpower realm %d bottleneck %v exp %d, want realm 1 without bottleneck", attr.Realm, attr.IsBottleneck, attr.RealmExp)
+				}
+				if want := realm.AttrMin(1) + 30; attr.Value != want {
+					t.Errorf("willpower = %v, want %v", attr.Value, want)
+				}
+				if attr.AccumulationPool != 0 {
+					t.Errorf("accumulation pool = %v, want 0", attr.AccumulationPool)
+				}
+			},
+		},
+		{
+			name: "task_reward_multiplier", spec: model.EffectSpec{Type: "task_reward_multiplier", Value: 20}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if s.Stats.NextRewardBonus != 40 {
+					t.Errorf("next reward bonus = %d, want 40", s.Stats.NextRewardBonus)
+				}
+			},
+		},
+		{
+			name: "streak_shield", spec: model.EffectSpec{Type: "streak_shield", Value: 1}, quantity: 3,
+			check: func(t *testing.T, s *State) {
+				if s.Stats.StreakShields != 3 {
+					t.Errorf("streak shields = %d, want 3", s.Stats.StreakShields)
+				}
+			},
+		},
+		{
+			name: "spirit_stone_buff", spec: model.EffectSpec{Type: "spirit_stone_buff", Value: 50, Duration: 2}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				if len(s.Buffs) != 1 {
+					t.Fatalf("got %d buffs, want 1", len(s.Buffs))
+				}
+				buff := s.Buffs[0]
+				if buff.Modifier != model.BuffModifierSpiritStones || buff.Value != 50 || buff.Stacking != model.BuffStackingRefresh {
+					t.Errorf("buff = %+v", buff)
+				}
+				if !buff.ExpiresAt.Valid || buff.RemainingUses != 0 {
+					t.Errorf("buff expires %v after %d uses, want a timed buff", buff.ExpiresAt, buff.RemainingUses)
+				}
+			},
+		},
+		{
+			name: "attr_gain_buff", spec: model.EffectSpec{Type: "attr_gain_buff", Target: "physique", Value: 20, Uses: 3, Stacking: model.BuffStackingStack}, quantity: 2,
+			check: func(t *testing.T, s *State) {
+				if len(s.Buffs) != 2 {
+					t.Fatalf("got %d buffs, want one per use", len(s.Buffs))
+				}
+				for _, buff := range s.Buffs {
+					if buff.Modifier != model.BuffModifierAttrGain || buff.Target != "physique" || buff.Stacking != model.BuffStackingStack {
+						t.Errorf("buff = %+v", buff)
+					}
+					if buff.ExpiresAt.Valid || buff.RemainingUses != 3 {
+						t.Errorf("buff expires %v after %d uses, want 3 uses only", buff.ExpiresAt, buff.RemainingUses)
+					}
+				}
+			},
+		},
+		{
+			name: "fatigue_cost_buff", spec: model.EffectSpec{Type: "fatigue_cost_buff", Value: -30, Duration: 1, Uses: 2}, quantity: 1,
+			check: func(t *testing.T, s *State) {
+				if len(s.Buffs) != 1 {
+					t.Fatalf("got %d buffs, want 1", len(s.Buffs))
+				}
+				buff := s.Buffs[0]
+				if buff.Modifier != model.BuffModifierFatigueCost || buff.Value != -30 || buff.UserID != 1 {
+					t.Errorf("buff = %+v", buff)
+				}
+				if !buff.ExpiresAt.Valid || buff.RemainingUses != 2 {
+					t.Errorf("buff expires %v after %d uses, want both limits", buff.ExpiresAt, buff.RemainingUses)
+				}
+			},
+		},
+	}
+
+	registry := NewDefaultRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newTestState()
+			messages, err := registry.Apply(state, []model.EffectSpec{tt.spec}, tt.quantity)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if len(messages) != 1 {
+				t.Errorf("Apply() messages = %v, want one", messages)
+			}
+			tt.check(t, state)
+		})
+	}
+}
+
+func TestApplyValidatesEverySpecFirst(t *testing.T) {
+	state := newTestState()
+	specs := []model.EffectSpec{
+		{Type: "spirit_stone_gain", Value: 30},
+		{Type: "teleport", Value: 1},
+	}
+	if _, err := NewDefaultRegistry().Apply(state, specs, 1); err == nil {
+		t.Fatal("Apply() accepted an unknown effect")
+	}
+	if state.SpiritStones != 0 {
+		t.Errorf("spirit stone change = %d, want nothing applied", state.SpiritStones)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	registry := NewDefaultRegistry()
+
+	for _, key := range []string{"none", "fatigue_restore", "attribute_boost", "physique_boost", "spirit_stone_buff"} {
+		if _, ok := registry.Lookup(key); !ok {
+			t.Errorf("Lookup(%q) found nothing", key)
+		}
+	}
+	for _, key := range []string{"", "teleport", "luck_boost"} {
+		if _, ok := registry.Lookup(key); ok {
+			t.Errorf("Lookup(%q) found an effect", key)
+		}
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering fatigue_restore twice did not panic")
+		}
+	}()
+	registry := NewRegistry()
+	registry.Register(fatigueRestore{})
+	registry.Register(fatigueRestore{})
+}
@@ -2,11 +2,15 @@ package handler
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/zeromicro/go-zero/rest/httpx"
 	"life-system-backend/internal/middleware"
 	"life-system-backend/internal/svc"
 	"life-system-backend/internal/types"
+	"life-system-backend/pkg/bark"
+	"life-system-backend/pkg/notify"
 )
 
 // SetBarkKeyHandler sets the user's Bark push notification key
@@ -24,8 +28,18 @@ func SetBarkKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
-		// Update bark key
-		if err := svcCtx.UserModel.UpdateBarkKey(userID, req.BarkKey); err != nil {
+		req.ServerURL = strings.TrimRight(strings.TrimSpace(req.ServerURL), "/")
+		if req.ServerURL != "" && !validServerURL(req.ServerURL) {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "服务器地址必须是 http(s) 链接"})
+			return
+		}
+		if req.CryptKey != "" && !bark.ValidCryptKey(req.CryptKey) {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "加密密钥长度必须是 16、24 或 32 位"})
+			return
+		}
+
+		// Update bark key together with server and encryption
+		if err := svcCtx.UserModel.UpdateBarkConfig(userID, req.BarkKey, req.ServerURL, req.CryptKey); err != nil {
 			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to update bark key"})
 			return
 		}
@@ -46,11 +60,12 @@ func GetBarkStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
-		barkKey, err := svcCtx.UserModel.GetBarkKey(userID)
-		if err != nil {
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil {
 			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to get bark status"})
 			return
 		}
+		barkKey := user.BarkKey
 
 		// Mask the bark key for security (show first 8 chars + ***)
 		maskedKey := ""
@@ -63,8 +78,14 @@ func GetBarkStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		}
 
 		resp := types.BarkStatusResp{
-			Enabled: barkKey != "",
-			BarkKey: maskedKey,
+			Enabled:   barkKey != "",
+			BarkKey:   maskedKey,
+			ServerURL: svcCtx.BarkClient.ServerURL(),
+			Custom:    user.BarkServer != "",
+			Encrypted: user.BarkCryptKey != "",
+		}
+		if user.BarkServer != "" {
+			resp.ServerURL = user.BarkServer
 		}
 
 		httpx.OkJson(w, types.CommonResp{
@@ -84,13 +105,14 @@ func TestBarkHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
-		if svcCtx.BarkClient == nil {
+		channel := svcCtx.Notifier.Channel(notify.ChannelBark)
+		if channel == nil {
 			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "Bark 服务未启用"})
 			return
 		}
 
-		barkKey, err := svcCtx.UserModel.GetBarkKey(userID)
-		if err != nil || barkKey == "" {
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil || !channel.Enabled(user) {
 			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "请先设置 Bark Key"})
 			return
 		}
@@ -109,8 +131,14 @@ func TestBarkHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			req.Body = "恭喜！Bark 推送配置成功！"
 		}
 
-		// Send test notification with alarm style
-		if err := svcCtx.BarkClient.PushAlarm(barkKey, req.Title, req.Body); err != nil {
+		// Send test notification with alarm style, through the user's server and encryption
+		err = channel.Send(user, &notify.Notification{
+			Title: req.Title,
+			Body:  req.Body,
+			Level: notify.LevelTimeSensitive,
+			Alarm: true,
+		})
+		if err != nil {
 			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "推送失败: " + err.Error()})
 			return
 		}
@@ -131,7 +159,7 @@ func DeleteBarkKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
-		if err := svcCtx.UserModel.UpdateBarkKey(userID, ""); err != nil {
+		if err := svcCtx.UserModel.UpdateBarkConfig(userID, "", "", ""); err != nil {
 			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to delete bark key"})
 			return
 		}
@@ -142,3 +170,9 @@ func DeleteBarkKeyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		})
 	}
 }
+
+// validServerURL reports whether s is an http(s) URL of a push server
+func validServerURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
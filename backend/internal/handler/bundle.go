@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+func GetShopBundlesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewShopLogic(svcCtx)
+		resp, err := l.GetBundles(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func CreateShopBundleHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.CreateShopBundleReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewShopLogic(svcCtx)
+		resp, err := l.CreateBundle(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func UpdateShopBundleHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		bundleID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid bundle id"})
+			return
+		}
+
+		var req types.UpdateShopBundleReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewShopLogic(svcCtx)
+		resp, err := l.UpdateBundle(r.Context(), userID, bundleID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func DeleteShopBundleHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		bundleID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid bundle id"})
+			return
+		}
+
+		l := logic.NewShopLogic(svcCtx)
+		if err := l.DeleteBundle(r.Context(), userID, bundleID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+func GetRecipesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewCraftingLogic(svcCtx)
+		resp, err := l.GetRecipes(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func CreateRecipeHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.CreateRecipeReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewCraftingLogic(svcCtx)
+		resp, err := l.CreateRecipe(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func UpdateRecipeHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		recipeID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid recipe id"})
+			return
+		}
+
+		var req types.UpdateRecipeReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewCraftingLogic(svcCtx)
+		resp, err := l.UpdateRecipe(r.Context(), userID, recipeID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+func DeleteRecipeHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		recipeID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid recipe id"})
+			return
+		}
+
+		l := logic.NewCraftingLogic(svcCtx)
+		if err := l.DeleteRecipe(r.Context(), userID, recipeID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+		})
+	}
+}
+
+func CraftHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.CraftReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewCraftingLogic(svcCtx)
+		resp, err := l.Craft(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
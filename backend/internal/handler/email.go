@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+// GetEmailStatusHandler returns the user's email address and verification state
+func GetEmailStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewEmailLogic(svcCtx)
+		resp, err := l.GetStatus(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{Code: 0, Message: "success", Data: resp})
+	}
+}
+
+// SetEmailHandler sets the user's address and mails a verification code
+func SetEmailHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.SetEmailReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewEmailLogic(svcCtx)
+		resp, err := l.SetEmail(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{Code: 0, Message: "验证码已发送，请查收邮件", Data: resp})
+	}
+}
+
+// VerifyEmailHandler confirms the address with the mailed code
+func VerifyEmailHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.VerifyEmailReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewEmailLogic(svcCtx)
+		resp, err := l.Verify(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{Code: 0, Message: "邮箱验证成功", Data: resp})
+	}
+}
+
+// TestEmailHandler sends a test mail to verify the setup
+func TestEmailHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewEmailLogic(svcCtx)
+		if err := l.Test(r.Context(), userID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{Code: 0, Message: "测试邮件已发送，请检查收件箱"})
+	}
+}
+
+// DeleteEmailHandler removes the user's address
+func DeleteEmailHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewEmailLogic(svcCtx)
+		if err := l.Delete(r.Context(), userID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to delete email"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{Code: 0, Message: "邮箱已删除"})
+	}
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>退订邮件</title></head>
+<body style="font-family:-apple-system,'PingFang SC','Microsoft YaHei',sans-serif;background:#f4f1ea;color:#2d2a26;">
+<div style="max-width:480px;margin:80px auto;padding:32px;background:#fff;border-radius:12px;text-align:center;">
+<h1 style="font-size:20px;">Life System</h1>
+<p style="font-size:15px;line-height:1.6;">{{.}}</p>
+</div>
+</body>
+</html>`))
+
+// UnsubscribeEmailHandler serves the signed unsubscribe links in notification
+// mail. Public, as the signature identifies the user. POST is the one-click
+// unsubscribe of RFC 8058.
+func UnsubscribeEmailHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.UnsubscribeEmailReq
+		message := "退订链接无效"
+		status := http.StatusBadRequest
+		if err := httpx.Parse(r, &req); err == nil {
+			l := logic.NewEmailLogic(svcCtx)
+			if message, err = l.Unsubscribe(r.Context(), &req); err != nil {
+				message = err.Error()
+			} else {
+				status = http.StatusOK
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		unsubscribePage.Execute(w, message)
+	}
+}
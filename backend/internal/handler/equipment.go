@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+func EquipItemHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.EquipItemReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewEquipmentLogic(svcCtx)
+		resp, err := l.EquipItem(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
+
+func UnequipItemHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.UnequipItemReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewEquipmentLogic(svcCtx)
+		resp, err := l.UnequipItem(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+)
+
+// SetGotifyHandler sets the user's Gotify server and application token
+func SetGotifyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.SetGotifyReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		req.ServerURL = strings.TrimRight(strings.TrimSpace(req.ServerURL), "/")
+		req.Token = strings.TrimSpace(req.Token)
+		if !validServerURL(req.ServerURL) {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "服务器地址必须是 http(s) 链接"})
+			return
+		}
+		if req.Token == "" {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "请填写应用 Token"})
+			return
+		}
+
+		if err := svcCtx.UserModel.UpdateGotifyConfig(userID, req.ServerURL, req.Token); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to update gotify config"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "Gotify 设置成功",
+		})
+	}
+}
+
+// GetGotifyStatusHandler returns the user's Gotify configuration status
+func GetGotifyStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to get gotify status"})
+			return
+		}
+
+		// Mask the token like the Bark key (first 4 chars + ***)
+		maskedToken := user.GotifyToken
+		if len(maskedToken) > 4 {
+			maskedToken = maskedToken[:4] + "***"
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data: types.GotifyStatusResp{
+				Enabled:   user.GotifyServer != "" && user.GotifyToken != "",
+				ServerURL: user.GotifyServer,
+				Token:     maskedToken,
+			},
+		})
+	}
+}
+
+// TestGotifyHandler sends a test notification to verify the Gotify setup
+func TestGotifyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		channel := svcCtx.Notifier.Channel(notify.ChannelGotify)
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil || channel == nil || !channel.Enabled(user) {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "请先设置 Gotify 服务器和 Token"})
+			return
+		}
+
+		var req types.TestPushReq
+		httpx.Parse(r, &req)
+		if req.Title == "" {
+			req.Title = "🔔 Life System 测试"
+		}
+		if req.Body == "" {
+			req.Body = "恭喜！Gotify 推送配置成功！"
+		}
+
+		err = channel.Send(user, &notify.Notification{
+			Title: req.Title,
+			Body:  req.Body,
+			Level: notify.LevelTimeSensitive,
+		})
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "推送失败: " + err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "测试推送已发送，请检查 Gotify 通知",
+		})
+	}
+}
+
+// DeleteGotifyHandler removes the user's Gotify configuration
+func DeleteGotifyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		if err := svcCtx.UserModel.UpdateGotifyConfig(userID, "", ""); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to delete gotify config"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "Gotify 设置已删除",
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+	"life-system-backend/pkg/ntfy"
+)
+
+// SetNtfyHandler sets the user's ntfy topic
+func SetNtfyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.SetNtfyReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		req.TopicURL = strings.TrimRight(strings.TrimSpace(req.TopicURL), "/")
+		if _, _, err := ntfy.SplitTopicURL(req.TopicURL); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "主题地址必须是 http(s) 链接，如 https://ntfy.sh/my-topic"})
+			return
+		}
+
+		if err := svcCtx.UserModel.UpdateNtfyConfig(userID, req.TopicURL, strings.TrimSpace(req.Token)); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to update ntfy topic"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "ntfy 主题设置成功",
+		})
+	}
+}
+
+// GetNtfyStatusHandler returns the user's ntfy configuration status
+func GetNtfyStatusHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to get ntfy status"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data: types.NtfyStatusResp{
+				Enabled:   user.NtfyTopicURL != "",
+				TopicURL:  user.NtfyTopicURL,
+				Protected: user.NtfyToken != "",
+			},
+		})
+	}
+}
+
+// TestNtfyHandler sends a test notification to verify the ntfy setup
+func TestNtfyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		channel := svcCtx.Notifier.Channel(notify.ChannelNtfy)
+		user, err := svcCtx.UserModel.FindByID(userID)
+		if err != nil || user == nil || channel == nil || !channel.Enabled(user) {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "请先设置 ntfy 主题"})
+			return
+		}
+
+		var req types.TestPushReq
+		httpx.Parse(r, &req)
+		if req.Title == "" {
+			req.Title = "🔔 Life System 测试"
+		}
+		if req.Body == "" {
+			req.Body = "恭喜！ntfy 推送配置成功！"
+		}
+
+		err = channel.Send(user, &notify.Notification{
+			Title: req.Title,
+			Body:  req.Body,
+			Level: notify.LevelTimeSensitive,
+		})
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "推送失败: " + err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "测试推送已发送，请检查 ntfy 通知",
+		})
+	}
+}
+
+// DeleteNtfyHandler removes the user's ntfy topic
+func DeleteNtfyHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		if err := svcCtx.UserModel.UpdateNtfyConfig(userID, "", ""); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 500, Message: "failed to delete ntfy topic"})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "ntfy 主题已删除",
+		})
+	}
+}
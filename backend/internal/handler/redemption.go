@@ -0,0 +1,379 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+// ListRedemptionsHandler lists the user's own redemptions, or those awaiting them as partner
+func ListRedemptionsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.RedemptionListReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.ListRedemptions(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// ApproveRedemptionHandler lets the partner approve a redemption
+func ApproveRedemptionHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		redemptionID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid redemption id"})
+			return
+		}
+
+		var req types.RedemptionActionReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.Approve(r.Context(), userID, redemptionID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// RejectRedemptionHandler lets the partner reject a redemption and refund the buyer
+func RejectRedemptionHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		redemptionID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid redemption id"})
+			return
+		}
+
+		var req types.RedemptionActionReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.Reject(r.Context(), userID, redemptionID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// FulfilRedemptionHandler marks a redemption as handed over
+func FulfilRedemptionHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		redemptionID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid redemption id"})
+			return
+		}
+
+		var req types.RedemptionActionReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.Fulfil(r.Context(), userID, redemptionID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// CancelRedemptionHandler withdraws a redemption and refunds it
+func CancelRedemptionHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		redemptionID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid redemption id"})
+			return
+		}
+
+		var req types.RedemptionActionReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.Cancel(r.Context(), userID, redemptionID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// GetRedemptionReportHandler compares spirit stones earned with those spent on real rewards
+func GetRedemptionReportHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.RedemptionReportReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.GetReport(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// GetPartnerHandler returns the partner who approves the user's redemptions
+func GetPartnerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.GetPartner(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// SetPartnerHandler designates the partner who approves the user's redemptions
+func SetPartnerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.SetPartnerReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.SetPartner(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// RemovePartnerHandler clears the user's partner
+func RemovePartnerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		if err := l.RemovePartner(r.Context(), userID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+		})
+	}
+}
+
+// ListPartnerRequestsHandler lists the users who named the caller as their partner
+func ListPartnerRequestsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		resp, err := l.ListPartnerRequests(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// AcceptPartnerHandler accepts a request to become the requester's partner
+func AcceptPartnerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		requesterID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid user id"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		if err := l.AcceptPartner(r.Context(), userID, requesterID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+		})
+	}
+}
+
+// DeclinePartnerHandler declines a partner request, or ends an accepted one
+func DeclinePartnerHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		requesterID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid user id"})
+			return
+		}
+
+		l := logic.NewRedemptionLogic(svcCtx)
+		if err := l.DeclinePartner(r.Context(), userID, requesterID); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+		})
+	}
+}
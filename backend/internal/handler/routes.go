@@ -6,6 +6,7 @@ import (
 	"github.com/zeromicro/go-zero/rest"
 	"life-system-backend/internal/middleware"
 	"life-system-backend/internal/svc"
+	"life-system-backend/pkg/telegram"
 )
 
 func RegisterRoutes(server *rest.Server, svcCtx *svc.ServiceContext) {
@@ -22,6 +23,23 @@ func RegisterRoutes(server *rest.Server, svcCtx *svc.ServiceContext) {
 				Path:    "/api/auth/login",
 				Handler: LoginHandler(svcCtx),
 			},
+			// Signed links in notification mail
+			{
+				Method:  "GET",
+				Path:    "/api/email/unsubscribe",
+				Handler: UnsubscribeEmailHandler(svcCtx),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/email/unsubscribe",
+				Handler: UnsubscribeEmailHandler(svcCtx),
+			},
+			// Bot updates in webhook mode, authenticated by the secret token header
+			{
+				Method:  "POST",
+				Path:    telegram.WebhookPath,
+				Handler: TelegramWebhookHandler(svcCtx),
+			},
 		},
 	)
 
@@ -74,7 +92,7 @@ func RegisterRoutes(server *rest.Server, svcCtx *svc.ServiceContext) {
 				Path:    "/api/character",
 				Handler: authMiddleware(GetCharacterHandler(svcCtx)),
 			},
-				// Tasks
+			// Tasks
 			{
 				Method:  "GET",
 				Path:    "/api/tasks",
@@ -147,6 +165,121 @@ func RegisterRoutes(server *rest.Server, svcCtx *svc.ServiceContext) {
 				Path:    "/api/bark/key",
 				Handler: authMiddleware(DeleteBarkKeyHandler(svcCtx)),
 			},
+			// ntfy push
+			{
+				Method:  "PUT",
+				Path:    "/api/ntfy/config",
+				Handler: authMiddleware(SetNtfyHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/ntfy/status",
+				Handler: authMiddleware(GetNtfyStatusHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/ntfy/test",
+				Handler: authMiddleware(TestNtfyHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/ntfy/config",
+				Handler: authMiddleware(DeleteNtfyHandler(svcCtx)),
+			},
+			// Gotify push
+			{
+				Method:  "PUT",
+				Path:    "/api/gotify/config",
+				Handler: authMiddleware(SetGotifyHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/gotify/status",
+				Handler: authMiddleware(GetGotifyStatusHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/gotify/test",
+				Handler: authMiddleware(TestGotifyHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/gotify/config",
+				Handler: authMiddleware(DeleteGotifyHandler(svcCtx)),
+			},
+			// Email notifications
+			{
+				Method:  "GET",
+				Path:    "/api/email/status",
+				Handler: authMiddleware(GetEmailStatusHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/email",
+				Handler: authMiddleware(SetEmailHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/email/verify",
+				Handler: authMiddleware(VerifyEmailHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/email/test",
+				Handler: authMiddleware(TestEmailHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/email",
+				Handler: authMiddleware(DeleteEmailHandler(svcCtx)),
+			},
+			// Notifications
+			{
+				Method:  "GET",
+				Path:    "/api/notifications",
+				Handler: authMiddleware(ListNotificationsHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/notifications/preferences",
+				Handler: authMiddleware(GetNotificationPreferencesHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/notifications/preferences",
+				Handler: authMiddleware(UpdateNotificationPreferenceHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/notifications/quiet-hours",
+				Handler: authMiddleware(GetQuietHoursHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/notifications/quiet-hours",
+				Handler: authMiddleware(SetQuietHoursHandler(svcCtx)),
+			},
+			// Reports
+			{
+				Method:  "GET",
+				Path:    "/api/reports/daily",
+				Handler: authMiddleware(GetDailyReportHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/reports/weekly",
+				Handler: authMiddleware(GetWeeklyReportHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/reports/settings",
+				Handler: authMiddleware(GetReportSettingsHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/reports/settings",
+				Handler: authMiddleware(UpdateReportSettingsHandler(svcCtx)),
+			},
 			// Timeline
 			{
 				Method:  "GET",
@@ -194,10 +327,221 @@ func RegisterRoutes(server *rest.Server, svcCtx *svc.ServiceContext) {
 				Path:    "/api/shop/sell",
 				Handler: authMiddleware(SellItemHandler(svcCtx)),
 			},
+			{
+				Method:  "POST",
+				Path:    "/api/shop/equip",
+				Handler: authMiddleware(EquipItemHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/shop/unequip",
+				Handler: authMiddleware(UnequipItemHandler(svcCtx)),
+			},
 			{
 				Method:  "GET",
 				Path:    "/api/shop/history",
-				Handler: authMiddleware(GetPurchaseHistoryHandler(svcCtx)),
+				Handler: authMiddleware(GetInventoryHistoryHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/shop/refund",
+				Handler: authMiddleware(RefundItemHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/shop/bundles",
+				Handler: authMiddleware(GetShopBundlesHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/shop/bundles",
+				Handler: authMiddleware(CreateShopBundleHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/shop/bundles/:id",
+				Handler: authMiddleware(UpdateShopBundleHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/shop/bundles/:id",
+				Handler: authMiddleware(DeleteShopBundleHandler(svcCtx)),
+			},
+			// Loot drop tables
+			{
+				Method:  "GET",
+				Path:    "/api/loot/entries",
+				Handler: authMiddleware(GetDropEntriesHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/loot/entries",
+				Handler: authMiddleware(CreateDropEntryHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/loot/entries/:id",
+				Handler: authMiddleware(UpdateDropEntryHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/loot/entries/:id",
+				Handler: authMiddleware(DeleteDropEntryHandler(svcCtx)),
+			},
+			// Crafting
+			{
+				Method:  "GET",
+				Path:    "/api/crafting/recipes",
+				Handler: authMiddleware(GetRecipesHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/crafting/recipes",
+				Handler: authMiddleware(CreateRecipeHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/crafting/recipes/:id",
+				Handler: authMiddleware(UpdateRecipeHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/crafting/recipes/:id",
+				Handler: authMiddleware(DeleteRecipeHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/crafting/craft",
+				Handler: authMiddleware(CraftHandler(svcCtx)),
+			},
+			// Real-world reward redemption
+			{
+				Method:  "GET",
+				Path:    "/api/redemptions",
+				Handler: authMiddleware(ListRedemptionsHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/redemptions/report",
+				Handler: authMiddleware(GetRedemptionReportHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/redemptions/partner",
+				Handler: authMiddleware(GetPartnerHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/redemptions/partner",
+				Handler: authMiddleware(SetPartnerHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/redemptions/partner",
+				Handler: authMiddleware(RemovePartnerHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/redemptions/partner/requests",
+				Handler: authMiddleware(ListPartnerRequestsHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/partner/requests/:id/accept",
+				Handler: authMiddleware(AcceptPartnerHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/partner/requests/:id/decline",
+				Handler: authMiddleware(DeclinePartnerHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/:id/approve",
+				Handler: authMiddleware(ApproveRedemptionHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/:id/reject",
+				Handler: authMiddleware(RejectRedemptionHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/:id/fulfil",
+				Handler: authMiddleware(FulfilRedemptionHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/redemptions/:id/cancel",
+				Handler: authMiddleware(CancelRedemptionHandler(svcCtx)),
+			},
+			// Savings goals
+			{
+				Method:  "GET",
+				Path:    "/api/savings",
+				Handler: authMiddleware(ListSavingsGoalsHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/savings",
+				Handler: authMiddleware(CreateSavingsGoalHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/savings/:id",
+				Handler: authMiddleware(UpdateSavingsGoalHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/savings/:id",
+				Handler: authMiddleware(ReleaseSavingsGoalHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/savings/:id/deposit",
+				Handler: authMiddleware(DepositSavingsHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/savings/:id/purchase",
+				Handler: authMiddleware(PurchaseSavingsGoalHandler(svcCtx)),
+			},
+			// Webhooks
+			{
+				Method:  "GET",
+				Path:    "/api/webhooks",
+				Handler: authMiddleware(ListWebhooksHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/webhooks",
+				Handler: authMiddleware(CreateWebhookHandler(svcCtx)),
+			},
+			{
+				Method:  "PUT",
+				Path:    "/api/webhooks/:id",
+				Handler: authMiddleware(UpdateWebhookHandler(svcCtx)),
+			},
+			{
+				Method:  "DELETE",
+				Path:    "/api/webhooks/:id",
+				Handler: authMiddleware(DeleteWebhookHandler(svcCtx)),
+			},
+			{
+				Method:  "GET",
+				Path:    "/api/webhooks/:id/deliveries",
+				Handler: authMiddleware(ListWebhookDeliveriesHandler(svcCtx)),
+			},
+			{
+				Method:  "POST",
+				Path:    "/api/webhooks/:id/test",
+				Handler: authMiddleware(TestWebhookHandler(svcCtx)),
+			},
+			// Spirit stone ledger
+			{
+				Method:  "GET",
+				Path:    "/api/ledger",
+				Handler: authMiddleware(GetLedgerHandler(svcCtx)),
 			},
 		},
 	)
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"life-system-backend/internal/logic"
+	"life-system-backend/internal/middleware"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+// ListSavingsGoalsHandler lists savings goals with progress and ETA
+func ListSavingsGoalsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.ListGoals(r.Context(), userID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// CreateSavingsGoalHandler creates a savings goal
+func CreateSavingsGoalHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.CreateSavingsGoalReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.CreateGoal(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// UpdateSavingsGoalHandler changes an open savings goal
+func UpdateSavingsGoalHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		goalID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid goal id"})
+			return
+		}
+
+		var req types.UpdateSavingsGoalReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.UpdateGoal(r.Context(), userID, goalID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: "success",
+			Data:    resp,
+		})
+	}
+}
+
+// ReleaseSavingsGoalHandler cancels a savings goal and returns its stones
+func ReleaseSavingsGoalHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		goalID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid goal id"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.Release(r.Context(), userID, goalID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
+
+// DepositSavingsHandler moves spirit stones into a savings goal
+func DepositSavingsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		goalID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid goal id"})
+			return
+		}
+
+		var req types.SavingsDepositReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.Deposit(r.Context(), userID, goalID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
+
+// PurchaseSavingsGoalHandler buys the linked item with the saved stones
+func PurchaseSavingsGoalHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		vars := pathvar.Vars(r)
+		goalID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid goal id"})
+			return
+		}
+
+		l := logic.NewSavingsLogic(svcCtx)
+		resp, err := l.Purchase(r.Context(), userID, goalID)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
@@ -238,7 +238,7 @@ func SellItemHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	}
 }
 
-func GetPurchaseHistoryHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+func RefundItemHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := middleware.GetUserID(r.Context())
 		if err != nil {
@@ -246,8 +246,43 @@ func GetPurchaseHistoryHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
+		var req types.RefundReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
+		l := logic.NewShopLogic(svcCtx)
+		resp, err := l.RefundItem(r.Context(), userID, &req)
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
+			return
+		}
+
+		httpx.OkJson(w, types.CommonResp{
+			Code:    0,
+			Message: resp.Message,
+			Data:    resp,
+		})
+	}
+}
+
+func GetInventoryHistoryHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r.Context())
+		if err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 401, Message: "unauthorized"})
+			return
+		}
+
+		var req types.InventoryHistoryReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.OkJson(w, types.CommonResp{Code: 400, Message: "invalid request"})
+			return
+		}
+
 		l := logic.NewShopLogic(svcCtx)
-		resp, err := l.GetPurchaseHistory(r.Context(), userID)
+		resp, err := l.GetInventoryHistory(r.Context(), userID, &req)
 		if err != nil {
 			httpx.OkJson(w, types.CommonResp{Code: 400, Message: err.Error()})
 			return
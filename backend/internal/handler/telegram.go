@@ -95,3 +95,15 @@ func UnbindTelegramHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		})
 	}
 }
+
+// TelegramWebhookHandler receives bot updates posted by Telegram in webhook
+// mode. It answers with plain status codes, as Telegram expects.
+func TelegramWebhookHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if svcCtx.TelegramBot == nil {
+			http.NotFound(w, r)
+			return
+		}
+		svcCtx.TelegramBot.ServeWebhook(w, r)
+	}
+}
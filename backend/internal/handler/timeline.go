@@ -114,11 +114,11 @@ func GetTimelineHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			}
 		}
 
-		// 3. Fetch purchase history
+		// 3. Fetch purchases, sales and refunds
 		purchaseRows, err := svcCtx.DB.Query(`
-			SELECT id, item_name, quantity, total_price, created_at
-			FROM purchase_history
-			WHERE user_id = ?
+			SELECT id, kind, item_name, quantity, spirit_stones, created_at
+			FROM inventory_events
+			WHERE user_id = ? AND kind IN ('purchase', 'sell', 'refund')
 			ORDER BY created_at DESC
 			LIMIT 50
 		`, userID)
@@ -126,23 +126,104 @@ func GetTimelineHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			defer purchaseRows.Close()
 			for purchaseRows.Next() {
 				var id int64
-				var itemName, createdAt string
-				var quantity, totalPrice int
-				if err := purchaseRows.Scan(&id, &itemName, &quantity, &totalPrice, &createdAt); err != nil {
+				var kind, itemName, createdAt string
+				var quantity, spiritStones int
+				if err := purchaseRows.Scan(&id, &kind, &itemName, &quantity, &spiritStones, &createdAt); err != nil {
 					continue
 				}
 
-				events = append(events, types.TimelineEvent{
+				event := types.TimelineEvent{
 					ID:          fmt.Sprintf("purchase_%d", id),
 					Type:        "purchase",
 					Title:       fmt.Sprintf("购买：%s", itemName),
-					Description: fmt.Sprintf("购买了 %d 个，花费 %d 灵石", quantity, totalPrice),
+					Description: fmt.Sprintf("购买了 %d 个，花费 %d 灵石", quantity, -spiritStones),
+					Timestamp:   createdAt,
+					Rewards:     &types.TimelineRewards{SpiritStones: spiritStones},
+				}
+				switch kind {
+				case "sell":
+					event.ID = fmt.Sprintf("sell_%d", id)
+					event.Type = "sell"
+					event.Title = fmt.Sprintf("出售：%s", itemName)
+					event.Description = fmt.Sprintf("出售了 %d 个，获得 %d 灵石", -quantity, spiritStones)
+				case "refund":
+					event.ID = fmt.Sprintf("refund_%d", id)
+					event.Type = "refund"
+					event.Title = fmt.Sprintf("退款：%s", itemName)
+					event.Description = fmt.Sprintf("退回了 %d 个，返还 %d 灵石", -quantity, spiritStones)
+				}
+
+				events = append(events, event)
+			}
+		}
+
+		// 4. Fetch loot drops
+		dropRows, err := svcCtx.DB.Query(`
+			SELECT ld.id, ld.item_name, ld.quantity, ld.created_at, COALESCE(t.title, '')
+			FROM loot_drops ld
+			LEFT JOIN tasks t ON ld.task_id = t.id
+			WHERE ld.user_id = ?
+			ORDER BY ld.created_at DESC
+			LIMIT 50
+		`, userID)
+		if err == nil {
+			defer dropRows.Close()
+			for dropRows.Next() {
+				var id int64
+				var itemName, createdAt, taskTitle string
+				var quantity int
+				if err := dropRows.Scan(&id, &itemName, &quantity, &createdAt, &taskTitle); err != nil {
+					continue
+				}
+
+				events = append(events, types.TimelineEvent{
+					ID:          fmt.Sprintf("loot_%d", id),
+					Type:        "loot_drop",
+					Title:       fmt.Sprintf("掉落：%s ×%d", itemName, quantity),
+					Description: fmt.Sprintf("完成任务「%s」时获得", taskTitle),
 					Timestamp:   createdAt,
-					Rewards:     &types.TimelineRewards{SpiritStones: -totalPrice},
 				})
 			}
 		}
 
+		// 5. Fetch crafting
+		craftRows, err := svcCtx.DB.Query(`
+			SELECT id, recipe_name, output_name, attempts, successes, quantity, spirit_stones, created_at
+			FROM crafting_logs
+			WHERE user_id = ?
+			ORDER BY created_at DESC
+			LIMIT 50
+		`, userID)
+		if err == nil {
+			defer craftRows.Close()
+			for craftRows.Next() {
+				var id int64
+				var recipeName, outputName, createdAt string
+				var attempts, successes, quantity, spiritStones int
+				if err := craftRows.Scan(&id, &recipeName, &outputName, &attempts, &successes, &quantity, &spiritStones, &createdAt); err != nil {
+					continue
+				}
+
+				desc := fmt.Sprintf("炼制 %d 次全部失败", attempts)
+				if successes > 0 {
+					desc = fmt.Sprintf("炼制 %d 次，成功 %d 次，获得 %s ×%d", attempts, successes, outputName, quantity)
+				}
+
+				event := types.TimelineEvent{
+					ID:          fmt.Sprintf("craft_%d", id),
+					Type:        "craft",
+					Title:       fmt.Sprintf("炼制：%s", recipeName),
+					Description: desc,
+					Timestamp:   createdAt,
+				}
+				if spiritStones > 0 {
+					event.Rewards = &types.TimelineRewards{SpiritStones: -spiritStones}
+				}
+
+				events = append(events, event)
+			}
+		}
+
 		// Sort all events by timestamp descending
 		parseTime := func(s string) time.Time {
 			formats := []string{
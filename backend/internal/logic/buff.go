@@ -0,0 +1,180 @@
+package logic
+
+import (
+	"fmt"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+	"life-system-backend/internal/types"
+)
+
+const maxBuffStacks = 5
+
+// grantBuff persists a newly granted buff. When a buff with the same modifier
+// and target is still active, the new buff's stacking rule decides how the two
+// merge instead of adding a second row.
+func grantBuff(uow *model.UnitOfWork, buff *model.CharacterBuff, now time.Time) error {
+	existing, err := uow.Buff.FindActive(buff.UserID, buff.Modifier, buff.Target, now)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		_, err := uow.Buff.Create(buff)
+		return err
+	}
+
+	switch buff.Stacking {
+	case model.BuffStackingExtend:
+		if existing.ExpiresAt.Valid && buff.ExpiresAt.Valid {
+			existing.ExpiresAt.Time = existing.ExpiresAt.Time.Add(buff.ExpiresAt.Time.Sub(now))
+		}
+		if existing.RemainingUses > 0 && buff.RemainingUses > 0 {
+			existing.RemainingUses += buff.RemainingUses
+		}
+	case model.BuffStackingStack:
+		if existing.Stacks < maxBuffStacks {
+			existing.Stacks++
+		}
+		existing.Value = buff.Value
+		existing.ExpiresAt = buff.ExpiresAt
+		existing.RemainingUses = buff.RemainingUses
+	default:
+		existing.Value = buff.Value
+		existing.Stacks = 1
+		existing.ExpiresAt = buff.ExpiresAt
+		existing.RemainingUses = buff.RemainingUses
+	}
+	existing.Name = buff.Name
+	existing.Stacking = buff.Stacking
+
+	return uow.Buff.Update(existing)
+}
+
+// taskBuffs applies active buffs and equipment passives to one task
+// completion and remembers which buffs took part, so use-limited buffs are
+// only spent when they mattered.
+type taskBuffs struct {
+	buffs []*model.CharacterBuff
+	used  map[int64]bool
+}
+
+func loadTaskBuffs(uow *model.UnitOfWork, userID int64, now time.Time) (*taskBuffs, error) {
+	buffs, err := uow.Buff.FindActiveByUserID(userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	passives, err := equipmentBuffs(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+	buffs = append(buffs, passives...)
+
+	return &taskBuffs{
+		buffs: buffs,
+		used:  make(map[int64]bool),
+	}, nil
+}
+
+// percent sums the matching buffs and marks them used.
+func (t *taskBuffs) percent(modifier, target string) int {
+	total := 0
+	for _, b := range t.buffs {
+		if b.Modifier != modifier || b.Target != target {
+			continue
+		}
+		total += b.EffectiveValue()
+		t.used[b.ID] = true
+	}
+	return total
+}
+
+// scale applies the matching buffs to a non-negative amount.
+func (t *taskBuffs) scale(amount int, modifier, target string) int {
+	if amount <= 0 {
+		return amount
+	}
+	scaled := amount * (100 + t.percent(modifier, target)) / 100
+	if scaled < 0 {
+		return 0
+	}
+	return scaled
+}
+
+func (t *taskBuffs) scaleFloat(amount float64, modifier, target string) float64 {
+	if amount <= 0 {
+		return amount
+	}
+	scaled := amount * float64(100+t.percent(modifier, target)) / 100
+	if scaled < 0 {
+		return 0
+	}
+	return scaled
+}
+
+// consume spends one use of every use-limited buff that took part and
+// deletes the ones that ran out.
+func (t *taskBuffs) consume(uow *model.UnitOfWork) error {
+	for _, b := range t.buffs {
+		if !t.used[b.ID] || b.RemainingUses <= 0 {
+			continue
+		}
+
+		b.RemainingUses--
+		if b.RemainingUses == 0 {
+			if err := uow.Buff.Delete(b.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := uow.Buff.Update(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeBuff(b *model.CharacterBuff) string {
+	switch b.Modifier {
+	case model.BuffModifierSpiritStones:
+		return fmt.Sprintf("任务灵石奖励 %+d%%", b.EffectiveValue())
+	case model.BuffModifierAttrGain:
+		name := b.Target
+		if info, ok := realm.AttrDisplay[b.Target]; ok {
+			name = info.Name
+		}
+		return fmt.Sprintf("%s任务收益 %+d%%", name, b.EffectiveValue())
+	case model.BuffModifierFatigueCost:
+		return fmt.Sprintf("任务疲劳消耗 %+d%%", b.EffectiveValue())
+	}
+	return b.Modifier
+}
+
+func buffsToResp(buffs []*model.CharacterBuff) []types.BuffResp {
+	resp := make([]types.BuffResp, 0, len(buffs))
+	for _, b := range buffs {
+		expiresAt := ""
+		if b.ExpiresAt.Valid {
+			expiresAt = b.ExpiresAt.Time.Local().Format(time.RFC3339)
+		}
+		// Higher fatigue cost is the bad direction
+		debuff := b.Value < 0
+		if b.Modifier == model.BuffModifierFatigueCost {
+			debuff = b.Value > 0
+		}
+		resp = append(resp, types.BuffResp{
+			ID:            b.ID,
+			Name:          b.Name,
+			Modifier:      b.Modifier,
+			Target:        b.Target,
+			Value:         b.EffectiveValue(),
+			Stacks:        b.Stacks,
+			RemainingUses: b.RemainingUses,
+			ExpiresAt:     expiresAt,
+			Description:   describeBuff(b),
+			IsDebuff:      debuff,
+		})
+	}
+	return resp
+}
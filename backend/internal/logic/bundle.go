@@ -0,0 +1,268 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/types"
+)
+
+func (l *ShopLogic) GetBundles(ctx context.Context, userID int64) (*types.ShopBundleListResp, error) {
+	bundles, err := l.svcCtx.ShopModel.GetBundlesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.ShopBundleListResp{
+		Bundles: make([]types.ShopBundleResp, 0, len(bundles)),
+	}
+	for _, bundle := range bundles {
+		resp.Bundles = append(resp.Bundles, l.bundleToResp(bundle))
+	}
+
+	return resp, nil
+}
+
+func (l *ShopLogic) CreateBundle(ctx context.Context, userID int64, req *types.CreateShopBundleReq) (*types.ShopBundleResp, error) {
+	bundle := &model.ShopBundle{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Icon:        req.Icon,
+		Items:       toBundleItems(req.Items),
+		Price:       req.Price,
+		Stock:       req.Stock,
+	}
+	if err := l.validateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	id, err := l.svcCtx.ShopModel.CreateBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	bundle.ID = id
+
+	resp := l.bundleToResp(bundle)
+	return &resp, nil
+}
+
+func (l *ShopLogic) UpdateBundle(ctx context.Context, userID int64, bundleID int64, req *types.UpdateShopBundleReq) (*types.ShopBundleResp, error) {
+	bundle, err := l.svcCtx.ShopModel.GetBundleByID(bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil || bundle.UserID != userID {
+		return nil, fmt.Errorf("礼包不存在")
+	}
+
+	if req.Name != nil {
+		bundle.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		bundle.Description = *req.Description
+	}
+	if req.Icon != nil {
+		bundle.Icon = *req.Icon
+	}
+	if req.Items != nil {
+		bundle.Items = toBundleItems(req.Items)
+	}
+	if req.Price != nil {
+		bundle.Price = *req.Price
+	}
+	if req.Stock != nil {
+		bundle.Stock = *req.Stock
+	}
+	if err := l.validateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	if err := l.svcCtx.ShopModel.UpdateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	resp := l.bundleToResp(bundle)
+	return &resp, nil
+}
+
+func (l *ShopLogic) DeleteBundle(ctx context.Context, userID int64, bundleID int64) error {
+	bundle, err := l.svcCtx.ShopModel.GetBundleByID(bundleID)
+	if err != nil {
+		return err
+	}
+	if bundle == nil || bundle.UserID != userID {
+		return fmt.Errorf("礼包不存在")
+	}
+
+	return l.svcCtx.ShopModel.DeleteBundle(bundleID, userID)
+}
+
+// purchaseBundle charges the bundle price once and puts every contained item
+// into the bag. Only the bundle's own stock is consumed.
+func (l *ShopLogic) purchaseBundle(uow *model.UnitOfWork, userID int64, req *types.PurchaseItemReq) (*types.PurchaseResult, error) {
+	bundle, err := uow.Shop.GetBundleByID(req.BundleID)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil || bundle.UserID != userID {
+		return nil, fmt.Errorf("礼包不存在")
+	}
+	if bundle.Stock != -1 && bundle.Stock < req.Quantity {
+		return nil, fmt.Errorf("库存不足")
+	}
+
+	items := make([]*model.ShopItem, 0, len(bundle.Items))
+	for _, bi := range bundle.Items {
+		item, err := uow.Shop.GetItemByID(bi.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			return nil, fmt.Errorf("礼包中的商品已下架")
+		}
+		items = append(items, item)
+	}
+
+	totalPrice := bundle.Price * req.Quantity
+
+	stats, err := uow.Character.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+	if stats.SpiritStones < totalPrice {
+		return nil, fmt.Errorf("灵石不足！需要 %d 灵石，当前只有 %d 灵石", totalPrice, stats.SpiritStones)
+	}
+
+	if err := adjustSpiritStones(uow, stats, -totalPrice, model.LedgerReasonPurchase, model.LedgerRefBundle, bundle.ID); err != nil {
+		return nil, err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
+	}
+
+	if bundle.Stock != -1 {
+		if err := uow.Shop.UpdateBundleStock(bundle.ID, req.Quantity); err != nil {
+			if err == model.ErrConflict {
+				return nil, fmt.Errorf("库存不足")
+			}
+			return nil, err
+		}
+	}
+
+	event := &model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventoryPurchase,
+		ItemName:     bundle.Name,
+		Quantity:     req.Quantity,
+		SpiritStones: -totalPrice,
+		RefType:      model.LedgerRefBundle,
+		RefID:        bundle.ID,
+	}
+	if _, err := uow.Shop.RecordEvent(event); err != nil {
+		return nil, err
+	}
+	if err := enqueuePurchase(uow, event); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(items))
+	for i, item := range items {
+		quantity := bundle.Items[i].Quantity * req.Quantity
+		if err := uow.Shop.AddToInventory(userID, item.ID, quantity); err != nil {
+			return nil, err
+		}
+		names = append(names, fmt.Sprintf("%s×%d", item.Name, quantity))
+	}
+
+	return &types.PurchaseResult{
+		Success:               true,
+		Message:               fmt.Sprintf("成功购买 %d 个礼包「%s」，获得 %s", req.Quantity, bundle.Name, strings.Join(names, "、")),
+		RemainingSpiritStones: stats.SpiritStones,
+	}, nil
+}
+
+func (l *ShopLogic) validateBundle(bundle *model.ShopBundle) error {
+	if bundle.Name == "" {
+		return fmt.Errorf("礼包名称不能为空")
+	}
+	if bundle.Price < 0 {
+		return fmt.Errorf("价格不能为负数")
+	}
+	if bundle.Stock < -1 {
+		return fmt.Errorf("库存不能为负数")
+	}
+	if len(bundle.Items) == 0 {
+		return fmt.Errorf("礼包至少需要一件商品")
+	}
+
+	seen := make(map[int64]bool)
+	for _, bi := range bundle.Items {
+		if bi.Quantity <= 0 {
+			return fmt.Errorf("商品数量必须大于0")
+		}
+		if seen[bi.ItemID] {
+			return fmt.Errorf("礼包中的商品不能重复")
+		}
+		seen[bi.ItemID] = true
+
+		item, err := l.svcCtx.ShopModel.GetItemByID(bi.ItemID)
+		if err != nil {
+			return err
+		}
+		if item == nil || item.UserID != bundle.UserID {
+			return fmt.Errorf("礼包中的商品不存在")
+		}
+		if item.ItemType == "redeemable" {
+			return fmt.Errorf("实物奖励只能单独兑换")
+		}
+	}
+
+	return nil
+}
+
+func (l *ShopLogic) bundleToResp(bundle *model.ShopBundle) types.ShopBundleResp {
+	resp := types.ShopBundleResp{
+		ID:          bundle.ID,
+		Name:        bundle.Name,
+		Description: bundle.Description,
+		Icon:        bundle.Icon,
+		Items:       make([]types.BundleItemResp, 0, len(bundle.Items)),
+		Price:       bundle.Price,
+		Stock:       bundle.Stock,
+	}
+
+	now := time.Now()
+	for _, bi := range bundle.Items {
+		itemResp := types.BundleItemResp{
+			ItemID:   bi.ItemID,
+			Quantity: bi.Quantity,
+		}
+		item, err := l.svcCtx.ShopModel.GetItemByID(bi.ItemID)
+		if err == nil && item != nil {
+			itemResp.Name = item.Name
+			itemResp.Icon = item.Icon
+			resp.OriginalPrice += item.EffectivePrice(now) * bi.Quantity
+		}
+		resp.Items = append(resp.Items, itemResp)
+	}
+
+	return resp
+}
+
+func toBundleItems(reqs []types.BundleItemReq) []model.BundleItem {
+	items := make([]model.BundleItem, 0, len(reqs))
+	for _, r := range reqs {
+		items = append(items, model.BundleItem{
+			ItemID:   r.ItemID,
+			Quantity: r.Quantity,
+		})
+	}
+	return items
+}
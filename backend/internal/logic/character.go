@@ -22,7 +22,8 @@ func NewCharacterLogic(svcCtx *svc.ServiceContext) *CharacterLogic {
 }
 
 func (l *CharacterLogic) GetCharacter(ctx context.Context, userID int64) (*types.CharacterResp, error) {
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	uow := l.svcCtx.Read()
+	stats, err := uow.Character.FindByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -30,19 +31,33 @@ func (l *CharacterLogic) GetCharacter(ctx context.Context, userID int64) (*types
 		return nil, fmt.Errorf("character not found")
 	}
 
-	attrs, err := l.svcCtx.CharacterModel.FindAttributesByUserID(userID)
+	attrs, err := uow.Character.FindAttributesByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Lazily trigger daily fatigue reset
-	if l.CheckAndResetDailyFatigue(stats) {
-		if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
+	// Lazily trigger daily fatigue reset. Update rewrites the whole row, so
+	// the reset reloads the stats inside a transaction.
+	if stats.LastFatigueReset != time.Now().Format("2006-01-02") {
+		err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+			fresh, err := uow.Character.FindByUserID(userID)
+			if err != nil {
+				return err
+			}
+			if l.CheckAndResetDailyFatigue(fresh) {
+				if err := uow.Character.Update(fresh); err != nil {
+					return err
+				}
+			}
+			stats = fresh
+			return nil
+		})
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	return l.statsToResp(stats, attrs), nil
+	return l.characterResp(uow, stats, attrs)
 }
 
 // CheckAndResetDailyFatigue resets fatigue when a new day starts.
@@ -68,6 +83,31 @@ func (l *CharacterLogic) CheckAndResetDailyFatigue(stats *model.CharacterStats)
 	return true
 }
 
+// characterResp renders the character together with its active buffs,
+// equipped items and savings.
+func (l *CharacterLogic) characterResp(uow *model.UnitOfWork, stats *model.CharacterStats, attrs []*model.CharacterAttribute) (*types.CharacterResp, error) {
+	buffs, err := uow.Buff.FindActiveByUserID(stats.UserID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	equipment, err := equipmentResp(uow, stats.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := uow.Savings.LockedByUserID(stats.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := l.statsToResp(stats, attrs)
+	resp.SavedSpiritStones = saved
+	resp.Buffs = buffsToResp(buffs)
+	resp.Equipment = equipment
+	return resp, nil
+}
+
 func (l *CharacterLogic) statsToResp(stats *model.CharacterStats, attrs []*model.CharacterAttribute) *types.CharacterResp {
 	resp := &types.CharacterResp{
 		UserID:           stats.UserID,
@@ -79,10 +119,12 @@ func (l *CharacterLogic) statsToResp(stats *model.CharacterStats, attrs []*model
 		Title:            stats.Title,
 		LastActivityDate: stats.LastActivityDate,
 		Attributes:       make([]types.AttributeResp, 0, len(attrs)),
+		Buffs:            make([]types.BuffResp, 0),
+		Equipment:        make([]types.EquippedItemResp, 0),
 	}
 
 	today := time.Now().Format("2006-01-02")
-	
+
 	for _, attr := range attrs {
 		display, ok := realm.AttrDisplay[attr.AttrKey]
 		if !ok {
@@ -0,0 +1,202 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"life-system-backend/internal/config"
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+const concurrentCallers = 8
+
+// newTestService opens a temp-file database with the production DSN, so
+// transactions take the same immediate write lock they do in production.
+func newTestService(t *testing.T) (*svc.ServiceContext, *sql.DB) {
+	t.Helper()
+
+	db, err := model.NewDB(filepath.Join(t.TempDir(), "life.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := model.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return svc.NewServiceContext(config.Config{}, db, nil), db
+}
+
+// newTestUser registers a user and grants stones through the ledger
+func newTestUser(t *testing.T, svcCtx *svc.ServiceContext, username string, stones int) int64 {
+	t.Helper()
+
+	auth, err := NewAuthLogic(svcCtx).Register(context.Background(), &types.RegisterReq{
+		Username: username,
+		Password: "password1",
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	userID := auth.User.ID
+
+	err = svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		stats, err := uow.Character.FindByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if err := adjustSpiritStones(uow, stats, stones, model.LedgerReasonOpening, "", 0); err != nil {
+			return err
+		}
+		return uow.Character.Update(stats)
+	})
+	if err != nil {
+		t.Fatalf("grant stones: %v", err)
+	}
+	return userID
+}
+
+// runConcurrently calls fn from n goroutines released at the same moment
+func runConcurrently(n int, fn func() error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// assertOneWinner checks exactly one call succeeded and every other one lost
+// the race in an expected way
+func assertOneWinner(t *testing.T, errs []error, lost ...string) {
+	t.Helper()
+
+	successes := 0
+	for _, err := range errs {
+		if err == nil {
+			successes++
+			continue
+		}
+		if errors.Is(err, model.ErrConflict) {
+			continue
+		}
+		expected := false
+		for _, msg := range lost {
+			if strings.Contains(err.Error(), msg) {
+				expected = true
+			}
+		}
+		if !expected {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if successes != 1 {
+		t.Errorf("got %d successes, want exactly 1", successes)
+	}
+}
+
+func assertLedgerBalanced(t *testing.T, db *sql.DB, userID int64) {
+	t.Helper()
+
+	var sum, balance int
+	if err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM spirit_stone_ledger WHERE user_id = ?", userID).Scan(&sum); err != nil {
+		t.Fatalf("sum ledger: %v", err)
+	}
+	if err := db.QueryRow("SELECT spirit_stones FROM character_stats WHERE user_id = ?", userID).Scan(&balance); err != nil {
+		t.Fatalf("read balance: %v", err)
+	}
+	if sum != balance {
+		t.Errorf("ledger sums to %d, balance is %d", sum, balance)
+	}
+}
+
+func TestConcurrentPurchaseOfLastUnit(t *testing.T) {
+	svcCtx, db := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 1000)
+	ctx := context.Background()
+
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{
+		Name:        "回春丹",
+		Price:       50,
+		SellPrice:   10,
+		ItemType:    "consumable",
+		Effect:      "fatigue_restore",
+		EffectValue: 10,
+		Stock:       1,
+	})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	errs := runConcurrently(concurrentCallers, func() error {
+		_, err := NewShopLogic(svcCtx).PurchaseItem(ctx, userID, &types.PurchaseItemReq{ItemID: item.ID, Quantity: 1})
+		return err
+	})
+	assertOneWinner(t, errs, "库存不足")
+
+	var stock int
+	if err := db.QueryRow("SELECT stock FROM shop_items WHERE id = ?", item.ID).Scan(&stock); err != nil {
+		t.Fatalf("read stock: %v", err)
+	}
+	if stock != 0 {
+		t.Errorf("stock is %d, want 0", stock)
+	}
+
+	inv, err := svcCtx.ShopModel.GetInventoryItemByItemID(userID, item.ID)
+	if err != nil {
+		t.Fatalf("read inventory: %v", err)
+	}
+	if inv == nil || inv.Quantity != 1 {
+		t.Errorf("inventory holds %v, want 1 item", inv)
+	}
+
+	assertLedgerBalanced(t, db, userID)
+}
+
+func TestConcurrentCompletionOfOnceTask(t *testing.T) {
+	svcCtx, db := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 100)
+	ctx := context.Background()
+
+	task, err := NewTaskLogic(svcCtx).CreateTask(ctx, userID, &types.CreateTaskReq{
+		Title:              "晨跑",
+		Type:               "once",
+		Difficulty:         1,
+		RewardSpiritStones: 20,
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	errs := runConcurrently(concurrentCallers, func() error {
+		_, err := NewTaskLogic(svcCtx).CompleteTask(ctx, userID, task.ID, "web")
+		return err
+	})
+	assertOneWinner(t, errs, "task is not active")
+
+	var rewards int
+	if err := db.QueryRow("SELECT COUNT(*) FROM spirit_stone_ledger WHERE user_id = ? AND reason = ?",
+		userID, model.LedgerReasonTaskReward).Scan(&rewards); err != nil {
+		t.Fatalf("count rewards: %v", err)
+	}
+	if rewards != 1 {
+		t.Errorf("task rewarded %d times, want 1", rewards)
+	}
+
+	assertLedgerBalanced(t, db, userID)
+}
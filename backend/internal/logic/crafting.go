@@ -0,0 +1,429 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"life-system-backend/internal/loot"
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+const maxCraftTimes = 99
+
+type CraftingLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewCraftingLogic(svcCtx *svc.ServiceContext) *CraftingLogic {
+	return &CraftingLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *CraftingLogic) GetRecipes(ctx context.Context, userID int64) (*types.RecipeListResp, error) {
+	recipes, err := l.svcCtx.CraftingModel.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]types.RecipeResp, 0, len(recipes))
+	for _, r := range recipes {
+		recipeResp, err := l.recipeToResp(r, stats)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, recipeResp)
+	}
+
+	return &types.RecipeListResp{Recipes: resp}, nil
+}
+
+func (l *CraftingLogic) CreateRecipe(ctx context.Context, userID int64, req *types.CreateRecipeReq) (*types.RecipeResp, error) {
+	recipe := &model.Recipe{
+		UserID:          userID,
+		Name:            strings.TrimSpace(req.Name),
+		Description:     req.Description,
+		Ingredients:     toIngredients(req.Ingredients),
+		SpiritStoneCost: req.SpiritStoneCost,
+		OutputItemID:    req.OutputItemID,
+		OutputQuantity:  req.OutputQuantity,
+		SuccessRate:     req.SuccessRate,
+	}
+	if err := l.validateRecipe(recipe); err != nil {
+		return nil, err
+	}
+
+	id, err := l.svcCtx.CraftingModel.Create(recipe)
+	if err != nil {
+		return nil, err
+	}
+	recipe.ID = id
+
+	return l.recipeResp(recipe)
+}
+
+func (l *CraftingLogic) UpdateRecipe(ctx context.Context, userID int64, recipeID int64, req *types.UpdateRecipeReq) (*types.RecipeResp, error) {
+	recipe, err := l.svcCtx.CraftingModel.FindByID(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe == nil || recipe.UserID != userID {
+		return nil, fmt.Errorf("配方不存在")
+	}
+
+	if req.Name != nil {
+		recipe.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		recipe.Description = *req.Description
+	}
+	if req.Ingredients != nil {
+		recipe.Ingredients = toIngredients(*req.Ingredients)
+	}
+	if req.SpiritStoneCost != nil {
+		recipe.SpiritStoneCost = *req.SpiritStoneCost
+	}
+	if req.OutputItemID != nil {
+		recipe.OutputItemID = *req.OutputItemID
+	}
+	if req.OutputQuantity != nil {
+		recipe.OutputQuantity = *req.OutputQuantity
+	}
+	if req.SuccessRate != nil {
+		recipe.SuccessRate = *req.SuccessRate
+	}
+	if err := l.validateRecipe(recipe); err != nil {
+		return nil, err
+	}
+
+	if err := l.svcCtx.CraftingModel.Update(recipe); err != nil {
+		return nil, err
+	}
+
+	return l.recipeResp(recipe)
+}
+
+func (l *CraftingLogic) DeleteRecipe(ctx context.Context, userID int64, recipeID int64) error {
+	recipe, err := l.svcCtx.CraftingModel.FindByID(recipeID)
+	if err != nil {
+		return err
+	}
+	if recipe == nil || recipe.UserID != userID {
+		return fmt.Errorf("配方不存在")
+	}
+
+	return l.svcCtx.CraftingModel.Delete(recipeID, userID)
+}
+
+func (l *CraftingLogic) Craft(ctx context.Context, userID int64, req *types.CraftReq) (*types.CraftResult, error) {
+	if req.Times <= 0 || req.Times > maxCraftTimes {
+		return nil, fmt.Errorf("炼制次数必须在 1-%d 之间", maxCraftTimes)
+	}
+
+	var result *types.CraftResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.craft(uow, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// craft spends the materials and spirit stones for every attempt up front,
+// then rolls each attempt against the recipe's success rate.
+func (l *CraftingLogic) craft(uow *model.UnitOfWork, userID int64, req *types.CraftReq) (*types.CraftResult, error) {
+	recipe, err := uow.Crafting.FindByID(req.RecipeID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe == nil || recipe.UserID != userID {
+		return nil, fmt.Errorf("配方不存在")
+	}
+
+	output, err := uow.Shop.GetItemByID(recipe.OutputItemID)
+	if err != nil {
+		return nil, err
+	}
+	if output == nil || output.UserID != userID {
+		return nil, fmt.Errorf("产物已不存在，请修改配方")
+	}
+
+	stats, err := uow.Character.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+
+	cost := recipe.SpiritStoneCost * req.Times
+	if stats.SpiritStones < cost {
+		return nil, fmt.Errorf("灵石不足，需要 %d 灵石", cost)
+	}
+
+	for _, ing := range recipe.Ingredients {
+		need := ing.Quantity * req.Times
+		available, err := craftableQuantity(uow, userID, ing.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if available < need {
+			return nil, fmt.Errorf("材料「%s」不足，需要 %d 个", l.itemName(uow, ing.ItemID), need)
+		}
+		if err := uow.Shop.RemoveFromInventory(userID, ing.ItemID, need); err != nil {
+			if err == model.ErrConflict {
+				return nil, fmt.Errorf("材料不足")
+			}
+			return nil, err
+		}
+		_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+			UserID:   userID,
+			Kind:     model.InventoryCraft,
+			ItemID:   ing.ItemID,
+			ItemName: l.itemName(uow, ing.ItemID),
+			Quantity: -need,
+			RefType:  model.LedgerRefRecipe,
+			RefID:    recipe.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := adjustSpiritStones(uow, stats, -cost, model.LedgerReasonCrafting, model.LedgerRefRecipe, recipe.ID); err != nil {
+		return nil, err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
+	}
+
+	attempts, err := uow.Crafting.CountAttempts(userID, recipe.ID)
+	if err != nil {
+		return nil, err
+	}
+	rng := loot.NewRand(l.svcCtx.Config.Loot.Seed, recipe.ID, attempts)
+
+	successes := 0
+	for i := 0; i < req.Times; i++ {
+		if rng.Float64()*100 < recipe.SuccessRate {
+			successes++
+		}
+	}
+
+	produced := successes * recipe.OutputQuantity
+	if produced > 0 {
+		if err := uow.Shop.AddToInventory(userID, output.ID, produced); err != nil {
+			return nil, err
+		}
+	}
+	// Recorded even when every attempt failed, to account for the spirit stones
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventoryCraft,
+		ItemID:       output.ID,
+		ItemName:     output.Name,
+		Quantity:     produced,
+		SpiritStones: -cost,
+		RefType:      model.LedgerRefRecipe,
+		RefID:        recipe.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = uow.Crafting.CreateLog(&model.CraftLog{
+		UserID:       userID,
+		RecipeID:     recipe.ID,
+		RecipeName:   recipe.Name,
+		OutputItemID: output.ID,
+		OutputName:   output.Name,
+		Attempts:     req.Times,
+		Successes:    successes,
+		Quantity:     produced,
+		SpiritStones: cost,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var message string
+	switch {
+	case successes == 0:
+		message = fmt.Sprintf("💨 炼制「%s」失败，材料已消耗", recipe.Name)
+	case req.Times == 1:
+		message = fmt.Sprintf("⚗️ 炼制成功！获得 %s%s ×%d", output.Icon, output.Name, produced)
+	default:
+		message = fmt.Sprintf("⚗️ 炼制 %d 次，成功 %d 次，获得 %s%s ×%d", req.Times, successes, output.Icon, output.Name, produced)
+	}
+
+	return &types.CraftResult{
+		Success:               true,
+		Message:               message,
+		Attempts:              req.Times,
+		Successes:             successes,
+		Produced:              produced,
+		RemainingSpiritStones: stats.SpiritStones,
+	}, nil
+}
+
+// craftableQuantity is how many units of an item may be used as material.
+// The equipped unit stays in the bag, as with selling.
+func craftableQuantity(uow *model.UnitOfWork, userID, itemID int64) (int, error) {
+	invItem, err := uow.Shop.GetInventoryItemByItemID(userID, itemID)
+	if err != nil || invItem == nil {
+		return 0, err
+	}
+
+	equipped, err := uow.Equipment.IsEquipped(userID, itemID)
+	if err != nil {
+		return 0, err
+	}
+	if equipped {
+		return invItem.Quantity - 1, nil
+	}
+	return invItem.Quantity, nil
+}
+
+func (l *CraftingLogic) itemName(uow *model.UnitOfWork, itemID int64) string {
+	item, err := uow.Shop.GetItemByID(itemID)
+	if err != nil || item == nil {
+		return fmt.Sprintf("#%d", itemID)
+	}
+	return item.Name
+}
+
+func (l *CraftingLogic) validateRecipe(recipe *model.Recipe) error {
+	if recipe.Name == "" {
+		return fmt.Errorf("配方名称不能为空")
+	}
+	if len(recipe.Ingredients) == 0 {
+		return fmt.Errorf("配方至少需要一种材料")
+	}
+	if recipe.SpiritStoneCost < 0 {
+		return fmt.Errorf("灵石消耗不能为负数")
+	}
+	if recipe.OutputQuantity <= 0 {
+		return fmt.Errorf("产出数量必须大于0")
+	}
+	if recipe.SuccessRate <= 0 || recipe.SuccessRate > 100 {
+		return fmt.Errorf("成功率必须在 0-100 之间")
+	}
+
+	output, err := l.svcCtx.ShopModel.GetItemByID(recipe.OutputItemID)
+	if err != nil {
+		return err
+	}
+	if output == nil || output.UserID != recipe.UserID {
+		return fmt.Errorf("产物商品不存在")
+	}
+	if output.ItemType == "redeemable" {
+		return fmt.Errorf("实物奖励只能通过兑换获得")
+	}
+
+	seen := make(map[int64]bool)
+	for _, ing := range recipe.Ingredients {
+		if ing.Quantity <= 0 {
+			return fmt.Errorf("材料数量必须大于0")
+		}
+		if seen[ing.ItemID] {
+			return fmt.Errorf("材料不能重复")
+		}
+		seen[ing.ItemID] = true
+
+		item, err := l.svcCtx.ShopModel.GetItemByID(ing.ItemID)
+		if err != nil {
+			return err
+		}
+		if item == nil || item.UserID != recipe.UserID {
+			return fmt.Errorf("材料商品不存在")
+		}
+	}
+
+	return nil
+}
+
+func (l *CraftingLogic) recipeResp(recipe *model.Recipe) (*types.RecipeResp, error) {
+	stats, err := l.svcCtx.CharacterModel.FindByUserID(recipe.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.recipeToResp(recipe, stats)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (l *CraftingLogic) recipeToResp(recipe *model.Recipe, stats *model.CharacterStats) (types.RecipeResp, error) {
+	resp := types.RecipeResp{
+		ID:              recipe.ID,
+		Name:            recipe.Name,
+		Description:     recipe.Description,
+		Ingredients:     make([]types.IngredientResp, 0, len(recipe.Ingredients)),
+		SpiritStoneCost: recipe.SpiritStoneCost,
+		OutputItemID:    recipe.OutputItemID,
+		OutputQuantity:  recipe.OutputQuantity,
+		SuccessRate:     recipe.SuccessRate,
+		CanCraft:        stats != nil && stats.SpiritStones >= recipe.SpiritStoneCost,
+	}
+
+	output, err := l.svcCtx.ShopModel.GetItemByID(recipe.OutputItemID)
+	if err != nil {
+		return resp, err
+	}
+	if output != nil {
+		resp.OutputName = output.Name
+		resp.OutputIcon = output.Icon
+	} else {
+		resp.CanCraft = false
+	}
+
+	for _, ing := range recipe.Ingredients {
+		ingResp := types.IngredientResp{
+			ItemID:   ing.ItemID,
+			Quantity: ing.Quantity,
+		}
+		item, err := l.svcCtx.ShopModel.GetItemByID(ing.ItemID)
+		if err != nil {
+			return resp, err
+		}
+		if item != nil {
+			ingResp.Name = item.Name
+			ingResp.Icon = item.Icon
+		}
+		invItem, err := l.svcCtx.ShopModel.GetInventoryItemByItemID(recipe.UserID, ing.ItemID)
+		if err != nil {
+			return resp, err
+		}
+		if invItem != nil {
+			ingResp.Owned = invItem.Quantity
+		}
+		if ingResp.Owned < ing.Quantity {
+			resp.CanCraft = false
+		}
+		resp.Ingredients = append(resp.Ingredients, ingResp)
+	}
+
+	return resp, nil
+}
+
+func toIngredients(reqs []types.IngredientReq) []model.Ingredient {
+	ingredients := make([]model.Ingredient, 0, len(reqs))
+	for _, r := range reqs {
+		ingredients = append(ingredients, model.Ingredient{ItemID: r.ItemID, Quantity: r.Quantity})
+	}
+	return ingredients
+}
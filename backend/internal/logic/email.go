@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/email"
+	"life-system-backend/pkg/notify"
+)
+
+// emailVerifyTTL is how long a verification code stays valid
+const emailVerifyTTL = 30 * time.Minute
+
+type EmailLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewEmailLogic(svcCtx *svc.ServiceContext) *EmailLogic {
+	return &EmailLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *EmailLogic) GetStatus(ctx context.Context, userID int64) (*types.EmailStatusResp, error) {
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	return &types.EmailStatusResp{
+		Available:    l.svcCtx.EmailSender != nil,
+		Email:        user.Email,
+		Verified:     user.EmailVerified,
+		Unsubscribed: user.EmailUnsubscribed,
+	}, nil
+}
+
+// SetEmail stores an unverified address and mails it a verification code.
+// Notifications are only sent once the code is confirmed.
+func (l *EmailLogic) SetEmail(ctx context.Context, userID int64, req *types.SetEmailReq) (*types.SetEmailResp, error) {
+	if l.svcCtx.EmailSender == nil {
+		return nil, fmt.Errorf("邮件服务未启用")
+	}
+
+	address := strings.TrimSpace(req.Email)
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return nil, fmt.Errorf("邮箱地址格式不正确")
+	}
+
+	code, err := generateDigitCode(6)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.UserModel.SetEmail(userID, address, code, time.Now().Add(emailVerifyTTL)); err != nil {
+		return nil, err
+	}
+
+	html, err := email.Render(email.TemplateVerify, &email.View{
+		Title:      "验证你的邮箱",
+		Paragraphs: []string{fmt.Sprintf("%d 分钟内有效", int(emailVerifyTTL.Minutes()))},
+		Code:       code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = l.svcCtx.EmailSender.Send(&email.Message{
+		To:      address,
+		Subject: "Life System 邮箱验证码",
+		HTML:    html,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("验证邮件发送失败: %w", err)
+	}
+
+	return &types.SetEmailResp{
+		Email:     address,
+		ExpiresIn: int(emailVerifyTTL.Seconds()),
+	}, nil
+}
+
+func (l *EmailLogic) Verify(ctx context.Context, userID int64, req *types.VerifyEmailReq) (*types.EmailStatusResp, error) {
+	ok, err := l.svcCtx.UserModel.VerifyEmail(userID, strings.TrimSpace(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("验证码无效或已过期")
+	}
+
+	return l.GetStatus(ctx, userID)
+}
+
+// Test mails a test notification to the verified address
+func (l *EmailLogic) Test(ctx context.Context, userID int64) error {
+	channel := l.svcCtx.Notifier.Channel(notify.ChannelEmail)
+	if channel == nil {
+		return fmt.Errorf("邮件服务未启用")
+	}
+
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.EmailVerified {
+		return fmt.Errorf("请先设置并验证邮箱")
+	}
+	if user.EmailUnsubscribed {
+		return fmt.Errorf("你已退订全部邮件，重新验证邮箱后可恢复")
+	}
+
+	err = channel.Send(user, &notify.Notification{
+		Title: "📧 Life System 测试",
+		Body:  "恭喜！邮件通知配置成功！",
+	})
+	if err != nil {
+		return fmt.Errorf("发送失败: %w", err)
+	}
+	return nil
+}
+
+func (l *EmailLogic) Delete(ctx context.Context, userID int64) error {
+	return l.svcCtx.UserModel.ClearEmail(userID)
+}
+
+// Unsubscribe handles a signed link from a notification mail. Kind "all"
+// stops all notification mail; any other kind only drops email from that
+// kind's channels. Returns a message for the confirmation page.
+func (l *EmailLogic) Unsubscribe(ctx context.Context, req *types.UnsubscribeEmailReq) (string, error) {
+	if !email.VerifyUnsubscribe(l.svcCtx.Config.Auth.Secret, req.UserID, req.Kind, req.Sig) {
+		return "", fmt.Errorf("退订链接无效")
+	}
+
+	user, err := l.svcCtx.UserModel.FindByID(req.UserID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", fmt.Errorf("用户不存在")
+	}
+
+	if req.Kind == email.UnsubscribeAll {
+		if err := l.svcCtx.UserModel.SetEmailUnsubscribed(user.ID, true); err != nil {
+			return "", err
+		}
+		return "已退订全部通知邮件。重新验证邮箱即可恢复。", nil
+	}
+
+	info, ok := notify.LookupKind(notify.Kind(req.Kind))
+	if !ok {
+		return "", fmt.Errorf("未知的通知类型: %s", req.Kind)
+	}
+
+	// Start from the channels in effect so the other channels are kept
+	pref, err := l.svcCtx.NotificationModel.FindPreference(user.ID, req.Kind)
+	if err != nil {
+		return "", err
+	}
+	var channels []string
+	switch {
+	case pref != nil:
+		channels = pref.Channels
+	case info.Defaults != nil:
+		channels = info.Defaults
+	default:
+		channels = l.svcCtx.Notifier.ChannelNames()
+	}
+	channels = slices.DeleteFunc(slices.Clone(channels), func(ch string) bool {
+		return ch == notify.ChannelEmail
+	})
+
+	err = l.svcCtx.NotificationModel.SavePreference(&model.NotificationPreference{
+		UserID:   user.ID,
+		Kind:     req.Kind,
+		Channels: channels,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已退订「%s」邮件，其他通知不受影响。", info.Name), nil
+}
+
+func generateDigitCode(length int) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + n.Int64())
+	}
+	return string(b), nil
+}
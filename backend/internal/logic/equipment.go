@@ -0,0 +1,366 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+var equipSlotNames = map[string]string{
+	model.EquipSlotWeapon:   "武器",
+	model.EquipSlotRobe:     "法袍",
+	model.EquipSlotArtifact: "法宝",
+	model.EquipSlotTalisman: "护符",
+}
+
+type EquipmentLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewEquipmentLogic(svcCtx *svc.ServiceContext) *EquipmentLogic {
+	return &EquipmentLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *EquipmentLogic) EquipItem(ctx context.Context, userID int64, req *types.EquipItemReq) (*types.EquipResult, error) {
+	var result *types.EquipResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		invItem, err := uow.Shop.GetInventoryItemByItemID(userID, req.ItemID)
+		if err != nil {
+			return err
+		}
+		if invItem == nil || invItem.Quantity <= 0 {
+			return fmt.Errorf("背包中没有该物品")
+		}
+
+		item, err := uow.Shop.GetItemByID(req.ItemID)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return fmt.Errorf("物品不存在")
+		}
+		if item.ItemType != "equipment" || item.Slot == "" {
+			return fmt.Errorf("该物品不能装备")
+		}
+
+		if err := uow.Equipment.Equip(userID, item.Slot, item.ID); err != nil {
+			return err
+		}
+
+		result, err = l.equipResult(uow, userID, fmt.Sprintf("已将「%s」装备到%s", item.Name, equipSlotNames[item.Slot]))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (l *EquipmentLogic) UnequipItem(ctx context.Context, userID int64, req *types.UnequipItemReq) (*types.EquipResult, error) {
+	slotName, ok := equipSlotNames[req.Slot]
+	if !ok {
+		return nil, fmt.Errorf("未知的装备部位: %s", req.Slot)
+	}
+
+	var result *types.EquipResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		if err := uow.Equipment.Unequip(userID, req.Slot); err != nil {
+			if err == model.ErrConflict {
+				return fmt.Errorf("%s没有装备", slotName)
+			}
+			return err
+		}
+
+		var err error
+		result, err = l.equipResult(uow, userID, fmt.Sprintf("已卸下%s", slotName))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (l *EquipmentLogic) equipResult(uow *model.UnitOfWork, userID int64, message string) (*types.EquipResult, error) {
+	stats, err := uow.Character.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+
+	attrs, err := uow.Character.FindAttributesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	charResp, err := NewCharacterLogic(l.svcCtx).characterResp(uow, stats, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.EquipResult{
+		Success:   true,
+		Message:   message,
+		Character: *charResp,
+	}, nil
+}
+
+// equippedItems returns the shop items currently worn, skipping entries whose
+// item was deleted or no longer fits the slot it was equipped in.
+func equippedItems(uow *model.UnitOfWork, userID int64) ([]*model.EquippedItem, map[int64]*model.ShopItem, error) {
+	equipped, err := uow.Equipment.FindByUserID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var valid []*model.EquippedItem
+	items := make(map[int64]*model.ShopItem)
+	for _, e := range equipped {
+		item, err := uow.Shop.GetItemByID(e.ItemID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if item == nil || item.ItemType != "equipment" || item.Slot != e.Slot {
+			continue
+		}
+		valid = append(valid, e)
+		items[item.ID] = item
+	}
+
+	return valid, items, nil
+}
+
+// equipmentBuffs turns equipped passives into permanent buffs so they take
+// part in task reward computation alongside timed buffs.
+func equipmentBuffs(uow *model.UnitOfWork, userID int64) ([]*model.CharacterBuff, error) {
+	equipped, items, err := equippedItems(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffs []*model.CharacterBuff
+	for _, e := range equipped {
+		item := items[e.ItemID]
+		for _, p := range item.Passives {
+			buffs = append(buffs, &model.CharacterBuff{
+				UserID:     userID,
+				Name:       item.Name,
+				Modifier:   p.Modifier,
+				Target:     p.Target,
+				Value:      p.Value,
+				Stacks:     1,
+				SourceType: model.LedgerRefShopItem,
+				SourceID:   item.ID,
+			})
+		}
+	}
+
+	return buffs, nil
+}
+
+func equipmentResp(uow *model.UnitOfWork, userID int64) ([]types.EquippedItemResp, error) {
+	equipped, items, err := equippedItems(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bySlot := make(map[string]*model.ShopItem)
+	for _, e := range equipped {
+		bySlot[e.Slot] = items[e.ItemID]
+	}
+
+	resp := make([]types.EquippedItemResp, 0, len(equipped))
+	for _, slot := range model.EquipSlots {
+		item, ok := bySlot[slot]
+		if !ok {
+			continue
+		}
+		itemResp := types.EquippedItemResp{
+			Slot:     slot,
+			SlotName: equipSlotNames[slot],
+			ItemID:   item.ID,
+			Name:     item.Name,
+			Icon:     item.Icon,
+			Image:    item.Image,
+			Passives: passivesToResp(item.Passives),
+		}
+		if item.Durability > 0 {
+			invItem, err := uow.Shop.GetInventoryItemByItemID(userID, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			itemResp.Durability = item.Durability
+			itemResp.DurabilityLeft = durabilityLeft(item, invItem)
+		}
+		resp = append(resp, itemResp)
+	}
+
+	return resp, nil
+}
+
+// wearEquipment uses up one point of durability on every breakable item worn
+// while completing a task. A unit that runs out breaks and leaves the bag;
+// the item is unequipped once no spare unit is left.
+func wearEquipment(uow *model.UnitOfWork, userID, taskID int64) ([]string, error) {
+	equipped, items, err := equippedItems(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, e := range equipped {
+		item := items[e.ItemID]
+		if item.Durability <= 0 {
+			continue
+		}
+
+		invItem, err := uow.Shop.GetInventoryItemByItemID(userID, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if invItem == nil || invItem.Quantity <= 0 {
+			continue
+		}
+
+		wear := invItem.Wear + 1
+		if wear < item.Durability {
+			if err := uow.Shop.SetWear(userID, item.ID, wear); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := uow.Shop.RemoveFromInventory(userID, item.ID, 1); err != nil {
+			return nil, err
+		}
+		if err := uow.Shop.SetWear(userID, item.ID, 0); err != nil {
+			return nil, err
+		}
+		_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+			UserID:   userID,
+			Kind:     model.InventoryBreak,
+			ItemID:   item.ID,
+			ItemName: item.Name,
+			Quantity: -1,
+			RefType:  model.LedgerRefTask,
+			RefID:    taskID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if invItem.Quantity > 1 {
+			messages = append(messages, fmt.Sprintf("💔 「%s」耐久耗尽已损坏，已换上备用的一件", item.Name))
+			continue
+		}
+		if err := uow.Equipment.Unequip(userID, e.Slot); err != nil {
+			return nil, err
+		}
+		messages = append(messages, fmt.Sprintf("💔 「%s」耐久耗尽已损坏，%s已空出", item.Name, equipSlotNames[e.Slot]))
+	}
+
+	return messages, nil
+}
+
+// unequipIfGone empties the slot holding itemID once the bag has none left
+func unequipIfGone(uow *model.UnitOfWork, userID, itemID int64) error {
+	invItem, err := uow.Shop.GetInventoryItemByItemID(userID, itemID)
+	if err != nil {
+		return err
+	}
+	if invItem != nil && invItem.Quantity > 0 {
+		return nil
+	}
+
+	equipped, err := uow.Equipment.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, e := range equipped {
+		if e.ItemID == itemID {
+			return uow.Equipment.Unequip(userID, e.Slot)
+		}
+	}
+	return nil
+}
+
+// durabilityLeft returns the completed tasks left on the unit in use
+func durabilityLeft(item *model.ShopItem, invItem *model.InventoryItem) int {
+	if item.Durability <= 0 || invItem == nil {
+		return 0
+	}
+	return max(item.Durability-invItem.Wear, 0)
+}
+
+// validateEquipment checks the slot and passive modifiers of a shop item.
+func validateEquipment(item *model.ShopItem) error {
+	if item.ItemType != "equipment" {
+		if item.Slot != "" || len(item.Passives) > 0 {
+			return fmt.Errorf("只有装备可以设置部位和被动效果")
+		}
+		if item.Durability != 0 {
+			return fmt.Errorf("只有装备可以设置耐久度")
+		}
+		return nil
+	}
+	if item.Durability < 0 {
+		return fmt.Errorf("耐久度不能为负数")
+	}
+
+	if item.Slot != "" {
+		if _, ok := equipSlotNames[item.Slot]; !ok {
+			return fmt.Errorf("未知的装备部位: %s", item.Slot)
+		}
+	}
+	if item.Slot == "" && len(item.Passives) > 0 {
+		return fmt.Errorf("设置被动效果需要指定装备部位")
+	}
+
+	for _, p := range item.Passives {
+		switch p.Modifier {
+		case model.BuffModifierSpiritStones, model.BuffModifierFatigueCost:
+			if p.Target != "" {
+				return fmt.Errorf("被动效果 %s 不需要目标", p.Modifier)
+			}
+		case model.BuffModifierAttrGain:
+			if info, ok := realm.AttrDisplay[p.Target]; !ok || !info.HasRealm {
+				return fmt.Errorf("被动效果 %s 的目标必须是修炼属性", p.Modifier)
+			}
+		default:
+			return fmt.Errorf("未知的被动效果: %s", p.Modifier)
+		}
+		if p.Value == 0 || p.Value < -100 {
+			return fmt.Errorf("被动效果的数值必须是不为0且不小于-100的百分比")
+		}
+	}
+
+	return nil
+}
+
+func passivesToResp(passives []model.PassiveModifier) []types.PassiveModifierResp {
+	resp := make([]types.PassiveModifierResp, 0, len(passives))
+	for _, p := range passives {
+		resp = append(resp, types.PassiveModifierResp{
+			Modifier: p.Modifier,
+			Target:   p.Target,
+			Value:    p.Value,
+			Description: describeBuff(&model.CharacterBuff{
+				Modifier: p.Modifier,
+				Target:   p.Target,
+				Value:    p.Value,
+				Stacks:   1,
+			}),
+		})
+	}
+	return resp
+}
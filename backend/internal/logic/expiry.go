@@ -0,0 +1,154 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/pkg/notify"
+)
+
+const defaultExpiryNoticeHours = 24
+
+// ExpiryNotice lists the items of one user that expired or are about to.
+type ExpiryNotice struct {
+	UserID int64
+	Items  []string // One line per lot, e.g. "「周末休息券」×1"
+}
+
+// ExpireItems removes every lot that expired by now from the bags, unequipping
+// items once no unit is left. Returns what was removed, grouped by user.
+func (l *ShopLogic) ExpireItems(ctx context.Context, now time.Time) ([]*ExpiryNotice, error) {
+	lots, err := l.svcCtx.ShopModel.FindLotsExpiringBefore(now, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var notices []*ExpiryNotice
+	for _, lot := range lots {
+		var line string
+		err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+			var err error
+			line, err = l.expireLot(uow, lot)
+			return err
+		})
+		if err != nil {
+			return notices, fmt.Errorf("expire lot #%d: %w", lot.ID, err)
+		}
+		if line != "" {
+			notices = appendExpiryNotice(notices, lot.UserID, line)
+		}
+	}
+
+	return notices, nil
+}
+
+func (l *ShopLogic) expireLot(uow *model.UnitOfWork, lot *model.InventoryLot) (string, error) {
+	invItem, err := uow.Shop.GetInventoryItemByItemID(lot.UserID, lot.ItemID)
+	if err != nil {
+		return "", err
+	}
+	quantity := 0
+	if invItem != nil {
+		quantity = min(lot.Quantity, invItem.Quantity)
+	}
+	if quantity <= 0 {
+		return "", uow.Shop.DeleteLot(lot.ID)
+	}
+
+	// Removal consumes the soonest-expiring lots, starting with this one
+	if err := uow.Shop.RemoveFromInventory(lot.UserID, lot.ItemID, quantity); err != nil {
+		return "", err
+	}
+	if quantity < lot.Quantity {
+		if err := uow.Shop.DeleteLot(lot.ID); err != nil {
+			return "", err
+		}
+	}
+	if err := unequipIfGone(uow, lot.UserID, lot.ItemID); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("#%d", lot.ItemID)
+	item, err := uow.Shop.GetItemByID(lot.ItemID)
+	if err != nil {
+		return "", err
+	}
+	if item != nil {
+		name = item.Name
+	}
+
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:   lot.UserID,
+		Kind:     model.InventoryExpire,
+		ItemID:   lot.ItemID,
+		ItemName: name,
+		Quantity: -quantity,
+		RefType:  model.InventoryRefLot,
+		RefID:    lot.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("「%s」×%d", name, quantity), nil
+}
+
+// ExpiringItems returns the lots that expire within the notice window and
+// have not been announced yet, grouped by user, and marks them announced.
+func (l *ShopLogic) ExpiringItems(ctx context.Context, now time.Time) ([]*ExpiryNotice, error) {
+	hours := l.svcCtx.Config.Shop.ExpiryNoticeHours
+	if hours <= 0 {
+		hours = defaultExpiryNoticeHours
+	}
+
+	lots, err := l.svcCtx.ShopModel.FindLotsExpiringBefore(now.Add(time.Duration(hours)*time.Hour), true)
+	if err != nil {
+		return nil, err
+	}
+
+	var notices []*ExpiryNotice
+	locations := make(map[int64]*time.Location)
+	for _, lot := range lots {
+		item, err := l.svcCtx.ShopModel.GetItemByID(lot.ItemID)
+		if err != nil {
+			return notices, err
+		}
+		// Expiry times are shown in the user's timezone, like digests
+		loc, ok := locations[lot.UserID]
+		if !ok {
+			user, err := l.svcCtx.UserModel.FindByID(lot.UserID)
+			if err != nil {
+				return notices, err
+			}
+			loc = time.Local
+			if user != nil {
+				loc = notify.UserLocation(user)
+			}
+			locations[lot.UserID] = loc
+		}
+		if err := l.svcCtx.ShopModel.MarkLotNotified(lot.ID); err != nil {
+			return notices, err
+		}
+		if item == nil {
+			continue
+		}
+
+		line := fmt.Sprintf("「%s」×%d 将于 %s 过期", item.Name, lot.Quantity,
+			lot.ExpiresAt.In(loc).Format("01-02 15:04"))
+		notices = appendExpiryNotice(notices, lot.UserID, line)
+	}
+
+	return notices, nil
+}
+
+// appendExpiryNotice adds line to the notice of userID. Lots arrive sorted by
+// user, so only the last notice needs checking.
+func appendExpiryNotice(notices []*ExpiryNotice, userID int64, line string) []*ExpiryNotice {
+	if n := len(notices); n > 0 && notices[n-1].UserID == userID {
+		notices[n-1].Items = append(notices[n-1].Items, line)
+		return notices
+	}
+	return append(notices, &ExpiryNotice{UserID: userID, Items: []string{line}})
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"life-system-backend/internal/types"
+)
+
+func TestExpiringItemsUseTheUserTimezone(t *testing.T) {
+	svcCtx, db := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 100)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`UPDATE users SET timezone = 'Asia/Tokyo' WHERE id = ?`, userID); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{
+		Name:         "周末休息券",
+		Price:        10,
+		ItemType:     "consumable",
+		Stock:        -1,
+		ExpiresAfter: 1,
+	})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	if _, err := NewShopLogic(svcCtx).PurchaseItem(ctx, userID, &types.PurchaseItemReq{ItemID: item.ID, Quantity: 1}); err != nil {
+		t.Fatalf("purchase: %v", err)
+	}
+
+	var expiresAt time.Time
+	if err := db.QueryRow(`SELECT expires_at FROM inventory_lots WHERE user_id = ?`, userID).Scan(&expiresAt); err != nil {
+		t.Fatalf("read lot: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+
+	notices, err := NewShopLogic(svcCtx).ExpiringItems(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ExpiringItems() error = %v", err)
+	}
+	if len(notices) != 1 || len(notices[0].Items) != 1 {
+		t.Fatalf("ExpiringItems() = %v, want one item", notices)
+	}
+	want := expiresAt.In(tokyo).Format("01-02 15:04")
+	if line := notices[0].Items[0]; !strings.Contains(line, want) {
+		t.Errorf("notice %q does not show the Tokyo time %s", line, want)
+	}
+}
@@ -0,0 +1,158 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+const (
+	defaultLedgerPageSize = 20
+	maxLedgerPageSize     = 100
+)
+
+// adjustSpiritStones applies a signed change to stats.SpiritStones and records
+// it in the ledger. Every balance mutation must go through here; the caller
+// still persists stats within the same unit of work.
+func adjustSpiritStones(uow *model.UnitOfWork, stats *model.CharacterStats, amount int, reason, refType string, refID int64) error {
+	if amount == 0 {
+		return nil
+	}
+
+	stats.SpiritStones += amount
+
+	return uow.Ledger.Append(&model.LedgerEntry{
+		UserID:       stats.UserID,
+		Amount:       amount,
+		BalanceAfter: stats.SpiritStones,
+		Reason:       reason,
+		RefType:      refType,
+		RefID:        refID,
+	})
+}
+
+type LedgerLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewLedgerLogic(svcCtx *svc.ServiceContext) *LedgerLogic {
+	return &LedgerLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *LedgerLogic) ListLedger(ctx context.Context, userID int64, req *types.LedgerListReq) (*types.LedgerListResp, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultLedgerPageSize
+	}
+	if pageSize > maxLedgerPageSize {
+		pageSize = maxLedgerPageSize
+	}
+
+	for _, d := range []string{req.From, req.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("日期格式应为 YYYY-MM-DD")
+		}
+	}
+
+	entries, total, err := l.svcCtx.LedgerModel.FindByUserID(userID, model.LedgerFilter{
+		Reason:  req.Reason,
+		RefType: req.RefType,
+		From:    req.From,
+		To:      req.To,
+		Limit:   pageSize,
+		Offset:  (page - 1) * pageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.LedgerListResp{
+		Entries:  make([]types.LedgerEntryResp, 0, len(entries)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, types.LedgerEntryResp{
+			ID:           e.ID,
+			Amount:       e.Amount,
+			BalanceAfter: e.BalanceAfter,
+			Reason:       e.Reason,
+			RefType:      e.RefType,
+			RefID:        e.RefID,
+			CreatedAt:    e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return resp, nil
+}
+
+// LedgerMismatch describes a character whose balance cannot be explained by its ledger.
+type LedgerMismatch struct {
+	UserID        int64
+	Balance       int // character_stats.spirit_stones
+	LedgerSum     int // SUM(amount)
+	BrokenEntryID int64
+	Detail        string
+}
+
+// VerifyLedger recomputes every character's balance from the ledger and
+// reports characters whose stored balance or running totals disagree.
+func (l *LedgerLogic) VerifyLedger(ctx context.Context) ([]LedgerMismatch, error) {
+	characters, err := l.svcCtx.CharacterModel.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []LedgerMismatch
+	for _, stats := range characters {
+		entries, err := l.svcCtx.LedgerModel.FindAllByUserID(stats.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		running := 0
+		var brokenID int64
+		for _, e := range entries {
+			running += e.Amount
+			if brokenID == 0 && e.BalanceAfter != running {
+				brokenID = e.ID
+			}
+		}
+
+		switch {
+		case running != stats.SpiritStones:
+			mismatches = append(mismatches, LedgerMismatch{
+				UserID:        stats.UserID,
+				Balance:       stats.SpiritStones,
+				LedgerSum:     running,
+				BrokenEntryID: brokenID,
+				Detail:        fmt.Sprintf("balance %d != ledger sum %d", stats.SpiritStones, running),
+			})
+		case brokenID != 0:
+			mismatches = append(mismatches, LedgerMismatch{
+				UserID:        stats.UserID,
+				Balance:       stats.SpiritStones,
+				LedgerSum:     running,
+				BrokenEntryID: brokenID,
+				Detail:        fmt.Sprintf("balance_after chain breaks at entry #%d", brokenID),
+			})
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+type LootLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewLootLogic(svcCtx *svc.ServiceContext) *LootLogic {
+	return &LootLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *LootLogic) GetDropEntries(ctx context.Context, userID int64) (*types.DropEntryListResp, error) {
+	entries, err := l.svcCtx.LootModel.FindEntriesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]types.DropEntryResp, 0, len(entries))
+	for _, e := range entries {
+		entryResp, err := l.entryToResp(e)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, entryResp)
+	}
+
+	return &types.DropEntryListResp{Entries: resp}, nil
+}
+
+func (l *LootLogic) CreateDropEntry(ctx context.Context, userID int64, req *types.CreateDropEntryReq) (*types.DropEntryResp, error) {
+	entry := &model.DropEntry{
+		UserID:      userID,
+		Difficulty:  req.Difficulty,
+		Category:    strings.TrimSpace(req.Category),
+		ItemID:      req.ItemID,
+		Chance:      req.Chance,
+		MinQuantity: req.MinQuantity,
+		MaxQuantity: req.MaxQuantity,
+	}
+	if entry.MaxQuantity == 0 {
+		entry.MaxQuantity = entry.MinQuantity
+	}
+	if err := l.validateEntry(entry); err != nil {
+		return nil, err
+	}
+
+	id, err := l.svcCtx.LootModel.CreateEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = id
+
+	resp, err := l.entryToResp(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (l *LootLogic) UpdateDropEntry(ctx context.Context, userID int64, entryID int64, req *types.UpdateDropEntryReq) (*types.DropEntryResp, error) {
+	entry, err := l.svcCtx.LootModel.FindEntryByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.UserID != userID {
+		return nil, fmt.Errorf("掉落条目不存在")
+	}
+
+	if req.Difficulty != nil {
+		entry.Difficulty = *req.Difficulty
+	}
+	if req.Category != nil {
+		entry.Category = strings.TrimSpace(*req.Category)
+	}
+	if req.ItemID != nil {
+		entry.ItemID = *req.ItemID
+	}
+	if req.Chance != nil {
+		entry.Chance = *req.Chance
+	}
+	if req.MinQuantity != nil {
+		entry.MinQuantity = *req.MinQuantity
+	}
+	if req.MaxQuantity != nil {
+		entry.MaxQuantity = *req.MaxQuantity
+	}
+	if err := l.validateEntry(entry); err != nil {
+		return nil, err
+	}
+
+	if err := l.svcCtx.LootModel.UpdateEntry(entry); err != nil {
+		return nil, err
+	}
+
+	resp, err := l.entryToResp(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (l *LootLogic) DeleteDropEntry(ctx context.Context, userID int64, entryID int64) error {
+	entry, err := l.svcCtx.LootModel.FindEntryByID(entryID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.UserID != userID {
+		return fmt.Errorf("掉落条目不存在")
+	}
+
+	return l.svcCtx.LootModel.DeleteEntry(entryID, userID)
+}
+
+func (l *LootLogic) validateEntry(entry *model.DropEntry) error {
+	if entry.Difficulty < -1 || entry.Difficulty > 5 {
+		return fmt.Errorf("难度必须是 0-5，或 -1 表示任意难度")
+	}
+	if entry.Chance <= 0 || entry.Chance > 100 {
+		return fmt.Errorf("掉落概率必须在 0-100 之间")
+	}
+	if entry.MinQuantity <= 0 || entry.MaxQuantity < entry.MinQuantity {
+		return fmt.Errorf("掉落数量必须大于0，且最大数量不小于最小数量")
+	}
+	if strings.Contains(entry.Category, ",") {
+		return fmt.Errorf("每个掉落条目只能指定一个分类")
+	}
+
+	item, err := l.svcCtx.ShopModel.GetItemByID(entry.ItemID)
+	if err != nil {
+		return err
+	}
+	if item == nil || item.UserID != entry.UserID {
+		return fmt.Errorf("商品不存在")
+	}
+	if item.ItemType == "redeemable" {
+		return fmt.Errorf("实物奖励只能通过兑换获得")
+	}
+	return nil
+}
+
+func (l *LootLogic) entryToResp(entry *model.DropEntry) (types.DropEntryResp, error) {
+	resp := types.DropEntryResp{
+		ID:          entry.ID,
+		Difficulty:  entry.Difficulty,
+		Category:    entry.Category,
+		ItemID:      entry.ItemID,
+		Chance:      entry.Chance,
+		MinQuantity: entry.MinQuantity,
+		MaxQuantity: entry.MaxQuantity,
+	}
+
+	item, err := l.svcCtx.ShopModel.GetItemByID(entry.ItemID)
+	if err != nil {
+		return resp, err
+	}
+	if item != nil {
+		resp.ItemName = item.Name
+		resp.ItemIcon = item.Icon
+	}
+	return resp, nil
+}
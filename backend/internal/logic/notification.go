@@ -0,0 +1,298 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+)
+
+var notifyChannelLabels = map[string]string{
+	notify.ChannelTelegram: "Telegram",
+	notify.ChannelBark:     "Bark",
+	notify.ChannelEmail:    "邮件",
+	notify.ChannelNtfy:     "ntfy",
+	notify.ChannelGotify:   "Gotify",
+}
+
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+var notifyLevelLabels = map[notify.Level]string{
+	notify.LevelPassive:       "静默",
+	notify.LevelActive:        "普通",
+	notify.LevelTimeSensitive: "时效性",
+	notify.LevelCritical:      "紧急",
+}
+
+type NotificationLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotificationLogic(svcCtx *svc.ServiceContext) *NotificationLogic {
+	return &NotificationLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotificationLogic) GetPreferences(ctx context.Context, userID int64) (*types.NotificationPreferencesResp, error) {
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	prefs, err := l.svcCtx.NotificationModel.FindPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	custom := make(map[string]*model.NotificationPreference)
+	for _, p := range prefs {
+		custom[p.Kind] = p
+	}
+
+	names := l.svcCtx.Notifier.ChannelNames()
+	resp := &types.NotificationPreferencesResp{
+		Channels: make([]types.NotificationChannelResp, 0, len(names)),
+		Levels:   make([]types.NotificationLevelResp, 0, len(notify.Levels)),
+		Kinds:    make([]types.NotificationKindResp, 0, len(notify.Kinds)),
+	}
+	for _, name := range names {
+		resp.Channels = append(resp.Channels, types.NotificationChannelResp{
+			Name:       name,
+			Label:      notifyChannelLabels[name],
+			Configured: l.svcCtx.Notifier.Channel(name).Enabled(user),
+		})
+	}
+	for _, level := range notify.Levels {
+		resp.Levels = append(resp.Levels, types.NotificationLevelResp{
+			Level: string(level),
+			Label: notifyLevelLabels[level],
+		})
+	}
+
+	for _, info := range notify.Kinds {
+		defaults := names
+		if info.Defaults != nil {
+			defaults = slices.DeleteFunc(slices.Clone(info.Defaults), func(ch string) bool {
+				return !slices.Contains(names, ch)
+			})
+		}
+		kindResp := types.NotificationKindResp{
+			Kind:         string(info.Kind),
+			Name:         info.Name,
+			Channels:     defaults,
+			Defaults:     defaults,
+			Level:        string(info.Level),
+			DefaultLevel: string(info.Level),
+		}
+		if pref, ok := custom[string(info.Kind)]; ok {
+			if pref.Channels != nil {
+				kindResp.Channels = pref.Channels
+				kindResp.Custom = true
+			}
+			if pref.Level != "" {
+				kindResp.Level = pref.Level
+				kindResp.LevelCustom = true
+			}
+		}
+		resp.Kinds = append(resp.Kinds, kindResp)
+	}
+
+	return resp, nil
+}
+
+func (l *NotificationLogic) UpdatePreference(ctx context.Context, userID int64, req *types.UpdateNotificationPreferenceReq) (*types.NotificationPreferencesResp, error) {
+	if _, ok := notify.LookupKind(notify.Kind(req.Kind)); !ok {
+		return nil, fmt.Errorf("未知的通知类型: %s", req.Kind)
+	}
+
+	if req.Reset {
+		if err := l.svcCtx.NotificationModel.DeletePreference(userID, req.Kind); err != nil {
+			return nil, err
+		}
+		return l.GetPreferences(ctx, userID)
+	}
+
+	pref, err := l.svcCtx.NotificationModel.FindPreference(userID, req.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		pref = &model.NotificationPreference{UserID: userID, Kind: req.Kind}
+	}
+
+	if req.Channels != nil {
+		names := l.svcCtx.Notifier.ChannelNames()
+		channels := make([]string, 0, len(req.Channels))
+		for _, ch := range req.Channels {
+			if !slices.Contains(names, ch) {
+				return nil, fmt.Errorf("未知的通知渠道: %s", ch)
+			}
+			if !slices.Contains(channels, ch) {
+				channels = append(channels, ch)
+			}
+		}
+		pref.Channels = channels
+	}
+
+	if req.Level != nil {
+		if *req.Level != "" && !slices.Contains(notify.Levels, notify.Level(*req.Level)) {
+			return nil, fmt.Errorf("未知的紧急程度: %s", *req.Level)
+		}
+		pref.Level = *req.Level
+	}
+
+	if pref.Channels == nil && pref.Level == "" {
+		err = l.svcCtx.NotificationModel.DeletePreference(userID, req.Kind)
+	} else {
+		err = l.svcCtx.NotificationModel.SavePreference(pref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l.GetPreferences(ctx, userID)
+}
+
+// ListNotifications returns the user's notifications from the outbox, newest first
+func (l *NotificationLogic) ListNotifications(ctx context.Context, userID int64, req *types.NotificationListReq) (*types.NotificationListResp, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultNotificationPageSize
+	}
+	if pageSize > maxNotificationPageSize {
+		pageSize = maxNotificationPageSize
+	}
+
+	switch req.Status {
+	case "", model.OutboxPending, model.OutboxSent, model.OutboxFailed, model.OutboxSkipped:
+	default:
+		return nil, fmt.Errorf("未知的通知状态: %s", req.Status)
+	}
+
+	items, total, err := l.svcCtx.NotificationModel.FindOutbox(userID, req.Status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.NotificationListResp{
+		Items:    make([]types.NotificationItemResp, 0, len(items)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		var n notify.Notification
+		if err := json.Unmarshal([]byte(item.Payload), &n); err != nil {
+			return nil, err
+		}
+		itemResp := types.NotificationItemResp{
+			ID:        item.ID,
+			Kind:      item.Kind,
+			Title:     n.Title,
+			Body:      n.Body,
+			Level:     string(n.Level),
+			Status:    item.Status,
+			Deferred:  item.Status == model.OutboxPending && item.Attempts == 0 && item.NextAttemptAt.Time.After(now),
+			Delivered: item.Delivered,
+			Pending:   item.Channels,
+			Attempts:  item.Attempts,
+			Error:     item.Error,
+			CreatedAt: item.CreatedAt.Format(time.RFC3339),
+		}
+		if info, ok := notify.LookupKind(notify.Kind(item.Kind)); ok {
+			itemResp.KindName = info.Name
+		}
+		if itemResp.Pending == nil {
+			itemResp.Pending = []string{}
+		}
+		if item.Status == model.OutboxPending && item.NextAttemptAt.Valid {
+			itemResp.NextAttemptAt = item.NextAttemptAt.Time.Format(time.RFC3339)
+		}
+		if item.SentAt.Valid {
+			itemResp.SentAt = item.SentAt.Time.Format(time.RFC3339)
+		}
+		resp.Items = append(resp.Items, itemResp)
+	}
+
+	return resp, nil
+}
+
+func (l *NotificationLogic) GetQuietHours(ctx context.Context, userID int64) (*types.QuietHoursResp, error) {
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	deferred, err := l.svcCtx.NotificationModel.CountDeferred(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	quiet := notify.UserQuietHours(user)
+	return &types.QuietHoursResp{
+		Enabled:  quiet != nil,
+		Start:    user.QuietStart,
+		End:      user.QuietEnd,
+		Timezone: user.Timezone,
+		Active:   quiet != nil && quiet.Active(time.Now()),
+		Deferred: deferred,
+	}, nil
+}
+
+// SetQuietHours turns quiet hours on or off. The timezone is kept either way,
+// as it is the user's own. Notifications deferred under the old settings are
+// released and deferred again if the new quiet hours still cover them.
+func (l *NotificationLogic) SetQuietHours(ctx context.Context, userID int64, req *types.SetQuietHoursReq) (*types.QuietHoursResp, error) {
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("未知的时区: %s", req.Timezone)
+		}
+	}
+
+	start, end := "", ""
+	if req.Enabled {
+		startMin, err := notify.ParseClock(req.Start)
+		if err != nil {
+			return nil, fmt.Errorf("开始时间格式错误，应为 HH:MM")
+		}
+		endMin, err := notify.ParseClock(req.End)
+		if err != nil {
+			return nil, fmt.Errorf("结束时间格式错误，应为 HH:MM")
+		}
+		if startMin == endMin {
+			return nil, fmt.Errorf("开始时间和结束时间不能相同")
+		}
+		start = fmt.Sprintf("%02d:%02d", startMin/60, startMin%60)
+		end = fmt.Sprintf("%02d:%02d", endMin/60, endMin%60)
+	}
+
+	if err := l.svcCtx.UserModel.UpdateQuietHours(userID, start, end, req.Timezone); err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.NotificationModel.ReleaseDeferred(userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return l.GetQuietHours(ctx, userID)
+}
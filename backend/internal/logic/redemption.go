@@ -0,0 +1,481 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+)
+
+type RedemptionLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewRedemptionLogic(svcCtx *svc.ServiceContext) *RedemptionLogic {
+	return &RedemptionLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+// createRedemption queues a purchased real-world reward. When the buyer has a
+// partner who accepted the link, the partner must approve it first.
+func createRedemption(uow *model.UnitOfWork, userID int64, item *model.ShopItem, quantity, totalPrice int) (*model.Redemption, error) {
+	partnerID, err := uow.User.GetPartnerID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	redemption := &model.Redemption{
+		UserID:     userID,
+		ItemID:     item.ID,
+		ItemName:   item.Name,
+		Quantity:   quantity,
+		TotalPrice: totalPrice,
+		Status:     model.RedemptionPending,
+		ApproverID: partnerID,
+	}
+	if partnerID > 0 {
+		redemption.Status = model.RedemptionAwaitingApproval
+	}
+
+	id, err := uow.Redemption.Create(redemption)
+	if err != nil {
+		return nil, err
+	}
+	redemption.ID = id
+
+	return redemption, nil
+}
+
+func (l *RedemptionLogic) ListRedemptions(ctx context.Context, userID int64, req *types.RedemptionListReq) (*types.RedemptionListResp, error) {
+	filter := model.RedemptionFilter{Status: req.Status}
+	switch req.Role {
+	case "", "mine":
+		filter.UserID = userID
+	case "partner":
+		filter.ApproverID = userID
+	default:
+		return nil, fmt.Errorf("未知的角色: %s", req.Role)
+	}
+
+	redemptions, err := l.svcCtx.RedemptionModel.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string)
+	resp := make([]types.RedemptionResp, 0, len(redemptions))
+	for _, r := range redemptions {
+		if _, ok := names[r.UserID]; !ok {
+			user, err := l.svcCtx.UserModel.FindByID(r.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if user != nil {
+				names[r.UserID] = user.DisplayName
+			}
+		}
+		resp = append(resp, redemptionToResp(r, names[r.UserID]))
+	}
+
+	return &types.RedemptionListResp{Redemptions: resp}, nil
+}
+
+// Approve lets the partner accept a redemption so it can be fulfilled.
+func (l *RedemptionLogic) Approve(ctx context.Context, userID int64, redemptionID int64, req *types.RedemptionActionReq) (*types.RedemptionResp, error) {
+	return l.act(userID, redemptionID, req.Note, func(uow *model.UnitOfWork, r *model.Redemption) (string, error) {
+		if r.ApproverID != userID {
+			return "", fmt.Errorf("无权审批此兑换")
+		}
+		if r.Status != model.RedemptionAwaitingApproval {
+			return "", fmt.Errorf("该兑换不在待审批状态")
+		}
+		return model.RedemptionPending, nil
+	})
+}
+
+// Reject lets the partner decline a redemption that has not been fulfilled.
+// The buyer is refunded.
+func (l *RedemptionLogic) Reject(ctx context.Context, userID int64, redemptionID int64, req *types.RedemptionActionReq) (*types.RedemptionResp, error) {
+	return l.act(userID, redemptionID, req.Note, func(uow *model.UnitOfWork, r *model.Redemption) (string, error) {
+		if r.ApproverID != userID {
+			return "", fmt.Errorf("无权审批此兑换")
+		}
+		if r.Status != model.RedemptionAwaitingApproval && r.Status != model.RedemptionPending {
+			return "", fmt.Errorf("该兑换已结束")
+		}
+		return model.RedemptionRejected, refundRedemption(uow, r)
+	})
+}
+
+// Fulfil marks the reward as handed over. With a partner only the partner
+// may fulfil; otherwise the buyer does it themselves.
+func (l *RedemptionLogic) Fulfil(ctx context.Context, userID int64, redemptionID int64, req *types.RedemptionActionReq) (*types.RedemptionResp, error) {
+	return l.act(userID, redemptionID, req.Note, func(uow *model.UnitOfWork, r *model.Redemption) (string, error) {
+		if (r.ApproverID > 0 && r.ApproverID != userID) || (r.ApproverID == 0 && r.UserID != userID) {
+			return "", fmt.Errorf("无权兑现此兑换")
+		}
+		if r.Status == model.RedemptionAwaitingApproval {
+			return "", fmt.Errorf("该兑换尚未审批")
+		}
+		if r.Status != model.RedemptionPending {
+			return "", fmt.Errorf("该兑换已结束")
+		}
+		return model.RedemptionFulfilled, nil
+	})
+}
+
+// Cancel lets the buyer withdraw a redemption that has not been fulfilled.
+// The spirit stones are refunded.
+func (l *RedemptionLogic) Cancel(ctx context.Context, userID int64, redemptionID int64, req *types.RedemptionActionReq) (*types.RedemptionResp, error) {
+	return l.act(userID, redemptionID, req.Note, func(uow *model.UnitOfWork, r *model.Redemption) (string, error) {
+		if r.UserID != userID {
+			return "", fmt.Errorf("兑换不存在")
+		}
+		if r.Status != model.RedemptionAwaitingApproval && r.Status != model.RedemptionPending {
+			return "", fmt.Errorf("该兑换已结束")
+		}
+		return model.RedemptionCancelled, refundRedemption(uow, r)
+	})
+}
+
+// act loads a redemption visible to userID, lets decide check it and pick the
+// next status, and applies the transition in one transaction.
+func (l *RedemptionLogic) act(userID, redemptionID int64, note string, decide func(*model.UnitOfWork, *model.Redemption) (string, error)) (*types.RedemptionResp, error) {
+	var resp types.RedemptionResp
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		r, err := uow.Redemption.FindByID(redemptionID)
+		if err != nil {
+			return err
+		}
+		if r == nil || (r.UserID != userID && r.ApproverID != userID) {
+			return fmt.Errorf("兑换不存在")
+		}
+
+		next, err := decide(uow, r)
+		if err != nil {
+			return err
+		}
+		if err := uow.Redemption.Transition(r.ID, r.Status, next, note); err != nil {
+			if err == model.ErrConflict {
+				return fmt.Errorf("兑换状态已变化，请刷新后重试")
+			}
+			return err
+		}
+
+		r, err = uow.Redemption.FindByID(redemptionID)
+		if err != nil {
+			return err
+		}
+		buyer, err := uow.User.FindByID(r.UserID)
+		if err != nil {
+			return err
+		}
+		buyerName := ""
+		if buyer != nil {
+			buyerName = buyer.DisplayName
+		}
+		resp = redemptionToResp(r, buyerName)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func refundRedemption(uow *model.UnitOfWork, r *model.Redemption) error {
+	stats, err := uow.Character.FindByUserID(r.UserID)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		return fmt.Errorf("角色不存在")
+	}
+
+	if err := adjustSpiritStones(uow, stats, r.TotalPrice, model.LedgerReasonRefund, model.LedgerRefRedemption, r.ID); err != nil {
+		return err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return err
+	}
+
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:       r.UserID,
+		Kind:         model.InventoryRefund,
+		ItemID:       r.ItemID,
+		ItemName:     r.ItemName,
+		Quantity:     -r.Quantity,
+		SpiritStones: r.TotalPrice,
+		RefType:      model.LedgerRefRedemption,
+		RefID:        r.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return uow.Shop.RestoreItemStock(r.ItemID, r.Quantity)
+}
+
+// Partner link states
+const (
+	partnerPending  = "pending"
+	partnerAccepted = "accepted"
+)
+
+func partnerStatus(accepted bool) string {
+	if accepted {
+		return partnerAccepted
+	}
+	return partnerPending
+}
+
+func (l *RedemptionLogic) GetPartner(ctx context.Context, userID int64) (*types.PartnerResp, error) {
+	partnerID, accepted, err := l.svcCtx.UserModel.GetPartnerLink(userID)
+	if err != nil {
+		return nil, err
+	}
+	if partnerID == 0 {
+		return &types.PartnerResp{}, nil
+	}
+
+	partner, err := l.svcCtx.UserModel.FindByID(partnerID)
+	if err != nil {
+		return nil, err
+	}
+	if partner == nil {
+		return &types.PartnerResp{}, nil
+	}
+
+	return &types.PartnerResp{
+		PartnerID:   partner.ID,
+		Username:    partner.Username,
+		DisplayName: partner.DisplayName,
+		Status:      partnerStatus(accepted),
+	}, nil
+}
+
+// SetPartner asks another account to approve and fulfil future redemptions.
+// The link stays pending, and redemptions are handled alone, until that
+// account accepts. Redemptions already queued keep their approver.
+func (l *RedemptionLogic) SetPartner(ctx context.Context, userID int64, req *types.SetPartnerReq) (*types.PartnerResp, error) {
+	partner, err := l.svcCtx.UserModel.FindByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if partner == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if partner.ID == userID {
+		return nil, fmt.Errorf("不能把自己设为道侣")
+	}
+
+	currentID, accepted, err := l.svcCtx.UserModel.GetPartnerLink(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Asking again must not undo an acceptance or repeat the notification
+	if currentID != partner.ID {
+		if err := l.svcCtx.UserModel.UpdatePartnerID(userID, partner.ID); err != nil {
+			return nil, err
+		}
+		accepted = false
+		go l.notifyPartnerRequest(userID, partner.ID)
+	}
+
+	return &types.PartnerResp{
+		PartnerID:   partner.ID,
+		Username:    partner.Username,
+		DisplayName: partner.DisplayName,
+		Status:      partnerStatus(accepted),
+	}, nil
+}
+
+func (l *RedemptionLogic) RemovePartner(ctx context.Context, userID int64) error {
+	return l.svcCtx.UserModel.UpdatePartnerID(userID, 0)
+}
+
+// ListPartnerRequests returns the users who named userID as their partner,
+// pending requests first
+func (l *RedemptionLogic) ListPartnerRequests(ctx context.Context, userID int64) (*types.PartnerRequestListResp, error) {
+	resp := &types.PartnerRequestListResp{Requests: []types.PartnerRequestResp{}}
+	for _, accepted := range []bool{false, true} {
+		users, err := l.svcCtx.UserModel.FindByPartnerID(userID, accepted)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			resp.Requests = append(resp.Requests, types.PartnerRequestResp{
+				UserID:      u.ID,
+				Username:    u.Username,
+				DisplayName: u.DisplayName,
+				Status:      partnerStatus(accepted),
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+// AcceptPartner agrees to approve and fulfil the redemptions requesterID
+// makes from now on.
+func (l *RedemptionLogic) AcceptPartner(ctx context.Context, userID, requesterID int64) error {
+	if err := l.svcCtx.UserModel.AcceptPartner(requesterID, userID); err != nil {
+		if err == model.ErrConflict {
+			return fmt.Errorf("没有待确认的道侣邀请")
+		}
+		return err
+	}
+	return nil
+}
+
+// DeclinePartner refuses a pending request, or ends an accepted link.
+// Redemptions already queued keep their approver.
+func (l *RedemptionLogic) DeclinePartner(ctx context.Context, userID, requesterID int64) error {
+	if err := l.svcCtx.UserModel.DeclinePartner(requesterID, userID); err != nil {
+		if err == model.ErrConflict {
+			return fmt.Errorf("对方未邀请你为道侣")
+		}
+		return err
+	}
+	return nil
+}
+
+// GetReport compares spirit stones earned from tasks with those spent on
+// fulfilled real-world rewards, per month.
+func (l *RedemptionLogic) GetReport(ctx context.Context, userID int64, req *types.RedemptionReportReq) (*types.RedemptionReportResp, error) {
+	for _, d := range []string{req.From, req.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("日期格式错误，应为 YYYY-MM-DD")
+		}
+	}
+
+	earned, err := l.svcCtx.LedgerModel.SumByMonth(userID, model.LedgerReasonTaskReward, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	penalties, err := l.svcCtx.LedgerModel.SumByMonth(userID, model.LedgerReasonTaskPenalty, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	spent, err := l.svcCtx.RedemptionModel.SumByMonth(userID, model.RedemptionFulfilled, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.RedemptionReportResp{Months: []types.RedemptionMonthResp{}}
+	for _, status := range []string{model.RedemptionAwaitingApproval, model.RedemptionPending} {
+		pending, err := l.svcCtx.RedemptionModel.SumByMonth(userID, status, req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range pending {
+			resp.Pending += v
+		}
+	}
+
+	months := make(map[string]*types.RedemptionMonthResp)
+	month := func(m string) *types.RedemptionMonthResp {
+		if _, ok := months[m]; !ok {
+			months[m] = &types.RedemptionMonthResp{Month: m}
+		}
+		return months[m]
+	}
+	for m, v := range earned {
+		month(m).Earned = v
+		resp.Earned += v
+	}
+	// Penalties are negative ledger amounts
+	for m, v := range penalties {
+		month(m).Penalties = -v
+		resp.Penalties -= v
+	}
+	for m, v := range spent {
+		month(m).Spent = v
+		resp.Spent += v
+	}
+
+	for _, m := range months {
+		resp.Months = append(resp.Months, *m)
+	}
+	sort.Slice(resp.Months, func(i, j int) bool {
+		return resp.Months[i].Month < resp.Months[j].Month
+	})
+
+	return resp, nil
+}
+
+// notifyApprover tells the partner that a redemption waits for approval. It runs after the purchase committed and is best effort.
+func (l *RedemptionLogic) notifyApprover(redemptionID int64) {
+	r, err := l.svcCtx.RedemptionModel.FindByID(redemptionID)
+	if err != nil || r == nil || r.Status != model.RedemptionAwaitingApproval {
+		return
+	}
+	buyer, err := l.svcCtx.UserModel.FindByID(r.UserID)
+	if err != nil || buyer == nil {
+		return
+	}
+
+	text := fmt.Sprintf("%s 兑换了 %d 个「%s」（%d 灵石），等待你的审批", buyer.DisplayName, r.Quantity, r.ItemName, r.TotalPrice)
+	err = l.svcCtx.Notifier.Notify(r.ApproverID, &notify.Notification{
+		Kind:    notify.KindRedemptionPending,
+		Title:   "🎁 兑换待审批",
+		Body:    text,
+		Message: "🎁 " + text,
+		Level:   notify.LevelTimeSensitive,
+	})
+	if err != nil {
+		log.Printf("Error sending redemption notification: %v", err)
+	}
+}
+
+// notifyPartnerRequest asks partnerID to accept being userID's partner. It is best effort.
+func (l *RedemptionLogic) notifyPartnerRequest(userID, partnerID int64) {
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil || user == nil {
+		return
+	}
+
+	text := fmt.Sprintf("%s（%s）想请你做道侣，审批并兑现 TA 的实物兑换。接受前不会收到 TA 的兑换。", user.DisplayName, user.Username)
+	err = l.svcCtx.Notifier.Notify(partnerID, &notify.Notification{
+		Kind:    notify.KindPartnerRequest,
+		Title:   "💞 道侣邀请",
+		Body:    text,
+		Message: "💞 " + text,
+		Level:   notify.LevelActive,
+	})
+	if err != nil {
+		log.Printf("Error sending partner request notification: %v", err)
+	}
+}
+
+func redemptionToResp(r *model.Redemption, buyerName string) types.RedemptionResp {
+	resolvedAt := ""
+	if r.ResolvedAt.Valid {
+		resolvedAt = r.ResolvedAt.Time.Format(time.RFC3339)
+	}
+	return types.RedemptionResp{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		BuyerName:  buyerName,
+		ItemID:     r.ItemID,
+		ItemName:   r.ItemName,
+		Quantity:   r.Quantity,
+		TotalPrice: r.TotalPrice,
+		Status:     r.Status,
+		ApproverID: r.ApproverID,
+		Note:       r.Note,
+		CreatedAt:  r.CreatedAt.Format(time.RFC3339),
+		ResolvedAt: resolvedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+// buyReward buys one unit of a fresh real-world reward and returns the redemption
+func buyReward(t *testing.T, svcCtx *svc.ServiceContext, userID int64) *types.RedemptionResp {
+	t.Helper()
+	ctx := context.Background()
+
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{
+		Name:     "电影之夜",
+		Price:    100,
+		ItemType: "redeemable",
+		Stock:    -1,
+	})
+	if err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	result, err := NewShopLogic(svcCtx).PurchaseItem(ctx, userID, &types.PurchaseItemReq{ItemID: item.ID, Quantity: 1})
+	if err != nil {
+		t.Fatalf("buy reward: %v", err)
+	}
+
+	list, err := NewRedemptionLogic(svcCtx).ListRedemptions(ctx, userID, &types.RedemptionListReq{})
+	if err != nil {
+		t.Fatalf("list redemptions: %v", err)
+	}
+	for i := range list.Redemptions {
+		if list.Redemptions[i].ID == result.RedemptionID {
+			return &list.Redemptions[i]
+		}
+	}
+	t.Fatalf("redemption %d not listed", result.RedemptionID)
+	return nil
+}
+
+func TestPartnerMustAcceptBeforeApproving(t *testing.T) {
+	svcCtx, _ := newTestService(t)
+	ctx := context.Background()
+	l := NewRedemptionLogic(svcCtx)
+
+	buyer := newTestUser(t, svcCtx, "buyer", 1000)
+	partner := newTestUser(t, svcCtx, "partner", 0)
+
+	resp, err := l.SetPartner(ctx, buyer, &types.SetPartnerReq{Username: "partner"})
+	if err != nil {
+		t.Fatalf("set partner: %v", err)
+	}
+	if resp.Status != partnerPending {
+		t.Errorf("status = %q, want %q", resp.Status, partnerPending)
+	}
+
+	// While pending the buyer handles redemptions alone
+	pending := buyReward(t, svcCtx, buyer)
+	if pending.ApproverID != 0 || pending.Status != model.RedemptionPending {
+		t.Errorf("redemption while pending: approver %d status %s, want none and %s", pending.ApproverID, pending.Status, model.RedemptionPending)
+	}
+	if _, err := l.Reject(ctx, partner, pending.ID, &types.RedemptionActionReq{}); err == nil {
+		t.Error("partner rejected a redemption before accepting")
+	}
+
+	requests, err := l.ListPartnerRequests(ctx, partner)
+	if err != nil {
+		t.Fatalf("list requests: %v", err)
+	}
+	if len(requests.Requests) != 1 || requests.Requests[0].UserID != buyer || requests.Requests[0].Status != partnerPending {
+		t.Fatalf("requests = %+v, want one pending from the buyer", requests.Requests)
+	}
+
+	if err := l.AcceptPartner(ctx, buyer, partner); err == nil {
+		t.Error("the buyer accepted their own request")
+	}
+	if err := l.AcceptPartner(ctx, partner, buyer); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if err := l.AcceptPartner(ctx, partner, buyer); err == nil {
+		t.Error("accepted the same request twice")
+	}
+
+	got, err := l.GetPartner(ctx, buyer)
+	if err != nil {
+		t.Fatalf("get partner: %v", err)
+	}
+	if got.PartnerID != partner || got.Status != partnerAccepted {
+		t.Errorf("partner = %+v, want %d accepted", got, partner)
+	}
+
+	// Naming the same partner again keeps the acceptance
+	if resp, err := l.SetPartner(ctx, buyer, &types.SetPartnerReq{Username: "partner"}); err != nil || resp.Status != partnerAccepted {
+		t.Errorf("set same partner: %+v, %v", resp, err)
+	}
+
+	awaiting := buyReward(t, svcCtx, buyer)
+	if awaiting.ApproverID != partner || awaiting.Status != model.RedemptionAwaitingApproval {
+		t.Errorf("redemption after accepting: approver %d status %s, want %d and %s", awaiting.ApproverID, awaiting.Status, partner, model.RedemptionAwaitingApproval)
+	}
+	if _, err := l.Approve(ctx, partner, awaiting.ID, &types.RedemptionActionReq{}); err != nil {
+		t.Errorf("approve: %v", err)
+	}
+}
+
+func TestPartnerCanDecline(t *testing.T) {
+	svcCtx, _ := newTestService(t)
+	ctx := context.Background()
+	l := NewRedemptionLogic(svcCtx)
+
+	buyer := newTestUser(t, svcCtx, "buyer", 1000)
+	partner := newTestUser(t, svcCtx, "partner", 0)
+	stranger := newTestUser(t, svcCtx, "stranger", 0)
+
+	if _, err := l.SetPartner(ctx, buyer, &types.SetPartnerReq{Username: "partner"}); err != nil {
+		t.Fatalf("set partner: %v", err)
+	}
+	if err := l.DeclinePartner(ctx, stranger, buyer); err == nil {
+		t.Error("a stranger declined someone else's request")
+	}
+	if err := l.DeclinePartner(ctx, partner, buyer); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+
+	got, err := l.GetPartner(ctx, buyer)
+	if err != nil {
+		t.Fatalf("get partner: %v", err)
+	}
+	if got.PartnerID != 0 {
+		t.Errorf("partner = %+v, want none after declining", got)
+	}
+	if err := l.AcceptPartner(ctx, partner, buyer); err == nil {
+		t.Error("accepted a declined request")
+	}
+}
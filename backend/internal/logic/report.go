@@ -0,0 +1,345 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/realm"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+)
+
+// reportWeekDays is how many days the weekly review covers, today included
+const reportWeekDays = 7
+
+type ReportLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewReportLogic(svcCtx *svc.ServiceContext) *ReportLogic {
+	return &ReportLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ReportLogic) GetDailyReport(ctx context.Context, userID int64) (*types.DailyReportResp, error) {
+	user, err := l.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return l.DailyReport(user, time.Now())
+}
+
+func (l *ReportLogic) GetWeeklyReport(ctx context.Context, userID int64) (*types.WeeklyReportResp, error) {
+	user, err := l.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return l.WeeklyReport(user, time.Now())
+}
+
+func (l *ReportLogic) findUser(userID int64) (*model.User, error) {
+	user, err := l.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	return user, nil
+}
+
+// DailyReport describes the day ahead of user: deadlines until the end of the
+// user's local day, repeatable tasks still open and what is at risk.
+func (l *ReportLogic) DailyReport(user *model.User, now time.Time) (*types.DailyReportResp, error) {
+	stats, err := l.svcCtx.CharacterModel.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+	tasks, err := l.svcCtx.TaskModel.FindByUserID(user.ID, "", "active")
+	if err != nil {
+		return nil, err
+	}
+
+	loc := notify.UserLocation(user)
+	local := now.In(loc)
+	endOfDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	// Repeatable tasks and activity count days on the server's clock, like the daily reset
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+
+	resp := &types.DailyReportResp{
+		Date:          local.Format("2006-01-02"),
+		Deadlines:     []types.ReportTaskResp{},
+		Repeatable:    []types.ReportTaskResp{},
+		StreaksAtRisk: []types.ReportTaskResp{},
+		Fatigue:       stats.Fatigue,
+		FatigueCap:    stats.FatigueCap,
+		DecayRisk:     stats.LastActivityDate != today,
+		StreakShields: stats.StreakShields,
+		SpiritStones:  stats.SpiritStones,
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Deadline.Valid && (!tasks[j].Deadline.Valid || tasks[i].Deadline.Time.Before(tasks[j].Deadline.Time))
+	})
+	for _, task := range tasks {
+		item := types.ReportTaskResp{ID: task.ID, Title: task.Title, Type: task.Type}
+		if task.Deadline.Valid {
+			item.Deadline = task.Deadline.Time.In(loc).Format(time.RFC3339)
+			item.Overdue = task.Deadline.Time.Before(now)
+			if task.Deadline.Time.Before(endOfDay) {
+				resp.Deadlines = append(resp.Deadlines, item)
+			}
+		}
+
+		if task.Type != "repeatable" {
+			continue
+		}
+		doneToday := 0
+		if task.LastCompletedDate == today {
+			doneToday = task.TodayCompletionCount
+		}
+		if doneToday == 0 || (task.DailyLimit > 0 && doneToday < task.DailyLimit) {
+			resp.Repeatable = append(resp.Repeatable, item)
+		}
+		if doneToday == 0 && task.LastCompletedDate == yesterday {
+			resp.StreaksAtRisk = append(resp.StreaksAtRisk, item)
+		}
+	}
+
+	return resp, nil
+}
+
+// WeeklyReport reviews the last seven local days of user, today included.
+// Attribute changes are measured against the snapshot taken at the start of
+// the period, or the oldest one when history is shorter.
+func (l *ReportLogic) WeeklyReport(user *model.User, now time.Time) (*types.WeeklyReportResp, error) {
+	stats, err := l.svcCtx.CharacterModel.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+
+	local := now.In(notify.UserLocation(user))
+	from := time.Date(local.Year(), local.Month(), local.Day()-(reportWeekDays-1), 0, 0, 0, 0, local.Location())
+
+	completed, err := l.svcCtx.TaskModel.CountLogsSince(user.ID, "complete", from)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := l.svcCtx.TaskModel.CountLogsSince(user.ID, "fail", from)
+	if err != nil {
+		return nil, err
+	}
+	earned, spent, err := l.svcCtx.LedgerModel.SumFlowsSince(user.ID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := l.svcCtx.CharacterModel.FindAttributesByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := l.svcCtx.CharacterModel.FindSnapshotAt(user.ID, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.WeeklyReportResp{
+		From:           from.Format("2006-01-02"),
+		To:             local.Format("2006-01-02"),
+		CompletedTasks: completed,
+		FailedTasks:    failed,
+		Attributes:     make([]types.ReportAttrResp, 0, len(attrs)),
+		StonesEarned:   earned,
+		StonesSpent:    spent,
+		SpiritStones:   stats.SpiritStones,
+	}
+
+	before := make(map[string]*model.AttributeSnapshot, len(snapshot))
+	for _, s := range snapshot {
+		before[s.AttrKey] = s
+		resp.HistorySince = s.Date
+	}
+	current := make(map[string]*model.CharacterAttribute, len(attrs))
+	for _, attr := range attrs {
+		current[attr.AttrKey] = attr
+	}
+
+	for _, key := range realm.AllAttrKeys {
+		attr, ok := current[key]
+		if !ok {
+			continue
+		}
+		info := realm.AttrDisplay[key]
+		item := types.ReportAttrResp{
+			Key:   key,
+			Name:  info.Name,
+			Value: math.Round(attr.Value*10) / 10,
+		}
+		if info.HasRealm {
+			item.Realm = realm.GetFullRealmName(attr.Realm, attr.SubRealm)
+			item.RealmBefore = item.Realm
+			item.Bottleneck = attr.IsBottleneck
+			item.RealmExp = attr.RealmExp
+			item.RealmExpRequired = realm.BreakthroughExpRequired(attr.Realm)
+		}
+		if s, ok := before[key]; ok {
+			item.Gain = math.Round((attr.Value-s.Value)*10) / 10
+			if info.HasRealm {
+				item.RealmBefore = realm.GetFullRealmName(s.Realm, s.SubRealm)
+				item.Breakthrough = attr.Realm > s.Realm
+			}
+		}
+		resp.Attributes = append(resp.Attributes, item)
+	}
+
+	return resp, nil
+}
+
+// DailyDigest renders the morning briefing of user as a notification
+func (l *ReportLogic) DailyDigest(user *model.User, now time.Time) (*notify.Notification, error) {
+	report, err := l.DailyReport(user, now)
+	if err != nil {
+		return nil, err
+	}
+	loc := notify.UserLocation(user)
+
+	var lines []string
+	for _, task := range report.Deadlines {
+		if task.Overdue {
+			lines = append(lines, fmt.Sprintf("⚠️ 「%s」已逾期", task.Title))
+			continue
+		}
+		deadline, _ := time.Parse(time.RFC3339, task.Deadline)
+		lines = append(lines, fmt.Sprintf("⏰ 「%s」%s 截止", task.Title, deadline.In(loc).Format("15:04")))
+	}
+	if len(report.Repeatable) > 0 {
+		lines = append(lines, "🔁 今日待完成："+reportTitles(report.Repeatable))
+	}
+	if len(report.StreaksAtRisk) > 0 {
+		lines = append(lines, "🔥 昨天完成了，今天别断："+reportTitles(report.StreaksAtRisk))
+	}
+	if len(report.Deadlines) == 0 && len(report.Repeatable) == 0 {
+		lines = append(lines, "🌤 今天没有待办任务")
+	}
+	lines = append(lines, fmt.Sprintf("😮‍💨 疲劳 %d/%d", report.Fatigue, report.FatigueCap))
+	if report.DecayRisk {
+		line := "🍂 今天还没有完成任务，连续一整天不活动属性就会衰减"
+		if report.StreakShields > 0 {
+			line += fmt.Sprintf("（护体 ×%d）", report.StreakShields)
+		}
+		lines = append(lines, line)
+	}
+
+	return &notify.Notification{
+		Kind:  notify.KindDailyDigest,
+		Title: "🌅 今日简报 · " + now.In(loc).Format("1月2日"),
+		Body:  strings.Join(lines, "\n"),
+		Level: notify.LevelPassive,
+	}, nil
+}
+
+// WeeklyDigest renders the weekly review of user as a notification
+func (l *ReportLogic) WeeklyDigest(user *model.User, now time.Time) (*notify.Notification, error) {
+	report, err := l.WeeklyReport(user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := fmt.Sprintf("✅ 完成任务 %d 个", report.CompletedTasks)
+	if report.FailedTasks > 0 {
+		tasks += fmt.Sprintf("，失败 %d 个", report.FailedTasks)
+	}
+	lines := []string{tasks}
+	for _, attr := range report.Attributes {
+		if attr.Gain == 0 && !attr.Breakthrough {
+			continue
+		}
+		line := fmt.Sprintf("%s %s %+.1f", realm.AttrDisplay[attr.Key].Emoji, attr.Name, attr.Gain)
+		if attr.Breakthrough {
+			line += "，突破至" + attr.Realm
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, fmt.Sprintf("💎 灵石 收入 %d · 支出 %d · 余额 %d",
+		report.StonesEarned, report.StonesSpent, report.SpiritStones))
+
+	from, _ := time.Parse("2006-01-02", report.From)
+	to, _ := time.Parse("2006-01-02", report.To)
+	return &notify.Notification{
+		Kind:  notify.KindWeeklyDigest,
+		Title: fmt.Sprintf("📊 每周回顾 · %s - %s", from.Format("1月2日"), to.Format("1月2日")),
+		Body:  strings.Join(lines, "\n"),
+		Level: notify.LevelPassive,
+	}, nil
+}
+
+func reportTitles(tasks []types.ReportTaskResp) string {
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, "「"+task.Title+"」")
+	}
+	return strings.Join(titles, "、")
+}
+
+func (l *ReportLogic) GetSettings(ctx context.Context, userID int64) (*types.ReportSettingsResp, error) {
+	user, err := l.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ReportSettingsResp{
+		Daily:    user.DigestDaily,
+		Weekly:   user.DigestWeekly,
+		Time:     user.DigestTime,
+		Weekday:  user.DigestWeekday,
+		Timezone: user.Timezone,
+	}, nil
+}
+
+func (l *ReportLogic) UpdateSettings(ctx context.Context, userID int64, req *types.UpdateReportSettingsReq) (*types.ReportSettingsResp, error) {
+	user, err := l.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, weekly, clock, weekday := user.DigestDaily, user.DigestWeekly, user.DigestTime, user.DigestWeekday
+	if req.Daily != nil {
+		daily = *req.Daily
+	}
+	if req.Weekly != nil {
+		weekly = *req.Weekly
+	}
+	if req.Time != nil {
+		minute, err := notify.ParseClock(*req.Time)
+		if err != nil {
+			return nil, fmt.Errorf("时间格式错误，应为 HH:MM")
+		}
+		clock = fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+	}
+	if req.Weekday != nil {
+		if *req.Weekday < 0 || *req.Weekday > 6 {
+			return nil, fmt.Errorf("星期取值应为 0-6（0 为周日）")
+		}
+		weekday = *req.Weekday
+	}
+
+	if err := l.svcCtx.UserModel.UpdateDigestSettings(userID, daily, weekly, clock, weekday); err != nil {
+		return nil, err
+	}
+
+	return l.GetSettings(ctx, userID)
+}
@@ -0,0 +1,536 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+)
+
+// savingsEtaWindow is how far back task income is averaged for ETAs
+const savingsEtaWindow = 14 * 24 * time.Hour
+
+type SavingsLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewSavingsLogic(svcCtx *svc.ServiceContext) *SavingsLogic {
+	return &SavingsLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SavingsLogic) ListGoals(ctx context.Context, userID int64) (*types.SavingsGoalListResp, error) {
+	uow := l.svcCtx.Read()
+	goals, err := uow.Savings.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyIncome, err := savingsDailyIncome(uow, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.SavingsGoalListResp{
+		Goals:       make([]types.SavingsGoalResp, 0, len(goals)),
+		DailyIncome: dailyIncome,
+	}
+	for _, g := range goals {
+		goalResp, err := savingsGoalResp(uow, g, dailyIncome)
+		if err != nil {
+			return nil, err
+		}
+		resp.Goals = append(resp.Goals, *goalResp)
+		if g.Status == model.SavingsActive || g.Status == model.SavingsReached {
+			resp.TotalSaved += g.SavedAmount
+		}
+		if g.Status == model.SavingsActive {
+			resp.TotalPercent += g.Percent
+		}
+	}
+
+	return resp, nil
+}
+
+func (l *SavingsLogic) CreateGoal(ctx context.Context, userID int64, req *types.CreateSavingsGoalReq) (*types.SavingsGoalResp, error) {
+	var resp *types.SavingsGoalResp
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		goal := &model.SavingsGoal{
+			UserID:       userID,
+			Name:         strings.TrimSpace(req.Name),
+			ItemID:       req.ItemID,
+			TargetAmount: req.TargetAmount,
+			Percent:      req.Percent,
+			AutoPurchase: req.AutoPurchase,
+			Status:       model.SavingsActive,
+		}
+
+		if goal.ItemID > 0 {
+			item, err := uow.Shop.GetItemByID(goal.ItemID)
+			if err != nil {
+				return err
+			}
+			if item == nil || item.UserID != userID {
+				return fmt.Errorf("商品不存在")
+			}
+			if goal.Name == "" {
+				goal.Name = item.Name
+			}
+			goal.TargetAmount = 0
+		} else if goal.AutoPurchase {
+			return fmt.Errorf("自动购买需要关联商品")
+		}
+
+		if err := validateSavingsGoal(uow, goal); err != nil {
+			return err
+		}
+
+		id, err := uow.Savings.Create(goal)
+		if err != nil {
+			return err
+		}
+		goal.ID = id
+
+		dailyIncome, err := savingsDailyIncome(uow, userID, time.Now())
+		if err != nil {
+			return err
+		}
+		resp, err = savingsGoalResp(uow, goal, dailyIncome)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (l *SavingsLogic) UpdateGoal(ctx context.Context, userID int64, goalID int64, req *types.UpdateSavingsGoalReq) (*types.SavingsGoalResp, error) {
+	var resp *types.SavingsGoalResp
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		goal, err := findOpenGoal(uow, userID, goalID)
+		if err != nil {
+			return err
+		}
+
+		if req.Name != nil {
+			goal.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.TargetAmount != nil {
+			if goal.ItemID > 0 {
+				return fmt.Errorf("关联商品的目标金额为商品价格，不能修改")
+			}
+			goal.TargetAmount = *req.TargetAmount
+		}
+		if req.Percent != nil {
+			goal.Percent = *req.Percent
+		}
+		if req.AutoPurchase != nil {
+			if *req.AutoPurchase && goal.ItemID == 0 {
+				return fmt.Errorf("自动购买需要关联商品")
+			}
+			goal.AutoPurchase = *req.AutoPurchase
+		}
+		if err := validateSavingsGoal(uow, goal); err != nil {
+			return err
+		}
+
+		// A lowered target may already be met; a raised one reopens the goal
+		target, err := savingsTarget(uow, goal)
+		if err != nil {
+			return err
+		}
+		if goal.SavedAmount >= target {
+			goal.Status = model.SavingsReached
+		} else {
+			goal.Status = model.SavingsActive
+		}
+
+		if err := uow.Savings.Update(goal); err != nil {
+			return err
+		}
+
+		dailyIncome, err := savingsDailyIncome(uow, userID, time.Now())
+		if err != nil {
+			return err
+		}
+		resp, err = savingsGoalResp(uow, goal, dailyIncome)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Deposit moves spirit stones from the balance into a goal by hand.
+func (l *SavingsLogic) Deposit(ctx context.Context, userID int64, goalID int64, req *types.SavingsDepositReq) (*types.SavingsResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("存入数量必须大于0")
+	}
+
+	return l.act(userID, goalID, func(uow *model.UnitOfWork, goal *model.SavingsGoal, stats *model.CharacterStats) (string, error) {
+		if goal.Status != model.SavingsActive {
+			return "", fmt.Errorf("该储蓄目标已达成")
+		}
+		if stats.SpiritStones < req.Amount {
+			return "", fmt.Errorf("灵石不足")
+		}
+
+		target, err := savingsTarget(uow, goal)
+		if err != nil {
+			return "", err
+		}
+		amount := req.Amount
+		if amount > target-goal.SavedAmount {
+			amount = target - goal.SavedAmount
+		}
+		if amount <= 0 {
+			return "", fmt.Errorf("该储蓄目标已存满")
+		}
+
+		if err := depositIntoGoal(uow, stats, goal, amount, target); err != nil {
+			return "", err
+		}
+		message := fmt.Sprintf("向「%s」存入 %d 灵石", goal.Name, amount)
+		if goal.Status == model.SavingsReached {
+			message += "，目标已达成"
+		}
+		return message, nil
+	})
+}
+
+// Purchase buys the linked item with the saved stones. Any surplus returns
+// to the balance.
+func (l *SavingsLogic) Purchase(ctx context.Context, userID int64, goalID int64) (*types.SavingsResult, error) {
+	return l.act(userID, goalID, func(uow *model.UnitOfWork, goal *model.SavingsGoal, stats *model.CharacterStats) (string, error) {
+		return l.purchaseGoal(uow, stats, goal)
+	})
+}
+
+// Release closes a goal and returns its saved stones to the balance.
+func (l *SavingsLogic) Release(ctx context.Context, userID int64, goalID int64) (*types.SavingsResult, error) {
+	return l.act(userID, goalID, func(uow *model.UnitOfWork, goal *model.SavingsGoal, stats *model.CharacterStats) (string, error) {
+		released := goal.SavedAmount
+		if err := releaseGoal(uow, stats, goal, model.SavingsCancelled); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已取消「%s」，%d 灵石退回余额", goal.Name, released), nil
+	})
+}
+
+// act runs fn on an open goal and the owner's stats in one transaction and
+// persists both afterwards.
+func (l *SavingsLogic) act(userID, goalID int64, fn func(*model.UnitOfWork, *model.SavingsGoal, *model.CharacterStats) (string, error)) (*types.SavingsResult, error) {
+	var result *types.SavingsResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		goal, err := findOpenGoal(uow, userID, goalID)
+		if err != nil {
+			return err
+		}
+
+		stats, err := uow.Character.FindByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if stats == nil {
+			return fmt.Errorf("角色不存在")
+		}
+
+		message, err := fn(uow, goal, stats)
+		if err != nil {
+			return err
+		}
+
+		// fn may have purchased through purchaseItem, which saves its own copy
+		stats, err = uow.Character.FindByUserID(userID)
+		if err != nil {
+			return err
+		}
+
+		dailyIncome, err := savingsDailyIncome(uow, userID, time.Now())
+		if err != nil {
+			return err
+		}
+		goalResp, err := savingsGoalResp(uow, goal, dailyIncome)
+		if err != nil {
+			return err
+		}
+
+		result = &types.SavingsResult{
+			Success:               true,
+			Message:               message,
+			Goal:                  *goalResp,
+			RemainingSpiritStones: stats.SpiritStones,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// purchaseGoal releases the saved stones and buys the linked item through the
+// normal purchase path, so stock, ledger and purchase history stay consistent.
+func (l *SavingsLogic) purchaseGoal(uow *model.UnitOfWork, stats *model.CharacterStats, goal *model.SavingsGoal) (string, error) {
+	if goal.ItemID == 0 {
+		return "", fmt.Errorf("该储蓄目标没有关联商品")
+	}
+	item, err := uow.Shop.GetItemByID(goal.ItemID)
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		return "", fmt.Errorf("关联商品已不存在")
+	}
+	price := item.EffectivePrice(time.Now())
+	if goal.SavedAmount < price {
+		return "", fmt.Errorf("储蓄不足，还差 %d 灵石", price-goal.SavedAmount)
+	}
+	// A negative balance eats into the released stones
+	if stats.SpiritStones+goal.SavedAmount < price {
+		return "", fmt.Errorf("灵石不足")
+	}
+	if item.Stock != -1 && item.Stock < 1 {
+		return "", fmt.Errorf("「%s」库存不足", item.Name)
+	}
+
+	if err := releaseGoal(uow, stats, goal, model.SavingsPurchased); err != nil {
+		return "", err
+	}
+
+	result, err := NewShopLogic(l.svcCtx).purchaseItem(uow, goal.UserID, &types.PurchaseItemReq{ItemID: item.ID, Quantity: 1})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🎯 储蓄目标「%s」已完成：%s", goal.Name, result.Message), nil
+}
+
+// depositSavings puts the configured share of a task reward into every
+// active goal and auto-purchases goals that reach their target. stats must be
+// persisted by the caller; purchases reload and save it themselves, so
+// callers should reload stats afterwards.
+func (l *SavingsLogic) depositSavings(uow *model.UnitOfWork, stats *model.CharacterStats, reward int) ([]string, error) {
+	if reward <= 0 {
+		return nil, nil
+	}
+
+	goals, err := uow.Savings.FindActiveByUserID(stats.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	deposited := 0
+	var reached []*model.SavingsGoal
+	for _, goal := range goals {
+		target, err := savingsTarget(uow, goal)
+		if err != nil {
+			return nil, err
+		}
+
+		amount := reward * goal.Percent / 100
+		if amount > target-goal.SavedAmount {
+			amount = target - goal.SavedAmount
+		}
+		if amount > stats.SpiritStones {
+			amount = stats.SpiritStones
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		if err := depositIntoGoal(uow, stats, goal, amount, target); err != nil {
+			return nil, err
+		}
+		deposited += amount
+		if goal.Status == model.SavingsReached {
+			reached = append(reached, goal)
+		}
+	}
+	if deposited > 0 {
+		messages = append(messages, fmt.Sprintf("💰 存入储蓄 %d 灵石", deposited))
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
+	}
+
+	for _, goal := range reached {
+		if !goal.AutoPurchase {
+			messages = append(messages, fmt.Sprintf("🎯 储蓄目标「%s」已达成", goal.Name))
+			continue
+		}
+
+		fresh, err := uow.Character.FindByUserID(stats.UserID)
+		if err != nil {
+			return nil, err
+		}
+		var message string
+		err = uow.Savepoint(func() error {
+			message, err = l.purchaseGoal(uow, fresh, goal)
+			return err
+		})
+		if err != nil {
+			// Undo the release so the stones stay locked; the user can buy by
+			// hand once possible
+			messages = append(messages, fmt.Sprintf("🎯 储蓄目标「%s」已达成，自动购买失败：%s", goal.Name, err.Error()))
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func depositIntoGoal(uow *model.UnitOfWork, stats *model.CharacterStats, goal *model.SavingsGoal, amount, target int) error {
+	if err := adjustSpiritStones(uow, stats, -amount, model.LedgerReasonSavingsIn, model.LedgerRefSavings, goal.ID); err != nil {
+		return err
+	}
+	goal.SavedAmount += amount
+	if goal.SavedAmount >= target {
+		goal.Status = model.SavingsReached
+	}
+	return uow.Savings.Update(goal)
+}
+
+// releaseGoal returns the saved stones to the balance and closes the goal
+// with status. stats is persisted.
+func releaseGoal(uow *model.UnitOfWork, stats *model.CharacterStats, goal *model.SavingsGoal, status string) error {
+	if err := adjustSpiritStones(uow, stats, goal.SavedAmount, model.LedgerReasonSavingsOut, model.LedgerRefSavings, goal.ID); err != nil {
+		return err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return err
+	}
+
+	goal.SavedAmount = 0
+	goal.Status = status
+	return uow.Savings.Update(goal)
+}
+
+func findOpenGoal(uow *model.UnitOfWork, userID, goalID int64) (*model.SavingsGoal, error) {
+	goal, err := uow.Savings.FindByID(goalID)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil || goal.UserID != userID {
+		return nil, fmt.Errorf("储蓄目标不存在")
+	}
+	if goal.Status != model.SavingsActive && goal.Status != model.SavingsReached {
+		return nil, fmt.Errorf("该储蓄目标已结束")
+	}
+	return goal, nil
+}
+
+func validateSavingsGoal(uow *model.UnitOfWork, goal *model.SavingsGoal) error {
+	if goal.Name == "" {
+		return fmt.Errorf("储蓄目标名称不能为空")
+	}
+	if goal.ItemID == 0 && goal.TargetAmount <= 0 {
+		return fmt.Errorf("目标金额必须大于0")
+	}
+	if goal.Percent < 0 || goal.Percent > 100 {
+		return fmt.Errorf("存入比例必须在 0-100 之间")
+	}
+
+	// Shares of one reward cannot add up to more than the reward
+	goals, err := uow.Savings.FindActiveByUserID(goal.UserID)
+	if err != nil {
+		return err
+	}
+	total := goal.Percent
+	for _, g := range goals {
+		if g.ID != goal.ID {
+			total += g.Percent
+		}
+	}
+	if total > 100 {
+		return fmt.Errorf("所有储蓄目标的存入比例之和不能超过 100%%（当前 %d%%）", total)
+	}
+	return nil
+}
+
+// savingsTarget is the item's current price for linked goals, including an
+// active discount.
+func savingsTarget(uow *model.UnitOfWork, goal *model.SavingsGoal) (int, error) {
+	if goal.ItemID == 0 {
+		return goal.TargetAmount, nil
+	}
+	item, err := uow.Shop.GetItemByID(goal.ItemID)
+	if err != nil {
+		return 0, err
+	}
+	if item == nil {
+		return goal.TargetAmount, nil
+	}
+	return item.EffectivePrice(time.Now()), nil
+}
+
+// savingsDailyIncome averages the task rewards of the recent window.
+func savingsDailyIncome(uow *model.UnitOfWork, userID int64, now time.Time) (float64, error) {
+	total, err := uow.Task.SumRewardsSince(userID, now.Add(-savingsEtaWindow))
+	if err != nil {
+		return 0, err
+	}
+	return float64(total) / savingsEtaWindow.Hours() * 24, nil
+}
+
+func savingsGoalResp(uow *model.UnitOfWork, goal *model.SavingsGoal, dailyIncome float64) (*types.SavingsGoalResp, error) {
+	target, err := savingsTarget(uow, goal)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.SavingsGoalResp{
+		ID:           goal.ID,
+		Name:         goal.Name,
+		ItemID:       goal.ItemID,
+		TargetAmount: target,
+		SavedAmount:  goal.SavedAmount,
+		Percent:      goal.Percent,
+		AutoPurchase: goal.AutoPurchase,
+		Status:       goal.Status,
+		EtaDays:      -1,
+		CreatedAt:    goal.CreatedAt.Format(time.RFC3339),
+	}
+	if goal.CompletedAt.Valid {
+		resp.CompletedAt = goal.CompletedAt.Time.Format(time.RFC3339)
+	}
+
+	if goal.ItemID > 0 {
+		item, err := uow.Shop.GetItemByID(goal.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			resp.ItemName = item.Name
+			resp.ItemIcon = item.Icon
+		}
+	}
+
+	if target > 0 {
+		resp.Progress = math.Min(100, math.Round(float64(goal.SavedAmount)*1000/float64(target))/10)
+	}
+
+	switch goal.Status {
+	case model.SavingsActive:
+		resp.DailyDeposit = dailyIncome * float64(goal.Percent) / 100
+		if resp.DailyDeposit > 0 {
+			resp.EtaDays = int(math.Ceil(float64(target-goal.SavedAmount) / resp.DailyDeposit))
+			resp.EtaDate = time.Now().AddDate(0, 0, resp.EtaDays).Format("2006-01-02")
+		}
+	case model.SavingsReached:
+		resp.EtaDays = 0
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,74 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/types"
+)
+
+func TestFailedAutoPurchaseKeepsStonesLocked(t *testing.T) {
+	svcCtx, db := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 0)
+	ctx := context.Background()
+
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{
+		Name:     "回春丹",
+		Price:    50,
+		ItemType: "consumable",
+		Stock:    -1,
+	})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	goal, err := NewSavingsLogic(svcCtx).CreateGoal(ctx, userID, &types.CreateSavingsGoalReq{
+		ItemID:       item.ID,
+		Percent:      100,
+		AutoPurchase: true,
+	})
+	if err != nil {
+		t.Fatalf("create goal: %v", err)
+	}
+	task, err := NewTaskLogic(svcCtx).CreateTask(ctx, userID, &types.CreateTaskReq{
+		Title:              "晨跑",
+		Type:               "once",
+		Difficulty:         1,
+		RewardSpiritStones: 50,
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	// Fail the purchase after the pre-checks, as a conflict would
+	if _, err := db.Exec(`CREATE TRIGGER fail_purchase BEFORE INSERT ON inventory
+		BEGIN SELECT RAISE(ABORT, 'purchase failed'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	if _, err := NewTaskLogic(svcCtx).CompleteTask(ctx, userID, task.ID, "web"); err != nil {
+		t.Fatalf("complete task: %v", err)
+	}
+
+	goals, err := NewSavingsLogic(svcCtx).ListGoals(ctx, userID)
+	if err != nil {
+		t.Fatalf("list goals: %v", err)
+	}
+	if len(goals.Goals) != 1 {
+		t.Fatalf("got %d goals, want 1", len(goals.Goals))
+	}
+	got := goals.Goals[0]
+	if got.ID != goal.ID || got.Status != model.SavingsReached || got.SavedAmount != 50 {
+		t.Errorf("goal is %s with %d saved, want reached with 50 saved", got.Status, got.SavedAmount)
+	}
+
+	inv, err := svcCtx.ShopModel.GetInventoryItemByItemID(userID, item.ID)
+	if err != nil {
+		t.Fatalf("read inventory: %v", err)
+	}
+	if inv != nil {
+		t.Errorf("inventory holds %d items, want none", inv.Quantity)
+	}
+
+	assertLedgerBalanced(t, db, userID)
+}
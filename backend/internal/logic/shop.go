@@ -2,14 +2,21 @@ package logic
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
+	"life-system-backend/internal/effect"
 	"life-system-backend/internal/model"
 	"life-system-backend/internal/realm"
 	"life-system-backend/internal/svc"
 	"life-system-backend/internal/types"
+	"life-system-backend/pkg/webhook"
 )
 
+const defaultRefundWindowHours = 24
+
 type ShopLogic struct {
 	svcCtx *svc.ServiceContext
 }
@@ -26,22 +33,22 @@ func (l *ShopLogic) GetShopItems(ctx context.Context, userID int64) (*types.Shop
 		return nil, err
 	}
 
+	bundles, err := l.svcCtx.ShopModel.GetBundlesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &types.ShopItemListResp{
-		Items: make([]types.ShopItemResp, 0),
+		Items:   make([]types.ShopItemResp, 0),
+		Bundles: make([]types.ShopBundleResp, 0),
+		Effects: l.itemEffectsResp(),
 	}
 
 	for _, item := range items {
-		resp.Items = append(resp.Items, types.ShopItemResp{
-			ID:          item.ID,
-			Name:        item.Name,
-			Description: item.Description,
-			Price:       item.Price,
-			SellPrice:   item.SellPrice,
-			ItemType:    item.ItemType,
-			Icon:        item.Icon,
-			Image:       item.Image,
-			Stock:       item.Stock,
-		})
+		resp.Items = append(resp.Items, l.itemToResp(item))
+	}
+	for _, bundle := range bundles {
+		resp.Bundles = append(resp.Bundles, l.bundleToResp(bundle))
 	}
 
 	return resp, nil
@@ -60,6 +67,15 @@ func (l *ShopLogic) CreateShopItem(ctx context.Context, userID int64, req *types
 		itemType = "consumable"
 	}
 
+	discountStart, err := parseSaleTime(req.DiscountStart)
+	if err != nil {
+		return nil, err
+	}
+	discountEnd, err := parseSaleTime(req.DiscountEnd)
+	if err != nil {
+		return nil, err
+	}
+
 	item := &model.ShopItem{
 		UserID:      userID,
 		Name:        req.Name,
@@ -67,9 +83,43 @@ func (l *ShopLogic) CreateShopItem(ctx context.Context, userID int64, req *types
 		Price:       req.Price,
 		SellPrice:   req.SellPrice,
 		ItemType:    itemType,
+		Effect:      req.Effect,
+		EffectValue: req.EffectValue,
+		Slot:        req.Slot,
+		Passives:    toPassives(req.Passives),
 		Icon:        req.Icon,
 		Image:       req.Image,
 		Stock:       req.Stock,
+
+		RestockPeriod:   req.RestockPeriod,
+		RestockAmount:   req.RestockAmount,
+		RestockMax:      req.RestockMax,
+		DiscountPercent: req.DiscountPercent,
+		DiscountStart:   discountStart,
+		DiscountEnd:     discountEnd,
+		ExpiresAfter:    req.ExpiresAfter,
+		Durability:      req.Durability,
+	}
+	if item.Effect == "" {
+		item.Effect = "none"
+	}
+	setItemEffects(item, req.Effects)
+	// The first restock happens once the current period is over
+	if item.RestockPeriod != "" {
+		item.RestockedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	if err := validateItemType(item); err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.ItemEffects.Validate(item.EffectSpecs()); err != nil {
+		return nil, err
+	}
+	if err := validateEquipment(item); err != nil {
+		return nil, err
+	}
+	if err := validateShopSchedule(item); err != nil {
+		return nil, err
 	}
 
 	id, err := l.svcCtx.ShopModel.CreateItem(item)
@@ -77,17 +127,9 @@ func (l *ShopLogic) CreateShopItem(ctx context.Context, userID int64, req *types
 		return nil, err
 	}
 
-	return &types.ShopItemResp{
-		ID:          id,
-		Name:        item.Name,
-		Description: item.Description,
-		Price:       item.Price,
-		SellPrice:   item.SellPrice,
-		ItemType:    item.ItemType,
-		Icon:        item.Icon,
-		Image:       item.Image,
-		Stock:       item.Stock,
-	}, nil
+	item.ID = id
+	resp := l.itemToResp(item)
+	return &resp, nil
 }
 
 func (l *ShopLogic) UpdateShopItem(ctx context.Context, userID int64, itemID int64, req *types.UpdateShopItemReq) (*types.ShopItemResp, error) {
@@ -117,6 +159,26 @@ func (l *ShopLogic) UpdateShopItem(ctx context.Context, userID int64, itemID int
 	if req.ItemType != nil {
 		existing.ItemType = *req.ItemType
 	}
+	if req.Effect != nil {
+		existing.Effect = *req.Effect
+		if existing.Effect == "" {
+			existing.Effect = "none"
+		}
+		existing.Effects = nil
+	}
+	if req.EffectValue != nil {
+		existing.EffectValue = *req.EffectValue
+		existing.Effects = nil
+	}
+	if req.Effects != nil {
+		setItemEffects(existing, req.Effects)
+	}
+	if req.Slot != nil {
+		existing.Slot = *req.Slot
+	}
+	if req.Passives != nil {
+		existing.Passives = toPassives(req.Passives)
+	}
 	if req.Icon != nil {
 		existing.Icon = *req.Icon
 	}
@@ -126,22 +188,57 @@ func (l *ShopLogic) UpdateShopItem(ctx context.Context, userID int64, itemID int
 	if req.Stock != nil {
 		existing.Stock = *req.Stock
 	}
+	if req.RestockPeriod != nil && *req.RestockPeriod != existing.RestockPeriod {
+		existing.RestockPeriod = *req.RestockPeriod
+		existing.RestockedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	if req.RestockAmount != nil {
+		existing.RestockAmount = *req.RestockAmount
+	}
+	if req.RestockMax != nil {
+		existing.RestockMax = *req.RestockMax
+	}
+	if req.DiscountPercent != nil {
+		existing.DiscountPercent = *req.DiscountPercent
+	}
+	if req.DiscountStart != nil {
+		if existing.DiscountStart, err = parseSaleTime(*req.DiscountStart); err != nil {
+			return nil, err
+		}
+	}
+	if req.DiscountEnd != nil {
+		if existing.DiscountEnd, err = parseSaleTime(*req.DiscountEnd); err != nil {
+			return nil, err
+		}
+	}
+	if req.ExpiresAfter != nil {
+		existing.ExpiresAfter = *req.ExpiresAfter
+	}
+	if req.Durability != nil {
+		existing.Durability = *req.Durability
+	}
+
+	if err := validateItemType(existing); err != nil {
+		return nil, err
+	}
+	if req.Effect != nil || req.EffectValue != nil || req.Effects != nil {
+		if err := l.svcCtx.ItemEffects.Validate(existing.EffectSpecs()); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateEquipment(existing); err != nil {
+		return nil, err
+	}
+	if err := validateShopSchedule(existing); err != nil {
+		return nil, err
+	}
 
 	if err := l.svcCtx.ShopModel.UpdateItem(existing); err != nil {
 		return nil, err
 	}
 
-	return &types.ShopItemResp{
-		ID:          existing.ID,
-		Name:        existing.Name,
-		Description: existing.Description,
-		Price:       existing.Price,
-		SellPrice:   existing.SellPrice,
-		ItemType:    existing.ItemType,
-		Icon:        existing.Icon,
-		Image:       existing.Image,
-		Stock:       existing.Stock,
-	}, nil
+	resp := l.itemToResp(existing)
+	return &resp, nil
 }
 
 func (l *ShopLogic) DeleteShopItem(ctx context.Context, userID int64, itemID int64) error {
@@ -160,7 +257,49 @@ func (l *ShopLogic) DeleteShopItem(ctx context.Context, userID int64, itemID int
 }
 
 func (l *ShopLogic) PurchaseItem(ctx context.Context, userID int64, req *types.PurchaseItemReq) (*types.PurchaseResult, error) {
-	item, err := l.svcCtx.ShopModel.GetItemByID(req.ItemID)
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("数量必须大于0")
+	}
+	if (req.ItemID > 0) == (req.BundleID > 0) {
+		return nil, fmt.Errorf("请选择一个商品或礼包")
+	}
+
+	var result *types.PurchaseResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.purchaseItem(uow, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RedemptionID > 0 {
+		go NewRedemptionLogic(l.svcCtx).notifyApprover(result.RedemptionID)
+	}
+	l.svcCtx.Webhooks.Kick()
+
+	return result, nil
+}
+
+// enqueuePurchase announces a recorded purchase to the user's webhooks
+func enqueuePurchase(uow *model.UnitOfWork, event *model.InventoryEvent) error {
+	return webhook.Enqueue(uow.Webhook, event.UserID, webhook.EventPurchase, map[string]interface{}{
+		"itemId":       event.ItemID, // 0 for bundles
+		"itemName":     event.ItemName,
+		"quantity":     event.Quantity,
+		"spiritStones": -event.SpiritStones,
+		"refType":      event.RefType,
+		"refId":        event.RefID,
+	})
+}
+
+func (l *ShopLogic) purchaseItem(uow *model.UnitOfWork, userID int64, req *types.PurchaseItemReq) (*types.PurchaseResult, error) {
+	if req.BundleID > 0 {
+		return l.purchaseBundle(uow, userID, req)
+	}
+
+	item, err := uow.Shop.GetItemByID(req.ItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -172,9 +311,10 @@ func (l *ShopLogic) PurchaseItem(ctx context.Context, userID int64, req *types.P
 		return nil, fmt.Errorf("库存不足")
 	}
 
-	totalPrice := item.Price * req.Quantity
+	now := time.Now()
+	totalPrice := item.EffectivePrice(now) * req.Quantity
 
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	stats, err := uow.Character.FindByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -188,29 +328,76 @@ func (l *ShopLogic) PurchaseItem(ctx context.Context, userID int64, req *types.P
 	}
 
 	// Deduct spirit stones
-	stats.SpiritStones -= totalPrice
+	if err := adjustSpiritStones(uow, stats, -totalPrice, model.LedgerReasonPurchase, model.LedgerRefShopItem, item.ID); err != nil {
+		return nil, err
+	}
 
-	if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
+	if err := uow.Character.Update(stats); err != nil {
 		return nil, err
 	}
 
 	if item.Stock != -1 {
-		if err := l.svcCtx.ShopModel.UpdateItemStock(item.ID, req.Quantity); err != nil {
+		if err := uow.Shop.UpdateItemStock(item.ID, req.Quantity); err != nil {
+			if err == model.ErrConflict {
+				return nil, fmt.Errorf("库存不足")
+			}
 			return nil, err
 		}
 	}
 
-	if err := l.svcCtx.ShopModel.AddToInventory(userID, item.ID, req.Quantity); err != nil {
-		return nil, err
+	event := &model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventoryPurchase,
+		ItemID:       item.ID,
+		ItemName:     item.Name,
+		Quantity:     req.Quantity,
+		SpiritStones: -totalPrice,
+	}
+
+	// Real-world rewards wait in the redemption queue instead of the bag
+	if item.ItemType == "redeemable" {
+		redemption, err := createRedemption(uow, userID, item, req.Quantity, totalPrice)
+		if err != nil {
+			return nil, err
+		}
+		event.RefType = model.LedgerRefRedemption
+		event.RefID = redemption.ID
+		if _, err := uow.Shop.RecordEvent(event); err != nil {
+			return nil, err
+		}
+		if err := enqueuePurchase(uow, event); err != nil {
+			return nil, err
+		}
+
+		message := fmt.Sprintf("成功兑换 %d 个「%s」，等待兑现", req.Quantity, item.Name)
+		if redemption.Status == model.RedemptionAwaitingApproval {
+			message = fmt.Sprintf("成功兑换 %d 个「%s」，等待道侣审批", req.Quantity, item.Name)
+		}
+		return &types.PurchaseResult{
+			Success:               true,
+			Message:               message,
+			RemainingSpiritStones: stats.SpiritStones,
+			RedemptionID:          redemption.ID,
+		}, nil
 	}
 
-	if err := l.svcCtx.ShopModel.RecordPurchase(userID, item.ID, item.Name, req.Quantity, totalPrice); err != nil {
+	if err := uow.Shop.AddToInventory(userID, item.ID, req.Quantity); err != nil {
+		return nil, err
+	}
+	if _, err := uow.Shop.RecordEvent(event); err != nil {
+		return nil, err
+	}
+	if err := enqueuePurchase(uow, event); err != nil {
 		return nil, err
 	}
 
+	message := fmt.Sprintf("成功购买 %d 个「%s」", req.Quantity, item.Name)
+	if item.OnSale(now) {
+		message += fmt.Sprintf("（限时优惠，共 %d 灵石）", totalPrice)
+	}
 	return &types.PurchaseResult{
-		Success:              true,
-		Message:              fmt.Sprintf("成功购买 %d 个「%s」", req.Quantity, item.Name),
+		Success:               true,
+		Message:               message,
 		RemainingSpiritStones: stats.SpiritStones,
 	}, nil
 }
@@ -221,6 +408,27 @@ func (l *ShopLogic) GetInventory(ctx context.Context, userID int64) (*types.Inve
 		return nil, err
 	}
 
+	equipped, err := l.svcCtx.EquipmentModel.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	equippedIDs := make(map[int64]bool)
+	for _, e := range equipped {
+		equippedIDs[e.ItemID] = true
+	}
+
+	lots, err := l.svcCtx.ShopModel.FindLotsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Lots are sorted by expiry, so the first one per item is the earliest
+	firstLots := make(map[int64]*model.InventoryLot)
+	for _, lot := range lots {
+		if _, ok := firstLots[lot.ItemID]; !ok {
+			firstLots[lot.ItemID] = lot
+		}
+	}
+
 	resp := &types.InventoryListResp{
 		Items: make([]types.InventoryItemResp, 0),
 	}
@@ -234,24 +442,52 @@ func (l *ShopLogic) GetInventory(ctx context.Context, userID int64) (*types.Inve
 			continue
 		}
 
-		resp.Items = append(resp.Items, types.InventoryItemResp{
-			ID:          invItem.ID,
-			ItemID:      invItem.ItemID,
-			Name:        item.Name,
-			Description: item.Description,
-			ItemType:    item.ItemType,
-			SellPrice:   item.SellPrice,
-			Icon:        item.Icon,
-			Image:       item.Image,
-			Quantity:    invItem.Quantity,
-		})
+		itemResp := types.InventoryItemResp{
+			ID:             invItem.ID,
+			ItemID:         invItem.ItemID,
+			Name:           item.Name,
+			Description:    item.Description,
+			ItemType:       item.ItemType,
+			SellPrice:      item.SellPrice,
+			Icon:           item.Icon,
+			Image:          item.Image,
+			Quantity:       invItem.Quantity,
+			Slot:           item.Slot,
+			Equipped:       equippedIDs[item.ID],
+			Durability:     item.Durability,
+			DurabilityLeft: durabilityLeft(item, invItem),
+		}
+		if lot, ok := firstLots[item.ID]; ok {
+			itemResp.ExpiresAt = lot.ExpiresAt.Local().Format(time.RFC3339)
+			itemResp.ExpiringQuantity = lot.Quantity
+		}
+		resp.Items = append(resp.Items, itemResp)
 	}
 
 	return resp, nil
 }
 
 func (l *ShopLogic) UseItem(ctx context.Context, userID int64, req *types.UseItemReq) (*types.UseItemResult, error) {
-	invItem, err := l.svcCtx.ShopModel.GetInventoryItemByItemID(userID, req.ItemID)
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("数量必须大于0")
+	}
+
+	var result *types.UseItemResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.useItem(uow, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.svcCtx.Webhooks.Kick()
+
+	return result, nil
+}
+
+func (l *ShopLogic) useItem(uow *model.UnitOfWork, userID int64, req *types.UseItemReq) (*types.UseItemResult, error) {
+	invItem, err := uow.Shop.GetInventoryItemByItemID(userID, req.ItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -259,15 +495,19 @@ func (l *ShopLogic) UseItem(ctx context.Context, userID int64, req *types.UseIte
 		return nil, fmt.Errorf("物品不足")
 	}
 
-	item, err := l.svcCtx.ShopModel.GetItemByID(req.ItemID)
+	item, err := uow.Shop.GetItemByID(req.ItemID)
 	if err != nil {
 		return nil, err
 	}
 	if item == nil {
 		return nil, fmt.Errorf("物品不存在")
 	}
+	// Items that are not used up must not apply their effects again and again
+	if item.ItemType != "consumable" {
+		return nil, fmt.Errorf("「%s」不是消耗品，无法使用", item.Name)
+	}
 
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	stats, err := uow.Character.FindByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -276,139 +516,96 @@ func (l *ShopLogic) UseItem(ctx context.Context, userID int64, req *types.UseIte
 	}
 
 	// Load attributes
-	attrs, err := l.svcCtx.CharacterModel.FindAttributesByUserID(userID)
+	attrs, err := uow.Character.FindAttributesByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
-	attrMap := make(map[string]*model.CharacterAttribute)
-	for _, a := range attrs {
-		attrMap[a.AttrKey] = a
+
+	// Realms before the effects apply, to detect breakthroughs
+	realms := make(map[string]int, len(attrs))
+	for _, attr := range attrs {
+		realms[attr.AttrKey] = attr.Realm
 	}
 
-	message := ""
-	switch item.Effect {
-	case "fatigue_restore":
-		stats.Fatigue -= item.EffectValue * req.Quantity
-		if stats.Fatigue < 0 {
-			stats.Fatigue = 0
-		}
-		message = fmt.Sprintf("恢复了 %d 点精力（降低疲劳）", item.EffectValue*req.Quantity)
+	state := effect.NewState(stats, attrs)
+	messages, err := l.svcCtx.ItemEffects.Apply(state, item.EffectSpecs(), req.Quantity)
+	if err != nil {
+		return nil, err
+	}
 
-	case "physique_boost":
-		if attr, ok := attrMap["physique"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return nil, err
-			}
-		}
-		message = fmt.Sprintf("体魄提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "willpower_boost":
-		if attr, ok := attrMap["willpower"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return nil, err
-			}
-		}
-		message = fmt.Sprintf("意志提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "intelligence_boost":
-		if attr, ok := attrMap["intelligence"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return nil, err
-			}
-		}
-		message = fmt.Sprintf("智力提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "perception_boost":
-		if attr, ok := attrMap["perception"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return nil, err
-			}
-		}
-		message = fmt.Sprintf("感知提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "charisma_boost":
-		if attr, ok := attrMap["charisma"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return nil, err
-			}
+	message := strings.Join(messages, "，")
+	if message == "" {
+		message = fmt.Sprintf("已使用「%s」", item.Name)
+	}
+
+	if err := adjustSpiritStones(uow, stats, state.SpiritStones, model.LedgerReasonItemEffect, model.LedgerRefShopItem, item.ID); err != nil {
+		return nil, err
+	}
+
+	for _, attr := range state.ChangedAttrs() {
+		if err := uow.Character.UpdateAttribute(attr); err != nil {
+			return nil, err
 		}
-		message = fmt.Sprintf("魅力提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "agility_boost":
-		if attr, ok := attrMap["agility"]; ok {
-			gain := float64(item.EffectValue * req.Quantity)
-			result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
-			attr.Value = result.NewValue
-			attr.AccumulationPool = result.NewAccPool
-			attr.RealmExp = result.NewRealmExp
-			attr.IsBottleneck = result.NewIsBottleneck
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
+		if attr.Realm > realms[attr.AttrKey] {
+			err := webhook.Enqueue(uow.Webhook, userID, webhook.EventRealmBreakthrough, map[string]interface{}{
+				"attribute":  attr.AttrKey,
+				"fromRealm":  realm.GetRealmName(realms[attr.AttrKey]),
+				"realm":      realm.GetRealmName(attr.Realm),
+				"realmIndex": attr.Realm,
+				"value":      attr.Value,
+				"itemId":     item.ID,
+			})
+			if err != nil {
 				return nil, err
 			}
 		}
-		message = fmt.Sprintf("敏捷提升了 %d 点", item.EffectValue*req.Quantity)
-
-	case "spirit_stone_gain":
-		stats.SpiritStones += item.EffectValue * req.Quantity
-		message = fmt.Sprintf("获得了 %d 灵石", item.EffectValue*req.Quantity)
-
-	case "", "none":
-		message = fmt.Sprintf("已使用「%s」", item.Name)
+	}
 
-	default:
-		return nil, fmt.Errorf("未知的物品效果")
+	now := time.Now()
+	for _, buff := range state.Buffs {
+		buff.SourceType = model.LedgerRefShopItem
+		buff.SourceID = item.ID
+		if err := grantBuff(uow, buff, now); err != nil {
+			return nil, err
+		}
 	}
 
 	// Update character stats
-	if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
+	if err := uow.Character.Update(stats); err != nil {
 		return nil, err
 	}
 
-	// Remove from inventory (only for consumables)
-	if item.ItemType == "consumable" {
-		if err := l.svcCtx.ShopModel.RemoveFromInventory(userID, req.ItemID, req.Quantity); err != nil {
-			return nil, err
+	// Remove from inventory
+	if err := uow.Shop.RemoveFromInventory(userID, req.ItemID, req.Quantity); err != nil {
+		if err == model.ErrConflict {
+			return nil, fmt.Errorf("物品不足")
 		}
+		return nil, err
+	}
+
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventoryUse,
+		ItemID:       item.ID,
+		ItemName:     item.Name,
+		Quantity:     -req.Quantity,
+		SpiritStones: state.SpiritStones,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Reload attributes for response
-	attrs, err = l.svcCtx.CharacterModel.FindAttributesByUserID(userID)
+	attrs, err = uow.Character.FindAttributesByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	charLogic := NewCharacterLogic(l.svcCtx)
-	charResp := charLogic.statsToResp(stats, attrs)
+	charResp, err := charLogic.characterResp(uow, stats, attrs)
+	if err != nil {
+		return nil, err
+	}
 
 	return &types.UseItemResult{
 		Success:   true,
@@ -418,7 +615,25 @@ func (l *ShopLogic) UseItem(ctx context.Context, userID int64, req *types.UseIte
 }
 
 func (l *ShopLogic) SellItem(ctx context.Context, userID int64, req *types.SellItemReq) (*types.SellItemResult, error) {
-	invItem, err := l.svcCtx.ShopModel.GetInventoryItemByItemID(userID, req.ItemID)
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("数量必须大于0")
+	}
+
+	var result *types.SellItemResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.sellItem(uow, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (l *ShopLogic) sellItem(uow *model.UnitOfWork, userID int64, req *types.SellItemReq) (*types.SellItemResult, error) {
+	invItem, err := uow.Shop.GetInventoryItemByItemID(userID, req.ItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -426,7 +641,7 @@ func (l *ShopLogic) SellItem(ctx context.Context, userID int64, req *types.SellI
 		return nil, fmt.Errorf("物品不足")
 	}
 
-	item, err := l.svcCtx.ShopModel.GetItemByID(req.ItemID)
+	item, err := uow.Shop.GetItemByID(req.ItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -438,9 +653,18 @@ func (l *ShopLogic) SellItem(ctx context.Context, userID int64, req *types.SellI
 		return nil, fmt.Errorf("该物品不可出售")
 	}
 
+	// The equipped unit stays in the bag
+	equipped, err := uow.Equipment.IsEquipped(userID, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if equipped && invItem.Quantity-1 < req.Quantity {
+		return nil, fmt.Errorf("已装备的物品不可出售，请先卸下")
+	}
+
 	totalGain := item.SellPrice * req.Quantity
 
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	stats, err := uow.Character.FindByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -448,41 +672,464 @@ func (l *ShopLogic) SellItem(ctx context.Context, userID int64, req *types.SellI
 		return nil, fmt.Errorf("角色不存在")
 	}
 
-	stats.SpiritStones += totalGain
-	if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
+	if err := adjustSpiritStones(uow, stats, totalGain, model.LedgerReasonSale, model.LedgerRefShopItem, item.ID); err != nil {
+		return nil, err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
+	}
+
+	if err := uow.Shop.RemoveFromInventory(userID, req.ItemID, req.Quantity); err != nil {
+		if err == model.ErrConflict {
+			return nil, fmt.Errorf("物品不足")
+		}
 		return nil, err
 	}
 
-	if err := l.svcCtx.ShopModel.RemoveFromInventory(userID, req.ItemID, req.Quantity); err != nil {
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventorySell,
+		ItemID:       item.ID,
+		ItemName:     item.Name,
+		Quantity:     -req.Quantity,
+		SpiritStones: totalGain,
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &types.SellItemResult{
-		Success:              true,
-		Message:              fmt.Sprintf("成功出售 %d 个「%s」，获得 %d 灵石", req.Quantity, item.Name, totalGain),
+		Success:               true,
+		Message:               fmt.Sprintf("成功出售 %d 个「%s」，获得 %d 灵石", req.Quantity, item.Name, totalGain),
 		RemainingSpiritStones: stats.SpiritStones,
 	}, nil
 }
 
-func (l *ShopLogic) GetPurchaseHistory(ctx context.Context, userID int64) (*types.PurchaseHistoryResp, error) {
-	history, err := l.svcCtx.ShopModel.GetPurchaseHistory(userID, 50)
+func (l *ShopLogic) GetInventoryHistory(ctx context.Context, userID int64, req *types.InventoryHistoryReq) (*types.InventoryHistoryResp, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultLedgerPageSize
+	}
+	if pageSize > maxLedgerPageSize {
+		pageSize = maxLedgerPageSize
+	}
+
+	for _, d := range []string{req.From, req.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("日期格式应为 YYYY-MM-DD")
+		}
+	}
+
+	events, total, err := l.svcCtx.ShopModel.FindEvents(userID, model.InventoryEventFilter{
+		Kind:   req.Kind,
+		ItemID: req.ItemID,
+		From:   req.From,
+		To:     req.To,
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp := &types.PurchaseHistoryResp{
-		History: make([]types.PurchaseRecordResp, 0),
+	resp := &types.InventoryHistoryResp{
+		History:  make([]types.InventoryEventResp, 0, len(events)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
 	}
 
-	for _, record := range history {
-		resp.History = append(resp.History, types.PurchaseRecordResp{
-			ID:         record.ID,
-			ItemName:   record.ItemName,
-			Quantity:   record.Quantity,
-			TotalPrice: record.TotalPrice,
-			CreatedAt:  record.CreatedAt.Format("2006-01-02 15:04:05"),
+	now := time.Now()
+	for _, e := range events {
+		totalPrice := e.SpiritStones
+		if totalPrice < 0 {
+			totalPrice = -totalPrice
+		}
+		resp.History = append(resp.History, types.InventoryEventResp{
+			ID:                 e.ID,
+			Kind:               e.Kind,
+			ItemID:             e.ItemID,
+			ItemName:           e.ItemName,
+			Quantity:           e.Quantity,
+			SpiritStones:       e.SpiritStones,
+			TotalPrice:         totalPrice,
+			RefType:            e.RefType,
+			RefID:              e.RefID,
+			RefundableQuantity: l.refundableQuantity(e, now),
+			CreatedAt:          e.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 
 	return resp, nil
 }
+
+func (l *ShopLogic) RefundItem(ctx context.Context, userID int64, req *types.RefundReq) (*types.RefundResult, error) {
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("数量必须大于0")
+	}
+
+	var result *types.RefundResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.refundItem(uow, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// refundItem returns unused units of a recent purchase. The refund is the
+// share of what was actually paid, so discounted purchases refund the
+// discounted price.
+func (l *ShopLogic) refundItem(uow *model.UnitOfWork, userID int64, req *types.RefundReq) (*types.RefundResult, error) {
+	purchase, err := uow.Shop.FindEventByID(req.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if purchase == nil || purchase.UserID != userID || purchase.Kind != model.InventoryPurchase {
+		return nil, fmt.Errorf("购买记录不存在")
+	}
+	if purchase.ItemID == 0 {
+		return nil, fmt.Errorf("礼包不支持退款")
+	}
+	if purchase.RefType == model.LedgerRefRedemption {
+		return nil, fmt.Errorf("实物奖励请在兑换队列中取消")
+	}
+	if time.Since(purchase.CreatedAt) > l.refundWindow() {
+		return nil, fmt.Errorf("已超过 %d 小时退款期限", int(l.refundWindow().Hours()))
+	}
+	if purchase.Refunded+req.Quantity > purchase.Quantity {
+		return nil, fmt.Errorf("最多还能退 %d 个", purchase.Quantity-purchase.Refunded)
+	}
+
+	// Only units still in the bag and not equipped can be returned
+	available, err := craftableQuantity(uow, userID, purchase.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if available < req.Quantity {
+		return nil, fmt.Errorf("背包中未使用的「%s」不足 %d 个", purchase.ItemName, req.Quantity)
+	}
+
+	paid := -purchase.SpiritStones
+	amount := paid*(purchase.Refunded+req.Quantity)/purchase.Quantity - paid*purchase.Refunded/purchase.Quantity
+
+	if err := uow.Shop.MarkRefunded(purchase.ID, req.Quantity); err != nil {
+		if err == model.ErrConflict {
+			return nil, fmt.Errorf("退款数量超过购买数量")
+		}
+		return nil, err
+	}
+	if err := uow.Shop.RemoveFromInventory(userID, purchase.ItemID, req.Quantity); err != nil {
+		if err == model.ErrConflict {
+			return nil, fmt.Errorf("物品不足")
+		}
+		return nil, err
+	}
+	if err := uow.Shop.RestoreItemStock(purchase.ItemID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	stats, err := uow.Character.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("角色不存在")
+	}
+	if err := adjustSpiritStones(uow, stats, amount, model.LedgerReasonRefund, model.LedgerRefShopItem, purchase.ItemID); err != nil {
+		return nil, err
+	}
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
+	}
+
+	_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+		UserID:       userID,
+		Kind:         model.InventoryRefund,
+		ItemID:       purchase.ItemID,
+		ItemName:     purchase.ItemName,
+		Quantity:     -req.Quantity,
+		SpiritStones: amount,
+		RefType:      model.InventoryRefEvent,
+		RefID:        purchase.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.RefundResult{
+		Success:               true,
+		Message:               fmt.Sprintf("已退回 %d 个「%s」，返还 %d 灵石", req.Quantity, purchase.ItemName, amount),
+		RefundedSpiritStones:  amount,
+		RemainingSpiritStones: stats.SpiritStones,
+	}, nil
+}
+
+// refundWindow is how long after purchase items can be refunded.
+func (l *ShopLogic) refundWindow() time.Duration {
+	hours := l.svcCtx.Config.Shop.RefundWindowHours
+	if hours <= 0 {
+		hours = defaultRefundWindowHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// refundableQuantity is how many units of a purchase event may still be
+// refunded at now, ignoring whether they are still in the bag.
+func (l *ShopLogic) refundableQuantity(e *model.InventoryEvent, now time.Time) int {
+	if e.Kind != model.InventoryPurchase || e.ItemID == 0 || e.RefType == model.LedgerRefRedemption {
+		return 0
+	}
+	if now.Sub(e.CreatedAt) > l.refundWindow() {
+		return 0
+	}
+	return e.Quantity - e.Refunded
+}
+
+// RestockItems tops up every limited item whose restock period has rolled
+// over since its last restock. Returns how many items gained stock.
+func (l *ShopLogic) RestockItems(ctx context.Context, now time.Time) (int, error) {
+	items, err := l.svcCtx.ShopModel.FindRestockable()
+	if err != nil {
+		return 0, err
+	}
+
+	restocked := 0
+	for _, item := range items {
+		if item.RestockedAt.Valid && !item.RestockedAt.Time.Before(restockPeriodStart(item.RestockPeriod, now)) {
+			continue
+		}
+
+		stock := item.Stock + item.RestockAmount
+		if item.RestockMax > 0 && stock > item.RestockMax {
+			stock = max(item.Stock, item.RestockMax)
+		}
+		if err := l.svcCtx.ShopModel.RestockItem(item.ID, item.Stock, stock, now); err != nil {
+			if err == model.ErrConflict {
+				// Bought in the meantime, picked up again on the next run
+				continue
+			}
+			return restocked, err
+		}
+		if stock > item.Stock {
+			restocked++
+		}
+	}
+
+	return restocked, nil
+}
+
+func (l *ShopLogic) itemToResp(item *model.ShopItem) types.ShopItemResp {
+	specs := item.EffectSpecs()
+	effects := make([]types.ItemEffectSpecResp, 0, len(specs))
+	names := make([]string, 0, len(specs))
+	descriptions := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		name, desc := l.svcCtx.ItemEffects.Describe(spec)
+		effects = append(effects, types.ItemEffectSpecResp{
+			Type:        spec.Type,
+			Target:      spec.Target,
+			Value:       spec.Value,
+			Duration:    spec.Duration,
+			Uses:        spec.Uses,
+			Stacking:    spec.Stacking,
+			Name:        name,
+			Description: desc,
+		})
+		names = append(names, name)
+		descriptions = append(descriptions, desc)
+	}
+
+	now := time.Now()
+	nextRestockAt := ""
+	if item.RestockPeriod != "" {
+		nextRestockAt = nextRestockPeriod(item.RestockPeriod, now).Format(time.RFC3339)
+	}
+
+	return types.ShopItemResp{
+		ID:                item.ID,
+		Name:              item.Name,
+		Description:       item.Description,
+		Price:             item.Price,
+		SellPrice:         item.SellPrice,
+		ItemType:          item.ItemType,
+		Effect:            item.Effect,
+		EffectValue:       item.EffectValue,
+		EffectName:        strings.Join(names, "、"),
+		EffectDescription: strings.Join(descriptions, "，"),
+		Effects:           effects,
+		Slot:              item.Slot,
+		Passives:          passivesToResp(item.Passives),
+		Icon:              item.Icon,
+		Image:             item.Image,
+		Stock:             item.Stock,
+		EffectivePrice:    item.EffectivePrice(now),
+		OnSale:            item.OnSale(now),
+		DiscountPercent:   item.DiscountPercent,
+		DiscountStart:     formatSaleTime(item.DiscountStart),
+		DiscountEnd:       formatSaleTime(item.DiscountEnd),
+		RestockPeriod:     item.RestockPeriod,
+		RestockAmount:     item.RestockAmount,
+		RestockMax:        item.RestockMax,
+		NextRestockAt:     nextRestockAt,
+		ExpiresAfter:      item.ExpiresAfter,
+		Durability:        item.Durability,
+	}
+}
+
+func (l *ShopLogic) itemEffectsResp() []types.ItemEffectResp {
+	infos := l.svcCtx.ItemEffects.All()
+	resp := make([]types.ItemEffectResp, 0, len(infos))
+	for _, info := range infos {
+		targets := info.Targets
+		if targets == nil {
+			targets = []string{}
+		}
+		resp = append(resp, types.ItemEffectResp{
+			Key:         info.Key,
+			Name:        info.Name,
+			Description: info.Description,
+			NeedsValue:  info.NeedsValue,
+			NeedsTarget: info.NeedsTarget,
+			Targets:     targets,
+		})
+	}
+	return resp
+}
+
+// setItemEffects stores composite effects on item. The first effect is
+// mirrored into Effect/EffectValue for clients that only read those.
+func setItemEffects(item *model.ShopItem, specs []types.ItemEffectSpec) {
+	if len(specs) == 0 {
+		item.Effects = nil
+		return
+	}
+
+	item.Effects = make([]model.EffectSpec, 0, len(specs))
+	for _, spec := range specs {
+		item.Effects = append(item.Effects, model.EffectSpec{
+			Type:     spec.Type,
+			Target:   spec.Target,
+			Value:    spec.Value,
+			Duration: spec.Duration,
+			Uses:     spec.Uses,
+			Stacking: spec.Stacking,
+		})
+	}
+	item.Effect = specs[0].Type
+	item.EffectValue = specs[0].Value
+}
+
+func toPassives(reqs []types.PassiveModifierReq) []model.PassiveModifier {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	passives := make([]model.PassiveModifier, 0, len(reqs))
+	for _, p := range reqs {
+		passives = append(passives, model.PassiveModifier{
+			Modifier: p.Modifier,
+			Target:   p.Target,
+			Value:    p.Value,
+		})
+	}
+	return passives
+}
+
+// itemTypes are the known shop item types
+var itemTypes = map[string]bool{"consumable": true, "equipment": true, "redeemable": true}
+
+// validateItemType checks the item type. Only consumables are used up, so
+// only they may carry effects applied on use.
+func validateItemType(item *model.ShopItem) error {
+	if !itemTypes[item.ItemType] {
+		return fmt.Errorf("未知的物品类型: %s", item.ItemType)
+	}
+	if item.ItemType == "consumable" {
+		return nil
+	}
+	for _, spec := range item.EffectSpecs() {
+		if spec.Type != "none" {
+			return fmt.Errorf("只有消耗品可以设置使用效果")
+		}
+	}
+	return nil
+}
+
+// validateShopSchedule checks an item's restock rule, discount window and expiry.
+func validateShopSchedule(item *model.ShopItem) error {
+	if item.ExpiresAfter < 0 {
+		return fmt.Errorf("有效期不能为负数")
+	}
+
+	switch item.RestockPeriod {
+	case "":
+	case model.RestockDaily, model.RestockWeekly:
+		if item.Stock == -1 {
+			return fmt.Errorf("无限库存的商品无需补货")
+		}
+		if item.RestockAmount <= 0 {
+			return fmt.Errorf("每次补货数量必须大于0")
+		}
+		if item.RestockMax < 0 {
+			return fmt.Errorf("补货上限不能为负数")
+		}
+	default:
+		return fmt.Errorf("未知的补货周期: %s", item.RestockPeriod)
+	}
+
+	if item.DiscountPercent < 0 || item.DiscountPercent > 100 {
+		return fmt.Errorf("折扣比例必须在 0-100 之间")
+	}
+	if item.DiscountStart.Valid && item.DiscountEnd.Valid && !item.DiscountEnd.Time.After(item.DiscountStart.Time) {
+		return fmt.Errorf("折扣结束时间必须晚于开始时间")
+	}
+	return nil
+}
+
+// restockPeriodStart is the local start of the day or week containing now.
+func restockPeriodStart(period string, now time.Time) time.Time {
+	now = now.Local()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == model.RestockWeekly {
+		// Weeks start on Monday
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	return start
+}
+
+func nextRestockPeriod(period string, now time.Time) time.Time {
+	if period == model.RestockWeekly {
+		return restockPeriodStart(period, now).AddDate(0, 0, 7)
+	}
+	return restockPeriodStart(period, now).AddDate(0, 0, 1)
+}
+
+func parseSaleTime(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("折扣时间格式错误，应为 RFC3339")
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+func formatSaleTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Local().Format(time.RFC3339)
+}
@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"life-system-backend/internal/types"
+)
+
+func TestShopItemTypeValidation(t *testing.T) {
+	svcCtx, _ := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     types.CreateShopItemReq
+		wantErr string
+	}{
+		{"consumable with effect", types.CreateShopItemReq{Name: "灵石袋", ItemType: "consumable", Effect: "spirit_stone_gain", EffectValue: 10}, ""},
+		{"default type", types.CreateShopItemReq{Name: "回春丹", Effect: "fatigue_restore", EffectValue: 10}, ""},
+		{"equipment without effect", types.CreateShopItemReq{Name: "铁剑", ItemType: "equipment"}, ""},
+		{"unknown type", types.CreateShopItemReq{Name: "灵草", ItemType: "material"}, "未知的物品类型"},
+		{"equipment with effect", types.CreateShopItemReq{Name: "聚宝盆", ItemType: "equipment", Effect: "spirit_stone_gain", EffectValue: 10}, "只有消耗品"},
+		{"redeemable with effect", types.CreateShopItemReq{Name: "电影之夜", ItemType: "redeemable", Effect: "fatigue_restore", EffectValue: 10}, "只有消耗品"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CreateShopItem() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CreateShopItem() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{Name: "铜镜", ItemType: "equipment"})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	effect := "spirit_stone_gain"
+	value := 10
+	_, err = NewShopLogic(svcCtx).UpdateShopItem(ctx, userID, item.ID, &types.UpdateShopItemReq{Effect: &effect, EffectValue: &value})
+	if err == nil || !strings.Contains(err.Error(), "只有消耗品") {
+		t.Errorf("UpdateShopItem() error = %v, want the effect refused", err)
+	}
+}
+
+func TestUseRefusesNonConsumables(t *testing.T) {
+	svcCtx, db := newTestService(t)
+	userID := newTestUser(t, svcCtx, "tester", 100)
+	ctx := context.Background()
+
+	item, err := NewShopLogic(svcCtx).CreateShopItem(ctx, userID, &types.CreateShopItemReq{
+		Name:     "铁剑",
+		Price:    10,
+		ItemType: "equipment",
+		Stock:    -1,
+	})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	if _, err := NewShopLogic(svcCtx).PurchaseItem(ctx, userID, &types.PurchaseItemReq{ItemID: item.ID, Quantity: 1}); err != nil {
+		t.Fatalf("purchase: %v", err)
+	}
+	// An item saved before effects were limited to consumables
+	if _, err := db.Exec(`UPDATE shop_items SET effect = 'spirit_stone_gain', effect_value = 50 WHERE id = ?`, item.ID); err != nil {
+		t.Fatalf("add effect: %v", err)
+	}
+
+	_, err = NewShopLogic(svcCtx).UseItem(ctx, userID, &types.UseItemReq{ItemID: item.ID, Quantity: 1})
+	if err == nil || !strings.Contains(err.Error(), "不是消耗品") {
+		t.Errorf("UseItem() error = %v, want equipment refused", err)
+	}
+	var balance int
+	if err := db.QueryRow("SELECT spirit_stones FROM character_stats WHERE user_id = ?", userID).Scan(&balance); err != nil {
+		t.Fatalf("read balance: %v", err)
+	}
+	if balance != 90 {
+		t.Errorf("balance is %d, want 90", balance)
+	}
+	assertLedgerBalanced(t, db, userID)
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"life-system-backend/internal/loot"
 	"life-system-backend/internal/model"
 	"life-system-backend/internal/realm"
 	"life-system-backend/internal/svc"
 	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+	"life-system-backend/pkg/webhook"
 )
 
 // Difficulty template: maps difficulty stars (0-5) to preset values
@@ -227,10 +232,30 @@ type CompleteTaskResult struct {
 	Task      types.TaskResp      `json:"task"`
 	Character types.CharacterResp `json:"character"`
 	Message   string              `json:"message"`
+	Drops     []types.DropResp    `json:"drops"`
 }
 
 func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64, source string) (*CompleteTaskResult, error) {
-	task, err := l.svcCtx.TaskModel.FindByID(taskID)
+	var result *CompleteTaskResult
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		result, err = l.completeTask(uow, userID, taskID, source)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Drops) > 0 {
+		go l.pushDrops(userID, result.Task.Title, result.Drops)
+	}
+	l.svcCtx.Webhooks.Kick()
+
+	return result, nil
+}
+
+func (l *TaskLogic) completeTask(uow *model.UnitOfWork, userID int64, taskID int64, source string) (*CompleteTaskResult, error) {
+	task, err := uow.Task.FindByID(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +293,7 @@ func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64
 	}
 
 	// Get character stats
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(userID)
+	stats, err := uow.Character.FindByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -276,17 +301,31 @@ func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64
 		return nil, fmt.Errorf("character not found")
 	}
 
+	// Active buffs modify fatigue cost, spirit stones and attribute gains
+	buffs, err := loadTaskBuffs(uow, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// Consume fatigue (allow overdraft, but no penalty)
-	stats.Fatigue += task.FatigueCost
+	stats.Fatigue += buffs.scale(task.FatigueCost, model.BuffModifierFatigueCost, "")
 
-	// Add spirit stones
-	stats.SpiritStones += task.RewardSpiritStones
+	// Add spirit stones, consuming any pending reward bonus from items
+	reward := buffs.scale(task.RewardSpiritStones, model.BuffModifierSpiritStones, "")
+	bonus := 0
+	if stats.NextRewardBonus > 0 && reward > 0 {
+		bonus = reward * stats.NextRewardBonus / 100
+		stats.NextRewardBonus = 0
+	}
+	if err := adjustSpiritStones(uow, stats, reward+bonus, model.LedgerReasonTaskReward, model.LedgerRefTask, taskID); err != nil {
+		return nil, err
+	}
 
 	// Update last activity date
 	stats.LastActivityDate = today
 
 	// Get attributes for reward processing
-	attrs, err := l.svcCtx.CharacterModel.FindAttributesByUserID(userID)
+	attrs, err := uow.Character.FindAttributesByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -315,6 +354,7 @@ func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64
 		if !ok {
 			continue
 		}
+		gain = buffs.scaleFloat(gain, model.BuffModifierAttrGain, key)
 
 		result := realm.ProcessAttrGain(attr.Value, gain, attr.Realm, attr.RealmExp, attr.IsBottleneck, attr.AccumulationPool)
 		attr.Value = result.NewValue
@@ -329,24 +369,27 @@ func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64
 		attr.TodayGain += gain
 		attr.LastGainDate = today
 
-		if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
+		if err := uow.Character.UpdateAttribute(attr); err != nil {
 			return nil, err
 		}
 	}
 
-	// Reload attributes after updates
-	attrs, err = l.svcCtx.CharacterModel.FindAttributesByUserID(userID)
+	if err := buffs.consume(uow); err != nil {
+		return nil, err
+	}
+
+	broken, err := wearEquipment(uow, userID, taskID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update task
-	if err := l.svcCtx.TaskModel.Update(task); err != nil {
+	if err := uow.Task.Update(task); err != nil {
 		return nil, err
 	}
 
 	// Update character stats
-	if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
+	if err := uow.Character.Update(stats); err != nil {
 		return nil, err
 	}
 
@@ -357,56 +400,227 @@ func (l *TaskLogic) CompleteTask(ctx context.Context, userID int64, taskID int64
 		Action: "complete",
 		Source: source,
 	}
-	if err := l.svcCtx.TaskModel.CreateLog(log); err != nil {
+	if err := uow.Task.CreateLog(log); err != nil {
+		return nil, err
+	}
+
+	// Earmark part of the reward for savings goals
+	savings, err := NewSavingsLogic(l.svcCtx).depositSavings(uow, stats, reward+bonus)
+	if err != nil {
+		return nil, err
+	}
+	if len(savings) > 0 {
+		stats, err = uow.Character.FindByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	luck := 0.0
+	if attr, ok := attrMap["luck"]; ok {
+		luck = attr.Value
+	}
+	drops, err := l.rollDrops(uow, task, luck)
+	if err != nil {
+		return nil, err
+	}
+
+	dropNames := make([]string, 0, len(drops))
+	for _, d := range drops {
+		dropNames = append(dropNames, d.Name)
+	}
+	err = webhook.Enqueue(uow.Webhook, userID, webhook.EventTaskCompleted, map[string]interface{}{
+		"taskId":       task.ID,
+		"title":        task.Title,
+		"type":         task.Type,
+		"difficulty":   task.Difficulty,
+		"spiritStones": reward + bonus,
+		"source":       source,
+		"drops":        dropNames,
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	charLogic := NewCharacterLogic(l.svcCtx)
-	charResp := charLogic.statsToResp(stats, attrs)
+	charResp, err := charLogic.characterResp(uow, stats, attrs)
+	if err != nil {
+		return nil, err
+	}
 
-	message := fmt.Sprintf("✅ 任务「%s」已完成！获得 %d灵石", task.Title, task.RewardSpiritStones)
+	message := fmt.Sprintf("✅ 任务「%s」已完成！获得 %d灵石", task.Title, reward)
+	if bonus > 0 {
+		message += fmt.Sprintf("（奖励加成 +%d）", bonus)
+	}
+	if len(drops) > 0 {
+		message += "\n🎁 掉落：" + describeDrops(drops)
+	}
+	for _, m := range savings {
+		message += "\n" + m
+	}
+	for _, m := range broken {
+		message += "\n" + m
+	}
 
 	return &CompleteTaskResult{
 		Task:      l.taskToResp(task),
 		Character: *charResp,
 		Message:   message,
+		Drops:     drops,
 	}, nil
 }
 
+// rollDrops rolls the user's drop tables for a completed task and puts the
+// dropped items into the inventory. Entries pointing at deleted items or at
+// items of another user are skipped.
+func (l *TaskLogic) rollDrops(uow *model.UnitOfWork, task *model.Task, luck float64) ([]types.DropResp, error) {
+	entries, err := uow.Loot.FindEntriesForDifficulty(task.UserID, task.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*model.DropEntry
+	for _, e := range entries {
+		if loot.Matches(e, task.Difficulty, task.Category) {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return []types.DropResp{}, nil
+	}
+
+	rng := loot.NewRand(l.svcCtx.Config.Loot.Seed, task.ID, task.CompletedCount)
+	drops := make([]types.DropResp, 0)
+	for _, d := range loot.Roll(matched, luck, rng) {
+		item, err := uow.Shop.GetItemByID(d.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil || item.UserID != task.UserID {
+			continue
+		}
+
+		if err := uow.Shop.AddToInventory(task.UserID, item.ID, d.Quantity); err != nil {
+			return nil, err
+		}
+		err = uow.Loot.RecordDrop(&model.LootDrop{
+			UserID:   task.UserID,
+			TaskID:   task.ID,
+			ItemID:   item.ID,
+			ItemName: item.Name,
+			Quantity: d.Quantity,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = uow.Shop.RecordEvent(&model.InventoryEvent{
+			UserID:   task.UserID,
+			Kind:     model.InventoryDrop,
+			ItemID:   item.ID,
+			ItemName: item.Name,
+			Quantity: d.Quantity,
+			RefType:  model.LedgerRefTask,
+			RefID:    task.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		drops = append(drops, types.DropResp{
+			ItemID:   item.ID,
+			Name:     item.Name,
+			Icon:     item.Icon,
+			Quantity: d.Quantity,
+		})
+	}
+
+	return drops, nil
+}
+
+func describeDrops(drops []types.DropResp) string {
+	parts := make([]string, 0, len(drops))
+	for _, d := range drops {
+		parts = append(parts, fmt.Sprintf("%s%s ×%d", d.Icon, d.Name, d.Quantity))
+	}
+	return strings.Join(parts, "、")
+}
+
+// pushDrops announces dropped items. It runs after the transaction committed
+// and is best effort.
+func (l *TaskLogic) pushDrops(userID int64, taskTitle string, drops []types.DropResp) {
+	err := l.svcCtx.Notifier.Notify(userID, &notify.Notification{
+		Kind:  notify.KindLootDrop,
+		Title: fmt.Sprintf("🎁 「%s」掉落物品", taskTitle),
+		Body:  describeDrops(drops),
+		Level: notify.LevelPassive,
+	})
+	if err != nil {
+		log.Printf("Error sending drop notification: %v", err)
+	}
+}
+
 func (l *TaskLogic) FailTask(ctx context.Context, taskID int64, reason string) error {
-	task, err := l.svcCtx.TaskModel.FindByID(taskID)
+	var task *model.Task
+	err := l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		var err error
+		task, err = l.failTask(uow, taskID)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if task == nil {
-		return fmt.Errorf("task not found")
+		return nil
+	}
+	l.svcCtx.Webhooks.Kick()
+
+	fmt.Printf("❌ Task #%d failed: %s. Penalties applied: -%d spiritStones\n",
+		taskID, reason, task.PenaltySpiritStones)
+
+	return nil
+}
+
+// failTask applies challenge failure penalties. It returns a nil task when
+// the task is no longer active and nothing was changed.
+func (l *TaskLogic) failTask(uow *model.UnitOfWork, taskID int64) (*model.Task, error) {
+	task, err := uow.Task.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task not found")
 	}
 	if task.Type != "challenge" {
-		return fmt.Errorf("only challenge tasks can fail")
+		return nil, fmt.Errorf("only challenge tasks can fail")
 	}
 	if task.Status != "active" {
-		return nil
+		return nil, nil
 	}
 
 	// Get character stats
-	stats, err := l.svcCtx.CharacterModel.FindByUserID(task.UserID)
+	stats, err := uow.Character.FindByUserID(task.UserID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if stats == nil {
-		return fmt.Errorf("character not found")
+		return nil, fmt.Errorf("character not found")
 	}
 
 	// Deduct spirit stones (min 0)
-	stats.SpiritStones -= task.PenaltySpiritStones
-	if stats.SpiritStones < 0 {
-		stats.SpiritStones = 0
+	penalty := task.PenaltySpiritStones
+	if penalty > stats.SpiritStones {
+		penalty = stats.SpiritStones
+	}
+	if penalty > 0 {
+		if err := adjustSpiritStones(uow, stats, -penalty, model.LedgerReasonTaskPenalty, model.LedgerRefTask, taskID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Get attributes for penalty processing
-	attrs, err := l.svcCtx.CharacterModel.FindAttributesByUserID(task.UserID)
+	attrs, err := uow.Character.FindAttributesByUserID(task.UserID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Attribute penalty: reduce by penaltyExp/10 but not below realm base value
@@ -421,8 +635,8 @@ func (l *TaskLogic) FailTask(ctx context.Context, taskID int64, reason string) e
 			if attr.Value < minVal {
 				attr.Value = minVal
 			}
-			if err := l.svcCtx.CharacterModel.UpdateAttribute(attr); err != nil {
-				return err
+			if err := uow.Character.UpdateAttribute(attr); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -430,11 +644,11 @@ func (l *TaskLogic) FailTask(ctx context.Context, taskID int64, reason string) e
 	// Update task status
 	task.Status = "failed"
 
-	if err := l.svcCtx.TaskModel.Update(task); err != nil {
-		return err
+	if err := uow.Task.Update(task); err != nil {
+		return nil, err
 	}
-	if err := l.svcCtx.CharacterModel.Update(stats); err != nil {
-		return err
+	if err := uow.Character.Update(stats); err != nil {
+		return nil, err
 	}
 
 	log := &model.TaskLog{
@@ -443,46 +657,55 @@ func (l *TaskLogic) FailTask(ctx context.Context, taskID int64, reason string) e
 		Action: "fail",
 		Source: "system",
 	}
-	if err := l.svcCtx.TaskModel.CreateLog(log); err != nil {
-		return err
+	if err := uow.Task.CreateLog(log); err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("❌ Task #%d failed: %s. Penalties applied: -%d spiritStones\n",
-		taskID, reason, task.PenaltySpiritStones)
-
-	return nil
-}
-
-func (l *TaskLogic) DeleteTask(ctx context.Context, userID int64, taskID int64, source string) error {
-	task, err := l.svcCtx.TaskModel.FindByID(taskID)
+	err = webhook.Enqueue(uow.Webhook, task.UserID, webhook.EventTaskFailed, map[string]interface{}{
+		"taskId":       task.ID,
+		"title":        task.Title,
+		"spiritStones": -penalty,
+		"attrPenalty":  attrPenalty,
+	})
 	if err != nil {
-		return err
-	}
-	if task == nil {
-		return fmt.Errorf("task not found")
-	}
-	if task.UserID != userID {
-		return fmt.Errorf("unauthorized")
-	}
-	if task.Status != "active" {
-		return fmt.Errorf("只能删除进行中的任务")
+		return nil, err
 	}
 
-	if err := l.svcCtx.TaskModel.Delete(taskID); err != nil {
-		return err
-	}
+	return task, nil
+}
 
+func (l *TaskLogic) DeleteTask(ctx context.Context, userID int64, taskID int64, source string) error {
 	if source == "" {
 		source = "web"
 	}
 
-	log := &model.TaskLog{
-		TaskID: taskID,
-		UserID: userID,
-		Action: "delete",
-		Source: source,
-	}
-	return l.svcCtx.TaskModel.CreateLog(log)
+	return l.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+		task, err := uow.Task.FindByID(taskID)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return fmt.Errorf("task not found")
+		}
+		if task.UserID != userID {
+			return fmt.Errorf("unauthorized")
+		}
+		if task.Status != "active" {
+			return fmt.Errorf("只能删除进行中的任务")
+		}
+
+		if err := uow.Task.Delete(taskID); err != nil {
+			return err
+		}
+
+		log := &model.TaskLog{
+			TaskID: taskID,
+			UserID: userID,
+			Action: "delete",
+			Source: source,
+		}
+		return uow.Task.CreateLog(log)
+	})
 }
 
 type QuickTaskResult struct {
@@ -492,11 +715,14 @@ type QuickTaskResult struct {
 	Completed bool                `json:"completed"` // true if auto-completed (once), false if just created (repeatable/challenge)
 }
 
-func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.QuickTaskReq) (*QuickTaskResult, error) {
+// presetTaskReq fills a task from the difficulty template: fatigue, spirit
+// stones and the attribute bonus of each category. Categories are attribute
+// keys, which also tag the task, or plain tags.
+func presetTaskReq(title string, difficulty int, categories []string) (*types.CreateTaskReq, error) {
 	// Validate difficulty
-	preset, ok := difficultyTable[req.Difficulty]
+	preset, ok := difficultyTable[difficulty]
 	if !ok {
-		return nil, fmt.Errorf("invalid difficulty: %d (must be 0-5)", req.Difficulty)
+		return nil, fmt.Errorf("invalid difficulty: %d (must be 0-5)", difficulty)
 	}
 
 	// Validate categories (can be either attribute keys or tags)
@@ -504,7 +730,7 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 		"physique": true, "willpower": true, "intelligence": true,
 		"perception": true, "charisma": true, "agility": true,
 	}
-	
+
 	// Attribute-to-default-tag mapping
 	attrToTag := map[string]string{
 		"physique":     "运动",
@@ -514,12 +740,12 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 		"charisma":     "社交",
 		"agility":      "灵活",
 	}
-	
+
 	// Deduplicate categories and collect both attrs and tags
 	seen := make(map[string]bool)
 	categoryTags := []string{}
-	
-	for _, cat := range req.Categories {
+
+	for _, cat := range categories {
 		// If it's an attribute key, use its default tag
 		if validAttrs[cat] {
 			tag := attrToTag[cat]
@@ -537,24 +763,9 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 		}
 	}
 
-	// Validate task type
-	taskType := req.Type
-	if taskType == "" {
-		taskType = "once"
-	}
-	if taskType != "once" && taskType != "repeatable" && taskType != "challenge" {
-		return nil, fmt.Errorf("invalid type: %s (must be once, repeatable, or challenge)", taskType)
-	}
-
-	// Challenge requires deadline
-	if taskType == "challenge" && req.Deadline == "" {
-		return nil, fmt.Errorf("challenge tasks require a deadline")
-	}
-
 	// Build title
-	title := req.Title
 	if title == "" {
-		title = fmt.Sprintf("快速任务 (★%d)", req.Difficulty)
+		title = fmt.Sprintf("快速任务 (★%d)", difficulty)
 	}
 
 	// Build attribute rewards from categories
@@ -577,11 +788,6 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 		}
 	}
 
-	source := req.Source
-	if source == "" {
-		source = "api"
-	}
-
 	// Create the task
 	categoryStr := ""
 	if len(categoryTags) > 0 {
@@ -590,12 +796,11 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 			categoryStr += "," + categoryTags[i]
 		}
 	}
-	
+
 	createReq := &types.CreateTaskReq{
 		Title:              title,
 		Category:           categoryStr,
-		Type:               taskType,
-		Difficulty:         req.Difficulty,
+		Difficulty:         difficulty,
 		FatigueCost:        preset.Fatigue,
 		RewardSpiritStones: preset.SpiritStones,
 		RewardPhysique:     rewardPhysique,
@@ -604,11 +809,42 @@ func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.
 		RewardPerception:   rewardPerception,
 		RewardCharisma:     rewardCharisma,
 		RewardAgility:      rewardAgility,
-		DailyLimit:         req.DailyLimit,
-		TotalLimit:         req.TotalLimit,
-		Deadline:           req.Deadline,
 	}
 
+	return createReq, nil
+}
+
+func (l *TaskLogic) QuickComplete(ctx context.Context, userID int64, req *types.QuickTaskReq) (*QuickTaskResult, error) {
+	createReq, err := presetTaskReq(req.Title, req.Difficulty, req.Categories)
+	if err != nil {
+		return nil, err
+	}
+	title := createReq.Title
+
+	// Validate task type
+	taskType := req.Type
+	if taskType == "" {
+		taskType = "once"
+	}
+	if taskType != "once" && taskType != "repeatable" && taskType != "challenge" {
+		return nil, fmt.Errorf("invalid type: %s (must be once, repeatable, or challenge)", taskType)
+	}
+
+	// Challenge requires deadline
+	if taskType == "challenge" && req.Deadline == "" {
+		return nil, fmt.Errorf("challenge tasks require a deadline")
+	}
+
+	source := req.Source
+	if source == "" {
+		source = "api"
+	}
+
+	createReq.Type = taskType
+	createReq.DailyLimit = req.DailyLimit
+	createReq.TotalLimit = req.TotalLimit
+	createReq.Deadline = req.Deadline
+
 	taskResp, err := l.CreateTask(ctx, userID, createReq)
 	if err != nil {
 		return nil, fmt.Errorf("create task failed: %w", err)
@@ -701,19 +937,23 @@ func NewTelegramTaskCompleter(svcCtx *svc.ServiceContext) *TelegramTaskCompleter
 	return &TelegramTaskCompleter{svcCtx: svcCtx}
 }
 
-func (t *TelegramTaskCompleter) CompleteTask(userID int64, taskID int64) (expGained int, spiritStonesGained int, realmTitle string, spiritStones int, err error) {
+func (t *TelegramTaskCompleter) CompleteTask(userID int64, taskID int64) (expGained int, spiritStonesGained int, realmTitle string, spiritStones int, drops []string, err error) {
 	logic := NewTaskLogic(t.svcCtx)
 	result, err := logic.CompleteTask(context.Background(), userID, taskID, "telegram")
 	if err != nil {
-		return 0, 0, "", 0, err
+		return 0, 0, "", 0, nil, err
+	}
+
+	for _, d := range result.Drops {
+		drops = append(drops, fmt.Sprintf("%s%s ×%d", d.Icon, d.Name, d.Quantity))
 	}
 
 	task, _ := t.svcCtx.TaskModel.FindByID(taskID)
 	if task != nil {
-		return task.RewardExp, task.RewardSpiritStones, result.Character.Title, result.Character.SpiritStones, nil
+		return task.RewardExp, task.RewardSpiritStones, result.Character.Title, result.Character.SpiritStones, drops, nil
 	}
 
-	return 0, 0, result.Character.Title, result.Character.SpiritStones, nil
+	return 0, 0, result.Character.Title, result.Character.SpiritStones, drops, nil
 }
 
 func (t *TelegramTaskCompleter) DeleteTask(userID int64, taskID int64) error {
@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"life-system-backend/internal/realm"
 	"life-system-backend/internal/svc"
 	"life-system-backend/internal/types"
+	"life-system-backend/pkg/notify"
+	"life-system-backend/pkg/telegram"
 )
 
 type TelegramLogic struct {
@@ -83,3 +89,311 @@ func generateRandomCode(length int) string {
 	}
 	return string(b)
 }
+
+// TelegramTaskManager is an adapter for the /add and /done bot commands
+type TelegramTaskManager struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTelegramTaskManager(svcCtx *svc.ServiceContext) *TelegramTaskManager {
+	return &TelegramTaskManager{svcCtx: svcCtx}
+}
+
+// AddTask creates a once task filled from the difficulty template
+func (t *TelegramTaskManager) AddTask(userID int64, draft telegram.TaskDraft) (string, error) {
+	difficulty := max(draft.Difficulty, 0)
+	req, err := presetTaskReq(draft.Title, difficulty, draftCategories(draft.Tags))
+	if err != nil {
+		return "", err
+	}
+	req.Type = "once"
+
+	var due time.Time
+	if draft.Due != "" {
+		user, err := t.svcCtx.UserModel.FindByID(userID)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return "", fmt.Errorf("user not found")
+		}
+		if due, err = parseDue(draft.Due, time.Now(), notify.UserLocation(user)); err != nil {
+			return "", err
+		}
+		req.Deadline = due.Format(time.RFC3339)
+	}
+
+	task, err := NewTaskLogic(t.svcCtx).CreateTask(context.Background(), userID, req)
+	if err != nil {
+		return "", err
+	}
+
+	message := fmt.Sprintf("✅ 任务「%s」已创建（★%d，%d灵石）", task.Title, difficulty, task.RewardSpiritStones)
+	if !due.IsZero() {
+		message += fmt.Sprintf("\n⏰ 截止：%s", due.Format("2006-01-02 15:04"))
+	}
+	return message + "\n使用 /tasks 查看并完成", nil
+}
+
+// DoneTask completes the active task titled like the draft. Drafts naming no
+// such task, or giving a difficulty or tags, are recorded as a quick task.
+func (t *TelegramTaskManager) DoneTask(userID int64, draft telegram.TaskDraft) (string, error) {
+	if draft.Due != "" {
+		return "", fmt.Errorf("完成任务不需要截止时间")
+	}
+
+	taskLogic := NewTaskLogic(t.svcCtx)
+	if draft.Title != "" && draft.Difficulty < 0 && len(draft.Tags) == 0 {
+		tasks, err := t.svcCtx.TaskModel.FindByUserID(userID, "", "active")
+		if err != nil {
+			return "", err
+		}
+		for _, task := range tasks {
+			if strings.EqualFold(task.Title, draft.Title) {
+				result, err := taskLogic.CompleteTask(context.Background(), userID, task.ID, "telegram")
+				if err != nil {
+					return "", err
+				}
+				return result.Message, nil
+			}
+		}
+	}
+
+	result, err := taskLogic.QuickComplete(context.Background(), userID, &types.QuickTaskReq{
+		Title:      draft.Title,
+		Difficulty: max(draft.Difficulty, 0),
+		Categories: draftCategories(draft.Tags),
+		Source:     "telegram",
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
+
+// draftCategories maps attribute names typed as tags, e.g. "体魄", to their keys
+func draftCategories(tags []string) []string {
+	categories := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		category := tag
+		for key, display := range realm.AttrDisplay {
+			if display.HasRealm && (display.Name == tag || strings.EqualFold(key, tag)) {
+				category = key
+				break
+			}
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// parseDue reads a due typed in Telegram in loc: today, tomorrow, a date
+// (end of that day), a date and time, a time of day (the next one) or a
+// duration from now like 3h or 2d.
+func parseDue(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	local := now.In(loc)
+
+	var due time.Time
+	if day, clock, ok := strings.Cut(s, " "); ok {
+		date, ok := parseDueDay(day, local, loc)
+		if !ok {
+			return time.Time{}, fmt.Errorf("无法识别截止日期 %s", day)
+		}
+		t, err := time.ParseInLocation("15:04", clock, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无法识别截止时间 %s", clock)
+		}
+		due = time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	} else if date, ok := parseDueDay(s, local, loc); ok {
+		due = time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, loc)
+	} else if t, err := time.ParseInLocation("2006-01-02T15:04", s, loc); err == nil {
+		due = t
+	} else if t, err := time.ParseInLocation("15:04", s, loc); err == nil {
+		due = time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		if !due.After(local) {
+			due = due.AddDate(0, 0, 1)
+		}
+	} else if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") && days > 0 {
+		due = local.AddDate(0, 0, days)
+	} else if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		due = local.Add(d)
+	} else {
+		return time.Time{}, fmt.Errorf("无法识别截止时间 %s", s)
+	}
+
+	if !due.After(local) {
+		return time.Time{}, fmt.Errorf("截止时间已过")
+	}
+	return due, nil
+}
+
+// parseDueDay reads today, tomorrow or a YYYY-MM-DD date in loc
+func parseDueDay(s string, local time.Time, loc *time.Location) (time.Time, bool) {
+	switch strings.ToLower(s) {
+	case "today", "今天":
+		return local, true
+	case "tomorrow", "明天":
+		return local.AddDate(0, 0, 1), true
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	return t, err == nil
+}
+
+// TelegramCharacterViewer is an adapter for the /status bot command
+type TelegramCharacterViewer struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTelegramCharacterViewer(svcCtx *svc.ServiceContext) *TelegramCharacterViewer {
+	return &TelegramCharacterViewer{svcCtx: svcCtx}
+}
+
+func (v *TelegramCharacterViewer) CharacterStatus(userID int64) (*telegram.CharacterStatus, error) {
+	character, err := NewCharacterLogic(v.svcCtx).GetCharacter(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &telegram.CharacterStatus{
+		Title:             character.Title,
+		SpiritStones:      character.SpiritStones,
+		SavedSpiritStones: character.SavedSpiritStones,
+		Fatigue:           character.Fatigue,
+		FatigueCap:        character.FatigueCap,
+	}
+	slices.SortStableFunc(character.Attributes, func(a, b types.AttributeResp) int {
+		return slices.Index(realm.AllAttrKeys, a.AttrKey) - slices.Index(realm.AllAttrKeys, b.AttrKey)
+	})
+	for _, attr := range character.Attributes {
+		line := telegram.AttributeStatus{
+			Emoji:      attr.Emoji,
+			Name:       attr.DisplayName,
+			Value:      attr.Value,
+			TodayGain:  attr.TodayGain,
+			Bottleneck: attr.IsBottleneck,
+		}
+		if realm.AttrDisplay[attr.AttrKey].HasRealm {
+			line.Realm = realm.GetFullRealmName(attr.Realm, attr.SubRealm)
+		}
+		status.Attributes = append(status.Attributes, line)
+	}
+	for _, buff := range character.Buffs {
+		name := buff.Name
+		if buff.Stacks > 1 {
+			name += fmt.Sprintf(" ×%d", buff.Stacks)
+		}
+		status.Buffs = append(status.Buffs, name)
+	}
+
+	return status, nil
+}
+
+// TelegramShopService is an adapter for the /shop and /bag bot commands
+type TelegramShopService struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTelegramShopService(svcCtx *svc.ServiceContext) *TelegramShopService {
+	return &TelegramShopService{svcCtx: svcCtx}
+}
+
+func (s *TelegramShopService) ShopItems(userID int64) ([]telegram.ShopEntry, error) {
+	shop, err := NewShopLogic(s.svcCtx).GetShopItems(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]telegram.ShopEntry, 0, len(shop.Items)+len(shop.Bundles))
+	for _, item := range shop.Items {
+		entries = append(entries, telegram.ShopEntry{
+			Kind:          telegram.ShopItem,
+			ID:            item.ID,
+			Name:          item.Name,
+			Icon:          item.Icon,
+			Price:         item.EffectivePrice,
+			OriginalPrice: item.Price,
+			Stock:         item.Stock,
+		})
+	}
+	for _, bundle := range shop.Bundles {
+		entries = append(entries, telegram.ShopEntry{
+			Kind:          telegram.ShopBundle,
+			ID:            bundle.ID,
+			Name:          bundle.Name,
+			Icon:          bundle.Icon,
+			Price:         bundle.Price,
+			OriginalPrice: bundle.OriginalPrice,
+			Stock:         bundle.Stock,
+		})
+	}
+	return entries, nil
+}
+
+func (s *TelegramShopService) Inventory(userID int64) ([]telegram.BagEntry, error) {
+	inventory, err := NewShopLogic(s.svcCtx).GetInventory(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if user != nil {
+		loc = notify.UserLocation(user)
+	}
+
+	entries := make([]telegram.BagEntry, 0, len(inventory.Items))
+	for _, item := range inventory.Items {
+		entry := telegram.BagEntry{
+			ItemID:           item.ItemID,
+			Name:             item.Name,
+			Icon:             item.Icon,
+			Quantity:         item.Quantity,
+			Usable:           item.ItemType == "consumable",
+			SellPrice:        item.SellPrice,
+			Equipped:         item.Equipped,
+			ExpiringQuantity: item.ExpiringQuantity,
+		}
+		if expiresAt, err := time.Parse(time.RFC3339, item.ExpiresAt); err == nil {
+			entry.ExpiresAt = expiresAt.In(loc)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *TelegramShopService) Buy(userID int64, kind string, id int64) (string, error) {
+	req := &types.PurchaseItemReq{Quantity: 1}
+	switch kind {
+	case telegram.ShopItem:
+		req.ItemID = id
+	case telegram.ShopBundle:
+		req.BundleID = id
+	default:
+		return "", fmt.Errorf("未知商品类型 %s", kind)
+	}
+
+	result, err := NewShopLogic(s.svcCtx).PurchaseItem(context.Background(), userID, req)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n剩余灵石：%d", result.Message, result.RemainingSpiritStones), nil
+}
+
+func (s *TelegramShopService) UseItem(userID int64, itemID int64) (string, error) {
+	result, err := NewShopLogic(s.svcCtx).UseItem(context.Background(), userID, &types.UseItemReq{ItemID: itemID, Quantity: 1})
+	if err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
+
+func (s *TelegramShopService) SellItem(userID int64, itemID int64) (string, error) {
+	result, err := NewShopLogic(s.svcCtx).SellItem(context.Background(), userID, &types.SellItemReq{ItemID: itemID, Quantity: 1})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n剩余灵石：%d", result.Message, result.RemainingSpiritStones), nil
+}
@@ -0,0 +1,40 @@
+package logic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"tomorrow", time.Date(2026, 10, 20, 23, 59, 59, 0, loc)},
+		{"2026-10-20", time.Date(2026, 10, 20, 23, 59, 59, 0, loc)},
+		{"2026-10-20 18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, loc)},
+		{"2026-10-20T18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, loc)},
+		{"明天 9:30", time.Date(2026, 10, 20, 9, 30, 0, 0, loc)},
+		{"today 18:00", time.Date(2026, 10, 19, 18, 0, 0, 0, loc)},
+		{"9:00", time.Date(2026, 10, 20, 9, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		got, err := parseDue(tt.in, now, loc)
+		if err != nil {
+			t.Errorf("parseDue(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDue(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"today 9:00", "2026-10-20 25:00", "someday 18:00"} {
+		if _, err := parseDue(in, now, loc); err == nil {
+			t.Errorf("parseDue(%q) accepted, want an error", in)
+		}
+	}
+}
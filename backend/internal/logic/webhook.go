@@ -0,0 +1,273 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/internal/svc"
+	"life-system-backend/internal/types"
+	"life-system-backend/pkg/webhook"
+)
+
+// maxWebhooksPerUser keeps a single user from fanning events out endlessly
+const maxWebhooksPerUser = 10
+
+type WebhookLogic struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewWebhookLogic(svcCtx *svc.ServiceContext) *WebhookLogic {
+	return &WebhookLogic{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *WebhookLogic) ListWebhooks(ctx context.Context, userID int64) (*types.WebhookListResp, error) {
+	hooks, err := l.svcCtx.WebhookModel.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.WebhookListResp{
+		Webhooks: make([]types.WebhookResp, 0, len(hooks)),
+		Events:   make([]types.WebhookEventResp, 0, len(webhook.Events)),
+	}
+	for _, hook := range hooks {
+		resp.Webhooks = append(resp.Webhooks, webhookToResp(hook))
+	}
+	for _, info := range webhook.Events {
+		resp.Events = append(resp.Events, types.WebhookEventResp{
+			Event: string(info.Event),
+			Name:  info.Name,
+		})
+	}
+
+	return resp, nil
+}
+
+func (l *WebhookLogic) CreateWebhook(ctx context.Context, userID int64, req *types.CreateWebhookReq) (*types.WebhookResp, error) {
+	hooks, err := l.svcCtx.WebhookModel.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("最多只能创建 %d 个 Webhook", maxWebhooksPerUser)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	hook := &model.Webhook{
+		UserID:  userID,
+		Name:    strings.TrimSpace(req.Name),
+		URL:     strings.TrimSpace(req.URL),
+		Secret:  secret,
+		Events:  req.Events,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	id, err := l.svcCtx.WebhookModel.Create(hook)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.getWebhook(userID, id)
+}
+
+func (l *WebhookLogic) UpdateWebhook(ctx context.Context, userID, webhookID int64, req *types.UpdateWebhookReq) (*types.WebhookResp, error) {
+	hook, err := l.findWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		hook.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		hook.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Events != nil {
+		hook.Events = req.Events
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if req.RotateSecret {
+		if hook.Secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	if err := l.svcCtx.WebhookModel.Update(hook); err != nil {
+		return nil, err
+	}
+
+	return l.getWebhook(userID, webhookID)
+}
+
+func (l *WebhookLogic) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	if _, err := l.findWebhook(userID, webhookID); err != nil {
+		return err
+	}
+
+	return l.svcCtx.WebhookModel.Delete(webhookID)
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first
+func (l *WebhookLogic) ListDeliveries(ctx context.Context, userID, webhookID int64, req *types.WebhookDeliveryListReq) (*types.WebhookDeliveryListResp, error) {
+	if _, err := l.findWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	deliveries, err := l.svcCtx.WebhookModel.FindDeliveries(webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.WebhookDeliveryListResp{
+		Deliveries: make([]types.WebhookDeliveryResp, 0, len(deliveries)),
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, webhookDeliveryToResp(d))
+	}
+
+	return resp, nil
+}
+
+// TestWebhook sends a ping event right away and returns the logged result.
+// Failed pings are not retried.
+func (l *WebhookLogic) TestWebhook(ctx context.Context, userID, webhookID int64) (*types.WebhookDeliveryResp, error) {
+	hook, err := l.findWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !hook.Enabled {
+		return nil, fmt.Errorf("Webhook 已停用")
+	}
+
+	payload, err := webhook.NewPayload(userID, webhook.EventPing, map[string]interface{}{
+		"webhookId": hook.ID,
+		"message":   "Life System Webhook 测试",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		UserID:    userID,
+		Event:     string(webhook.EventPing),
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	if d.ID, err = l.svcCtx.WebhookModel.Enqueue(d); err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.Webhooks.DeliverOnce(hook, d); err != nil {
+		return nil, err
+	}
+
+	resp := webhookDeliveryToResp(d)
+	return &resp, nil
+}
+
+func (l *WebhookLogic) findWebhook(userID, webhookID int64) (*model.Webhook, error) {
+	hook, err := l.svcCtx.WebhookModel.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || hook.UserID != userID {
+		return nil, fmt.Errorf("Webhook 不存在")
+	}
+	return hook, nil
+}
+
+func (l *WebhookLogic) getWebhook(userID, webhookID int64) (*types.WebhookResp, error) {
+	hook, err := l.findWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	resp := webhookToResp(hook)
+	return &resp, nil
+}
+
+func validateWebhook(hook *model.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Webhook 地址必须是 http(s) 链接")
+	}
+	if len(hook.Events) == 0 {
+		return fmt.Errorf("请至少订阅一个事件")
+	}
+
+	events := make([]string, 0, len(hook.Events))
+	for _, event := range hook.Events {
+		if _, ok := webhook.LookupEvent(webhook.Event(event)); !ok {
+			return fmt.Errorf("未知的事件类型: %s", event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	hook.Events = events
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func webhookToResp(hook *model.Webhook) types.WebhookResp {
+	return types.WebhookResp{
+		ID:        hook.ID,
+		Name:      hook.Name,
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		Events:    hook.Events,
+		Enabled:   hook.Enabled,
+		CreatedAt: hook.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: hook.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func webhookDeliveryToResp(d *model.WebhookDelivery) types.WebhookDeliveryResp {
+	resp := types.WebhookDeliveryResp{
+		ID:           d.ID,
+		Event:        d.Event,
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		Error:        d.Error,
+		Payload:      d.Payload,
+		CreatedAt:    d.CreatedAt.Format(time.RFC3339),
+	}
+	if d.NextAttemptAt.Valid && d.Status == model.WebhookDeliveryPending {
+		resp.NextAttemptAt = d.NextAttemptAt.Time.Local().Format(time.RFC3339)
+	}
+	if d.DeliveredAt.Valid {
+		resp.DeliveredAt = d.DeliveredAt.Time.Local().Format(time.RFC3339)
+	}
+	return resp
+}
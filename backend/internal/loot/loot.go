@@ -0,0 +1,89 @@
+// Package loot rolls item drops for completed tasks.
+package loot
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// Drop is one rolled item.
+type Drop struct {
+	ItemID   int64
+	Quantity int
+}
+
+const (
+	minLuckMultiplier = 0.5
+	maxLuckMultiplier = 3.0
+)
+
+// LuckMultiplier scales drop chances by the luck attribute. Luck 100 (the
+// starting value) is neutral; every further 100 luck adds the base chance again.
+func LuckMultiplier(luck float64) float64 {
+	m := luck / 100
+	if m < minLuckMultiplier {
+		return minLuckMultiplier
+	}
+	if m > maxLuckMultiplier {
+		return maxLuckMultiplier
+	}
+	return m
+}
+
+// NewRand returns the random source for one roll, such as a task completion
+// or a craft. With a non-zero seed the rolls depend only on the seed, the
+// rolling entity's ID and its attempt count, so they are reproducible
+// regardless of what else ran before.
+func NewRand(seed int64, id int64, attempt int) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(seed ^ id<<20 ^ int64(attempt)))
+}
+
+// Matches reports whether entry applies to a task with the given difficulty
+// and comma separated category tags.
+func Matches(entry *model.DropEntry, difficulty int, category string) bool {
+	if entry.Difficulty != -1 && entry.Difficulty != difficulty {
+		return false
+	}
+	if entry.Category == "" {
+		return true
+	}
+	for _, tag := range strings.Split(category, ",") {
+		if strings.TrimSpace(tag) == entry.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// Roll rolls every entry independently and returns the items that dropped.
+func Roll(entries []*model.DropEntry, luck float64, rng *rand.Rand) []Drop {
+	multiplier := LuckMultiplier(luck)
+
+	var drops []Drop
+	for _, e := range entries {
+		chance := e.Chance * multiplier
+		if chance > 100 {
+			chance = 100
+		}
+		if rng.Float64()*100 >= chance {
+			continue
+		}
+
+		quantity := e.MinQuantity
+		if e.MaxQuantity > e.MinQuantity {
+			quantity += rng.Intn(e.MaxQuantity - e.MinQuantity + 1)
+		}
+		if quantity <= 0 {
+			continue
+		}
+		drops = append(drops, Drop{ItemID: e.ItemID, Quantity: quantity})
+	}
+
+	return drops
+}
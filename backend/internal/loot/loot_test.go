@@ -0,0 +1,132 @@
+package loot
+
+import (
+	"reflect"
+	"testing"
+
+	"life-system-backend/internal/model"
+)
+
+const testSeed = 42
+
+// coinFlips returns entries that each drop 1-3 items half the time at neutral luck
+func coinFlips(n int) []*model.DropEntry {
+	entries := make([]*model.DropEntry, n)
+	for i := range entries {
+		entries[i] = &model.DropEntry{ItemID: int64(i + 1), Difficulty: -1, Chance: 50, MinQuantity: 1, MaxQuantity: 3}
+	}
+	return entries
+}
+
+// dropsKey identifies a roll of coinFlips
+func dropsKey(drops []Drop) string {
+	key := make([]byte, 0, len(drops)*2)
+	for _, d := range drops {
+		key = append(key, byte(d.ItemID), byte(d.Quantity))
+	}
+	return string(key)
+}
+
+func TestRollIsReproducibleUnderASeed(t *testing.T) {
+	entries := coinFlips(32)
+
+	for attempt := 0; attempt < 5; attempt++ {
+		first := Roll(entries, 100, NewRand(testSeed, 7, attempt))
+		second := Roll(entries, 100, NewRand(testSeed, 7, attempt))
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("attempt %d rolled %v then %v", attempt, first, second)
+		}
+	}
+}
+
+func TestRollDependsOnlyOnSeedIDAndAttempt(t *testing.T) {
+	entries := coinFlips(32)
+	want := Roll(entries, 100, NewRand(testSeed, 7, 1))
+
+	// Rolls for other entities in between must not shift this one
+	for id := int64(1); id < 5; id++ {
+		Roll(entries, 100, NewRand(testSeed, id, 1))
+	}
+	if got := Roll(entries, 100, NewRand(testSeed, 7, 1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("rolled %v after other rolls, want %v", got, want)
+	}
+}
+
+func TestRollDiffersPerAttempt(t *testing.T) {
+	entries := coinFlips(32)
+
+	seen := map[string]bool{}
+	for attempt := 0; attempt < 5; attempt++ {
+		drops := Roll(entries, 100, NewRand(testSeed, 7, attempt))
+		seen[dropsKey(drops)] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("5 attempts gave %d distinct rolls, want each attempt rolled independently", len(seen))
+	}
+
+	other := Roll(entries, 100, NewRand(testSeed, 8, 0))
+	if reflect.DeepEqual(other, Roll(entries, 100, NewRand(testSeed, 7, 0))) {
+		t.Error("different IDs rolled the same drops")
+	}
+}
+
+func TestRollChanceBounds(t *testing.T) {
+	entries := []*model.DropEntry{
+		{ItemID: 1, Chance: 0, MinQuantity: 1, MaxQuantity: 1},
+		{ItemID: 2, Chance: 100, MinQuantity: 2, MaxQuantity: 2},
+		{ItemID: 3, Chance: 100, MinQuantity: 0, MaxQuantity: 0},
+	}
+
+	for attempt := 0; attempt < 20; attempt++ {
+		drops := Roll(entries, 300, NewRand(testSeed, 1, attempt))
+		want := []Drop{{ItemID: 2, Quantity: 2}}
+		if !reflect.DeepEqual(drops, want) {
+			t.Fatalf("attempt %d rolled %v, want %v", attempt, drops, want)
+		}
+	}
+}
+
+func TestLuckMultiplier(t *testing.T) {
+	tests := []struct {
+		luck float64
+		want float64
+	}{
+		{-50, minLuckMultiplier},
+		{0, minLuckMultiplier},
+		{49, minLuckMultiplier},
+		{50, 0.5},
+		{100, 1},
+		{250, 2.5},
+		{300, 3},
+		{301, maxLuckMultiplier},
+		{10000, maxLuckMultiplier},
+	}
+
+	for _, tt := range tests {
+		if got := LuckMultiplier(tt.luck); got != tt.want {
+			t.Errorf("LuckMultiplier(%v) = %v, want %v", tt.luck, got, tt.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		entry      model.DropEntry
+		difficulty int
+		category   string
+		want       bool
+	}{
+		{"any", model.DropEntry{Difficulty: -1}, 3, "physique", true},
+		{"difficulty", model.DropEntry{Difficulty: 3}, 3, "", true},
+		{"other difficulty", model.DropEntry{Difficulty: 2}, 3, "", false},
+		{"tag", model.DropEntry{Difficulty: -1, Category: "physique"}, 1, "willpower, physique", true},
+		{"other tag", model.DropEntry{Difficulty: -1, Category: "physique"}, 1, "willpower", false},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(&tt.entry, tt.difficulty, tt.category); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
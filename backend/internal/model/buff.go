@@ -0,0 +1,158 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Buff modifiers. Value is a signed percentage; negative values are debuffs.
+const (
+	BuffModifierSpiritStones = "spirit_stones" // Task spirit stone reward
+	BuffModifierAttrGain     = "attr_gain"     // Task attribute gain, Target is the attribute key
+	BuffModifierFatigueCost  = "fatigue_cost"  // Task fatigue cost
+)
+
+// Buff stacking rules, applied when a buff with the same modifier and target
+// is already active.
+const (
+	BuffStackingRefresh = "refresh" // Replace value, duration and uses
+	BuffStackingExtend  = "extend"  // Keep value, add duration and uses
+	BuffStackingStack   = "stack"   // Add a stack (value × stacks), refresh duration and uses
+)
+
+// CharacterBuff is a temporary modifier on task rewards. It ends at ExpiresAt,
+// after RemainingUses completed tasks, or whichever comes first when both are set.
+type CharacterBuff struct {
+	ID            int64
+	UserID        int64
+	Name          string
+	Modifier      string
+	Target        string
+	Value         int // Percent per stack
+	Stacks        int
+	Stacking      string
+	RemainingUses int // 0 means not limited by uses
+	ExpiresAt     sql.NullTime
+	SourceType    string
+	SourceID      int64
+	CreatedAt     time.Time
+}
+
+// EffectiveValue is the total percentage across stacks.
+func (b *CharacterBuff) EffectiveValue() int {
+	return b.Value * b.Stacks
+}
+
+// utcNullTime stores times in UTC so expires_at compares correctly as text.
+func utcNullTime(t sql.NullTime) sql.NullTime {
+	if t.Valid {
+		t.Time = t.Time.UTC()
+	}
+	return t
+}
+
+type BuffModel struct {
+	db DBTX
+}
+
+func NewBuffModel(db DBTX) *BuffModel {
+	return &BuffModel{db: db}
+}
+
+const buffColumns = `id, user_id, name, modifier, target, value, stacks, stacking,
+       remaining_uses, expires_at, source_type, source_id, created_at`
+
+func scanBuffs(rows *sql.Rows) ([]*CharacterBuff, error) {
+	var buffs []*CharacterBuff
+	for rows.Next() {
+		var b CharacterBuff
+		err := rows.Scan(
+			&b.ID, &b.UserID, &b.Name, &b.Modifier, &b.Target, &b.Value, &b.Stacks, &b.Stacking,
+			&b.RemainingUses, &b.ExpiresAt, &b.SourceType, &b.SourceID, &b.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		buffs = append(buffs, &b)
+	}
+
+	return buffs, rows.Err()
+}
+
+// FindActiveByUserID returns the buffs that have not expired at now
+func (m *BuffModel) FindActiveByUserID(userID int64, now time.Time) ([]*CharacterBuff, error) {
+	rows, err := m.db.Query(`
+		SELECT `+buffColumns+`
+		FROM character_buffs
+		WHERE user_id = ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY id ASC
+	`, userID, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBuffs(rows)
+}
+
+// FindActive returns the active buff with the given modifier and target, if any
+func (m *BuffModel) FindActive(userID int64, modifier, target string, now time.Time) (*CharacterBuff, error) {
+	rows, err := m.db.Query(`
+		SELECT `+buffColumns+`
+		FROM character_buffs
+		WHERE user_id = ? AND modifier = ? AND target = ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID, modifier, target, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buffs, err := scanBuffs(rows)
+	if err != nil || len(buffs) == 0 {
+		return nil, err
+	}
+	return buffs[0], nil
+}
+
+func (m *BuffModel) Create(buff *CharacterBuff) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO character_buffs (user_id, name, modifier, target, value, stacks, stacking,
+		                             remaining_uses, expires_at, source_type, source_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, buff.UserID, buff.Name, buff.Modifier, buff.Target, buff.Value, buff.Stacks, buff.Stacking,
+		buff.RemainingUses, utcNullTime(buff.ExpiresAt), buff.SourceType, buff.SourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *BuffModel) Update(buff *CharacterBuff) error {
+	_, err := m.db.Exec(`
+		UPDATE character_buffs
+		SET name = ?, value = ?, stacks = ?, stacking = ?, remaining_uses = ?, expires_at = ?
+		WHERE id = ?
+	`, buff.Name, buff.Value, buff.Stacks, buff.Stacking, buff.RemainingUses, utcNullTime(buff.ExpiresAt), buff.ID)
+
+	return err
+}
+
+func (m *BuffModel) Delete(id int64) error {
+	_, err := m.db.Exec(`DELETE FROM character_buffs WHERE id = ?`, id)
+	return err
+}
+
+// DeleteExpired removes every buff that expired at or before now
+func (m *BuffModel) DeleteExpired(now time.Time) (int64, error) {
+	result, err := m.db.Exec(`
+		DELETE FROM character_buffs WHERE expires_at IS NOT NULL AND expires_at <= ?
+	`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
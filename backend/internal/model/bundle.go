@@ -0,0 +1,129 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// BundleItem is one shop item contained in a bundle.
+type BundleItem struct {
+	ItemID   int64 `json:"itemId"`
+	Quantity int   `json:"quantity"`
+}
+
+// ShopBundle sells several shop items together at a combined price.
+// Bundles keep their own stock; the stock of the contained items is untouched.
+type ShopBundle struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	Description string
+	Icon        string
+	Items       []BundleItem
+	Price       int // Spirit stone cost of the whole bundle
+	Stock       int // -1 for unlimited
+	CreatedAt   time.Time
+}
+
+const bundleColumns = `id, user_id, name, description, icon, items, price, stock, created_at`
+
+func scanBundles(rows *sql.Rows) ([]*ShopBundle, error) {
+	var bundles []*ShopBundle
+	for rows.Next() {
+		var b ShopBundle
+		var items string
+		err := rows.Scan(&b.ID, &b.UserID, &b.Name, &b.Description, &b.Icon, &items, &b.Price, &b.Stock, &b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(items), &b.Items); err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, &b)
+	}
+
+	return bundles, rows.Err()
+}
+
+// GetBundlesByUserID returns the bundles of a user that are still in stock
+func (m *ShopModel) GetBundlesByUserID(userID int64) ([]*ShopBundle, error) {
+	rows, err := m.db.Query(`
+		SELECT `+bundleColumns+`
+		FROM shop_bundles
+		WHERE user_id = ? AND stock != 0
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBundles(rows)
+}
+
+func (m *ShopModel) GetBundleByID(id int64) (*ShopBundle, error) {
+	rows, err := m.db.Query(`SELECT `+bundleColumns+` FROM shop_bundles WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bundles, err := scanBundles(rows)
+	if err != nil || len(bundles) == 0 {
+		return nil, err
+	}
+	return bundles[0], nil
+}
+
+func (m *ShopModel) CreateBundle(bundle *ShopBundle) (int64, error) {
+	items, err := json.Marshal(bundle.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := m.db.Exec(`
+		INSERT INTO shop_bundles (user_id, name, description, icon, items, price, stock, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, bundle.UserID, bundle.Name, bundle.Description, bundle.Icon, string(items), bundle.Price, bundle.Stock)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *ShopModel) UpdateBundle(bundle *ShopBundle) error {
+	items, err := json.Marshal(bundle.Items)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
+		UPDATE shop_bundles
+		SET name = ?, description = ?, icon = ?, items = ?, price = ?, stock = ?
+		WHERE id = ? AND user_id = ?
+	`, bundle.Name, bundle.Description, bundle.Icon, string(items), bundle.Price, bundle.Stock, bundle.ID, bundle.UserID)
+
+	return err
+}
+
+func (m *ShopModel) DeleteBundle(id, userID int64) error {
+	_, err := m.db.Exec(`DELETE FROM shop_bundles WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// UpdateBundleStock decrements the stock of a bundle.
+// Returns ErrConflict if the remaining stock is insufficient.
+func (m *ShopModel) UpdateBundleStock(id int64, quantity int) error {
+	result, err := m.db.Exec(`
+		UPDATE shop_bundles
+		SET stock = stock - ?
+		WHERE id = ? AND stock >= ?
+	`, quantity, id, quantity)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
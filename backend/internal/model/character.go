@@ -14,6 +14,8 @@ type CharacterStats struct {
 	Title            string
 	LastActivityDate string
 	LastFatigueReset string
+	NextRewardBonus  int // Percent bonus on the next completed task's spirit stones
+	StreakShields    int // Each shield absorbs one attribute decay
 }
 
 type CharacterAttribute struct {
@@ -30,26 +32,33 @@ type CharacterAttribute struct {
 	LastGainDate     string
 }
 
-type CharacterModel struct {
-	db *sql.DB
-}
-
-func NewCharacterModel(db *sql.DB) *CharacterModel {
-	return &CharacterModel{db: db}
-}
+// statsColumns is the shared column list for character_stats queries.
+const statsColumns = `user_id, spirit_stones, fatigue, fatigue_cap, fatigue_level,
+       overdraft_penalty, title, last_activity_date, last_fatigue_reset,
+       COALESCE(next_reward_bonus, 0), COALESCE(streak_shields, 0)`
 
-func (m *CharacterModel) FindByUserID(userID int64) (*CharacterStats, error) {
+func scanStats(scanner interface{ Scan(...interface{}) error }) (*CharacterStats, error) {
 	var stats CharacterStats
-	err := m.db.QueryRow(`
-		SELECT user_id, spirit_stones, fatigue, fatigue_cap, fatigue_level,
-		       overdraft_penalty, title, last_activity_date, last_fatigue_reset
-		FROM character_stats WHERE user_id = ?
-	`, userID).Scan(
+	err := scanner.Scan(
 		&stats.UserID, &stats.SpiritStones, &stats.Fatigue, &stats.FatigueCap,
 		&stats.FatigueLevel, &stats.OverdraftPenalty, &stats.Title,
 		&stats.LastActivityDate, &stats.LastFatigueReset,
+		&stats.NextRewardBonus, &stats.StreakShields,
 	)
+	return &stats, err
+}
 
+type CharacterModel struct {
+	db DBTX
+}
+
+func NewCharacterModel(db DBTX) *CharacterModel {
+	return &CharacterModel{db: db}
+}
+
+func (m *CharacterModel) FindByUserID(userID int64) (*CharacterStats, error) {
+	row := m.db.QueryRow(`SELECT `+statsColumns+` FROM character_stats WHERE user_id = ?`, userID)
+	stats, err := scanStats(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -57,49 +66,47 @@ func (m *CharacterModel) FindByUserID(userID int64) (*CharacterStats, error) {
 		return nil, err
 	}
 
-	return &stats, nil
+	return stats, nil
 }
 
 func (m *CharacterModel) Create(userID int64) error {
-	tx, err := m.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Insert character_stats row
-	_, err = tx.Exec(`
-		INSERT INTO character_stats (user_id, spirit_stones, fatigue, fatigue_cap, fatigue_level,
-		                             overdraft_penalty, title, last_activity_date, last_fatigue_reset)
-		VALUES (?, 0, 0, 100, 0, 0, '凡人', date('now'), '')
-	`, userID)
-	if err != nil {
-		return err
-	}
-
-	// Insert 7 attribute rows (6 cultivation + luck), all starting at value=100, realm=0
-	attrKeys := []string{"physique", "willpower", "intelligence", "perception", "charisma", "agility", "luck"}
-	for _, key := range attrKeys {
-		_, err = tx.Exec(`
-			INSERT INTO character_attributes (user_id, attr_key, value, realm, sub_realm, realm_exp, is_bottleneck, accumulation_pool)
-			VALUES (?, ?, 100, 0, 0, 0, 0, 0)
-		`, userID, key)
+	return inTx(m.db, func(tx DBTX) error {
+		// Insert character_stats row
+		_, err := tx.Exec(`
+			INSERT INTO character_stats (user_id, spirit_stones, fatigue, fatigue_cap, fatigue_level,
+			                             overdraft_penalty, title, last_activity_date, last_fatigue_reset)
+			VALUES (?, 0, 0, 100, 0, 0, '凡人', date('now'), '')
+		`, userID)
 		if err != nil {
 			return err
 		}
-	}
 
-	return tx.Commit()
+		// Insert 7 attribute rows (6 cultivation + luck), all starting at value=100, realm=0
+		attrKeys := []string{"physique", "willpower", "intelligence", "perception", "charisma", "agility", "luck"}
+		for _, key := range attrKeys {
+			_, err = tx.Exec(`
+				INSERT INTO character_attributes (user_id, attr_key, value, realm, sub_realm, realm_exp, is_bottleneck, accumulation_pool)
+				VALUES (?, ?, 100, 0, 0, 0, 0, 0)
+			`, userID, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
 func (m *CharacterModel) Update(stats *CharacterStats) error {
 	_, err := m.db.Exec(`
 		UPDATE character_stats
 		SET spirit_stones = ?, fatigue = ?, fatigue_cap = ?, fatigue_level = ?,
-		    overdraft_penalty = ?, title = ?, last_activity_date = ?, last_fatigue_reset = ?
+		    overdraft_penalty = ?, title = ?, last_activity_date = ?, last_fatigue_reset = ?,
+		    next_reward_bonus = ?, streak_shields = ?
 		WHERE user_id = ?
 	`, stats.SpiritStones, stats.Fatigue, stats.FatigueCap, stats.FatigueLevel,
 		stats.OverdraftPenalty, stats.Title, stats.LastActivityDate, stats.LastFatigueReset,
+		stats.NextRewardBonus, stats.StreakShields,
 		stats.UserID)
 
 	return err
@@ -148,11 +155,30 @@ func (m *CharacterModel) UpdateAttribute(attr *CharacterAttribute) error {
 	return err
 }
 
+// FindAll returns the stats row of every character
+func (m *CharacterModel) FindAll() ([]*CharacterStats, error) {
+	rows, err := m.db.Query(`SELECT ` + statsColumns + ` FROM character_stats ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var characters []*CharacterStats
+	for rows.Next() {
+		stats, err := scanStats(rows)
+		if err != nil {
+			return nil, err
+		}
+		characters = append(characters, stats)
+	}
+
+	return characters, rows.Err()
+}
+
 // FindInactiveCharacters finds all characters that haven't been active for the specified number of days
 func (m *CharacterModel) FindInactiveCharacters(daysThreshold int) ([]*CharacterStats, error) {
 	rows, err := m.db.Query(`
-		SELECT user_id, spirit_stones, fatigue, fatigue_cap, fatigue_level,
-		       overdraft_penalty, title, last_activity_date, last_fatigue_reset
+		SELECT `+statsColumns+`
 		FROM character_stats
 		WHERE last_activity_date < date('now', '-' || ? || ' days')
 	`, daysThreshold)
@@ -164,16 +190,11 @@ func (m *CharacterModel) FindInactiveCharacters(daysThreshold int) ([]*Character
 
 	var characters []*CharacterStats
 	for rows.Next() {
-		var stats CharacterStats
-		err := rows.Scan(
-			&stats.UserID, &stats.SpiritStones, &stats.Fatigue, &stats.FatigueCap,
-			&stats.FatigueLevel, &stats.OverdraftPenalty, &stats.Title,
-			&stats.LastActivityDate, &stats.LastFatigueReset,
-		)
+		stats, err := scanStats(rows)
 		if err != nil {
 			return nil, err
 		}
-		characters = append(characters, &stats)
+		characters = append(characters, stats)
 	}
 
 	return characters, rows.Err()
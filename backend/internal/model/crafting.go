@@ -0,0 +1,167 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Ingredient is one material consumed by a recipe.
+type Ingredient struct {
+	ItemID   int64 `json:"itemId"`
+	Quantity int   `json:"quantity"`
+}
+
+// Recipe turns inventory materials and spirit stones into another item.
+// Materials and spirit stones are spent whether or not the craft succeeds.
+type Recipe struct {
+	ID              int64
+	UserID          int64
+	Name            string
+	Description     string
+	Ingredients     []Ingredient
+	SpiritStoneCost int
+	OutputItemID    int64
+	OutputQuantity  int
+	SuccessRate     float64 // Percent, 0-100
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// CraftLog records one craft request, which may cover several attempts.
+type CraftLog struct {
+	ID           int64
+	UserID       int64
+	RecipeID     int64
+	RecipeName   string
+	OutputItemID int64
+	OutputName   string
+	Attempts     int
+	Successes    int
+	Quantity     int // Output items produced
+	SpiritStones int // Spirit stones spent
+	CreatedAt    time.Time
+}
+
+type CraftingModel struct {
+	db DBTX
+}
+
+func NewCraftingModel(db DBTX) *CraftingModel {
+	return &CraftingModel{db: db}
+}
+
+const recipeColumns = `id, user_id, name, description, ingredients, spirit_stone_cost, output_item_id,
+       output_quantity, success_rate, created_at, updated_at`
+
+func scanRecipes(rows *sql.Rows) ([]*Recipe, error) {
+	var recipes []*Recipe
+	for rows.Next() {
+		var r Recipe
+		var ingredients string
+		err := rows.Scan(
+			&r.ID, &r.UserID, &r.Name, &r.Description, &ingredients, &r.SpiritStoneCost, &r.OutputItemID,
+			&r.OutputQuantity, &r.SuccessRate, &r.CreatedAt, &r.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(ingredients), &r.Ingredients); err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, &r)
+	}
+
+	return recipes, rows.Err()
+}
+
+func (m *CraftingModel) FindByUserID(userID int64) ([]*Recipe, error) {
+	rows, err := m.db.Query(`
+		SELECT `+recipeColumns+`
+		FROM crafting_recipes
+		WHERE user_id = ?
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRecipes(rows)
+}
+
+func (m *CraftingModel) FindByID(id int64) (*Recipe, error) {
+	rows, err := m.db.Query(`SELECT `+recipeColumns+` FROM crafting_recipes WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes, err := scanRecipes(rows)
+	if err != nil || len(recipes) == 0 {
+		return nil, err
+	}
+	return recipes[0], nil
+}
+
+func (m *CraftingModel) Create(recipe *Recipe) (int64, error) {
+	ingredients, err := json.Marshal(recipe.Ingredients)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := m.db.Exec(`
+		INSERT INTO crafting_recipes (user_id, name, description, ingredients, spirit_stone_cost, output_item_id,
+		                              output_quantity, success_rate, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`, recipe.UserID, recipe.Name, recipe.Description, string(ingredients), recipe.SpiritStoneCost,
+		recipe.OutputItemID, recipe.OutputQuantity, recipe.SuccessRate)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *CraftingModel) Update(recipe *Recipe) error {
+	ingredients, err := json.Marshal(recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
+		UPDATE crafting_recipes
+		SET name = ?, description = ?, ingredients = ?, spirit_stone_cost = ?, output_item_id = ?,
+		    output_quantity = ?, success_rate = ?, updated_at = datetime('now')
+		WHERE id = ? AND user_id = ?
+	`, recipe.Name, recipe.Description, string(ingredients), recipe.SpiritStoneCost, recipe.OutputItemID,
+		recipe.OutputQuantity, recipe.SuccessRate, recipe.ID, recipe.UserID)
+
+	return err
+}
+
+func (m *CraftingModel) Delete(id, userID int64) error {
+	_, err := m.db.Exec(`DELETE FROM crafting_recipes WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// CountAttempts returns how often a user has crafted a recipe
+func (m *CraftingModel) CountAttempts(userID, recipeID int64) (int, error) {
+	var count int
+	err := m.db.QueryRow(`
+		SELECT COALESCE(SUM(attempts), 0) FROM crafting_logs WHERE user_id = ? AND recipe_id = ?
+	`, userID, recipeID).Scan(&count)
+
+	return count, err
+}
+
+func (m *CraftingModel) CreateLog(log *CraftLog) error {
+	_, err := m.db.Exec(`
+		INSERT INTO crafting_logs (user_id, recipe_id, recipe_name, output_item_id, output_name, attempts,
+		                           successes, quantity, spirit_stones, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, log.UserID, log.RecipeID, log.RecipeName, log.OutputItemID, log.OutputName, log.Attempts,
+		log.Successes, log.Quantity, log.SpiritStones)
+
+	return err
+}
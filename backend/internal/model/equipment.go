@@ -0,0 +1,104 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Equipment slots
+const (
+	EquipSlotWeapon   = "weapon"   // 武器
+	EquipSlotRobe     = "robe"     // 法袍
+	EquipSlotArtifact = "artifact" // 法宝
+	EquipSlotTalisman = "talisman" // 护符
+)
+
+// EquipSlots lists the slots in display order.
+var EquipSlots = []string{EquipSlotWeapon, EquipSlotRobe, EquipSlotArtifact, EquipSlotTalisman}
+
+// PassiveModifier is a permanent modifier granted while an item is equipped.
+// Modifier, Target and Value follow the same rules as CharacterBuff.
+type PassiveModifier struct {
+	Modifier string `json:"modifier"`
+	Target   string `json:"target,omitempty"`
+	Value    int    `json:"value"`
+}
+
+// EquippedItem is the item worn in one slot.
+type EquippedItem struct {
+	UserID     int64
+	Slot       string
+	ItemID     int64
+	EquippedAt time.Time
+}
+
+type EquipmentModel struct {
+	db DBTX
+}
+
+func NewEquipmentModel(db DBTX) *EquipmentModel {
+	return &EquipmentModel{db: db}
+}
+
+// FindByUserID returns the equipped items of a user
+func (m *EquipmentModel) FindByUserID(userID int64) ([]*EquippedItem, error) {
+	rows, err := m.db.Query(`
+		SELECT user_id, slot, item_id, equipped_at
+		FROM character_equipment
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*EquippedItem
+	for rows.Next() {
+		var item EquippedItem
+		if err := rows.Scan(&item.UserID, &item.Slot, &item.ItemID, &item.EquippedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// IsEquipped reports whether the item is worn in any slot
+func (m *EquipmentModel) IsEquipped(userID, itemID int64) (bool, error) {
+	var slot string
+	err := m.db.QueryRow(`
+		SELECT slot FROM character_equipment WHERE user_id = ? AND item_id = ?
+	`, userID, itemID).Scan(&slot)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Equip puts the item into slot, replacing whatever was there
+func (m *EquipmentModel) Equip(userID int64, slot string, itemID int64) error {
+	_, err := m.db.Exec(`
+		INSERT INTO character_equipment (user_id, slot, item_id, equipped_at)
+		VALUES (?, ?, ?, datetime('now'))
+		ON CONFLICT(user_id, slot) DO UPDATE SET item_id = excluded.item_id, equipped_at = excluded.equipped_at
+	`, userID, slot, itemID)
+
+	return err
+}
+
+// Unequip empties slot. Returns ErrConflict when the slot was already empty.
+func (m *EquipmentModel) Unequip(userID int64, slot string) error {
+	result, err := m.db.Exec(`
+		DELETE FROM character_equipment WHERE user_id = ? AND slot = ?
+	`, userID, slot)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
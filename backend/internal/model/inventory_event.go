@@ -0,0 +1,156 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Inventory event kinds
+const (
+	InventoryPurchase = "purchase"
+	InventoryUse      = "use"
+	InventorySell     = "sell"
+	InventoryRefund   = "refund"
+	InventoryDrop     = "drop"
+	InventoryCraft    = "craft"
+	InventoryExpire   = "expire"
+	InventoryBreak    = "break"
+)
+
+// Inventory event reference types, in addition to the ledger ones
+const (
+	InventoryRefEvent = "inventory_event"
+	InventoryRefLot   = "inventory_lot"
+)
+
+// InventoryEvent is one append-only change to a user's items.
+type InventoryEvent struct {
+	ID           int64
+	UserID       int64
+	Kind         string
+	ItemID       int64 // 0 for bundle purchases
+	ItemName     string
+	Quantity     int // Signed change, positive when items are gained
+	SpiritStones int // Signed spirit stone change caused by the event
+	RefType      string
+	RefID        int64
+	Refunded     int // Purchases: units refunded so far
+	CreatedAt    time.Time
+}
+
+// InventoryEventFilter narrows FindEvents results. Empty fields are ignored.
+type InventoryEventFilter struct {
+	Kind   string
+	ItemID int64
+	From   string // YYYY-MM-DD, inclusive
+	To     string // YYYY-MM-DD, inclusive
+	Limit  int
+	Offset int
+}
+
+const inventoryEventColumns = `id, user_id, kind, item_id, item_name, quantity, spirit_stones, ref_type, ref_id,
+       refunded, created_at`
+
+func scanInventoryEvents(rows *sql.Rows) ([]*InventoryEvent, error) {
+	var events []*InventoryEvent
+	for rows.Next() {
+		var e InventoryEvent
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Kind, &e.ItemID, &e.ItemName, &e.Quantity, &e.SpiritStones, &e.RefType, &e.RefID,
+			&e.Refunded, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+
+	return events, rows.Err()
+}
+
+// RecordEvent appends an inventory event
+func (m *ShopModel) RecordEvent(event *InventoryEvent) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO inventory_events (user_id, kind, item_id, item_name, quantity, spirit_stones, ref_type, ref_id,
+		                              created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, event.UserID, event.Kind, event.ItemID, event.ItemName, event.Quantity, event.SpiritStones,
+		event.RefType, event.RefID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// FindEvents returns matching events (newest first) and the total match count
+func (m *ShopModel) FindEvents(userID int64, filter InventoryEventFilter) ([]*InventoryEvent, int, error) {
+	where := ` WHERE user_id = ?`
+	args := []interface{}{userID}
+
+	if filter.Kind != "" {
+		where += ` AND kind = ?`
+		args = append(args, filter.Kind)
+	}
+	if filter.ItemID > 0 {
+		where += ` AND item_id = ?`
+		args = append(args, filter.ItemID)
+	}
+	if filter.From != "" {
+		where += ` AND date(created_at) >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where += ` AND date(created_at) <= ?`
+		args = append(args, filter.To)
+	}
+
+	var total int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM inventory_events`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + inventoryEventColumns + ` FROM inventory_events` + where + ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events, err := scanInventoryEvents(rows)
+	return events, total, err
+}
+
+func (m *ShopModel) FindEventByID(id int64) (*InventoryEvent, error) {
+	rows, err := m.db.Query(`SELECT `+inventoryEventColumns+` FROM inventory_events WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanInventoryEvents(rows)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// MarkRefunded adds quantity to the refunded units of a purchase.
+// Returns ErrConflict if that would exceed the purchased quantity.
+func (m *ShopModel) MarkRefunded(id int64, quantity int) error {
+	result, err := m.db.Exec(`
+		UPDATE inventory_events
+		SET refunded = refunded + ?
+		WHERE id = ? AND kind = ? AND refunded + ? <= quantity
+	`, quantity, id, InventoryPurchase, quantity)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// InventoryLot is a batch of expiring units inside an inventory entry. Lots
+// are created when an item with ExpiresAfter is acquired and are consumed
+// soonest-expiring first whenever units leave the bag, so the lots of an
+// entry never hold more than its quantity.
+type InventoryLot struct {
+	ID        int64
+	UserID    int64
+	ItemID    int64
+	Quantity  int
+	ExpiresAt time.Time
+	Notified  bool // Expiry warning sent
+	CreatedAt time.Time
+}
+
+const inventoryLotColumns = `id, user_id, item_id, quantity, expires_at, notified, created_at`
+
+func scanInventoryLots(rows *sql.Rows) ([]*InventoryLot, error) {
+	var lots []*InventoryLot
+	for rows.Next() {
+		var lot InventoryLot
+		err := rows.Scan(&lot.ID, &lot.UserID, &lot.ItemID, &lot.Quantity, &lot.ExpiresAt, &lot.Notified, &lot.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, &lot)
+	}
+
+	return lots, rows.Err()
+}
+
+// addLot records the expiry of newly acquired units when the item expires
+func (m *ShopModel) addLot(userID, itemID int64, quantity int) error {
+	var hours int
+	err := m.db.QueryRow(`SELECT COALESCE(expires_after, 0) FROM shop_items WHERE id = ?`, itemID).Scan(&hours)
+	if err == sql.ErrNoRows || hours <= 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
+		INSERT INTO inventory_lots (user_id, item_id, quantity, expires_at, created_at)
+		VALUES (?, ?, ?, ?, datetime('now'))
+	`, userID, itemID, quantity, time.Now().Add(time.Duration(hours)*time.Hour).UTC())
+
+	return err
+}
+
+// consumeLots takes quantity units out of the lots of an entry, soonest
+// expiring first. Units beyond the lots never expire and need no bookkeeping.
+func (m *ShopModel) consumeLots(userID, itemID int64, quantity int) error {
+	lots, err := m.FindLotsByItem(userID, itemID)
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		if quantity <= 0 {
+			break
+		}
+		take := min(lot.Quantity, quantity)
+		quantity -= take
+
+		if take == lot.Quantity {
+			_, err = m.db.Exec(`DELETE FROM inventory_lots WHERE id = ?`, lot.ID)
+		} else {
+			_, err = m.db.Exec(`UPDATE inventory_lots SET quantity = quantity - ? WHERE id = ?`, take, lot.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindLotsByItem returns the lots of one inventory entry, soonest expiring first
+func (m *ShopModel) FindLotsByItem(userID, itemID int64) ([]*InventoryLot, error) {
+	rows, err := m.db.Query(`
+		SELECT `+inventoryLotColumns+`
+		FROM inventory_lots
+		WHERE user_id = ? AND item_id = ?
+		ORDER BY expires_at ASC, id ASC
+	`, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInventoryLots(rows)
+}
+
+// FindLotsByUserID returns every lot of a user, soonest expiring first
+func (m *ShopModel) FindLotsByUserID(userID int64) ([]*InventoryLot, error) {
+	rows, err := m.db.Query(`
+		SELECT `+inventoryLotColumns+`
+		FROM inventory_lots
+		WHERE user_id = ?
+		ORDER BY expires_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInventoryLots(rows)
+}
+
+// FindLotsExpiringBefore returns the lots of all users that expire at or
+// before t. With unnotifiedOnly, lots that were already warned about are skipped.
+func (m *ShopModel) FindLotsExpiringBefore(t time.Time, unnotifiedOnly bool) ([]*InventoryLot, error) {
+	query := `SELECT ` + inventoryLotColumns + ` FROM inventory_lots WHERE expires_at <= ?`
+	if unnotifiedOnly {
+		query += ` AND notified = 0`
+	}
+	query += ` ORDER BY user_id ASC, expires_at ASC, id ASC`
+
+	rows, err := m.db.Query(query, t.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanInventoryLots(rows)
+}
+
+// DeleteLot drops a lot whose units already left the bag
+func (m *ShopModel) DeleteLot(id int64) error {
+	_, err := m.db.Exec(`DELETE FROM inventory_lots WHERE id = ?`, id)
+	return err
+}
+
+func (m *ShopModel) MarkLotNotified(id int64) error {
+	_, err := m.db.Exec(`UPDATE inventory_lots SET notified = 1 WHERE id = ?`, id)
+	return err
+}
+
+// SetWear stores the durability used up on the unit of an entry in use
+func (m *ShopModel) SetWear(userID, itemID int64, wear int) error {
+	_, err := m.db.Exec(`
+		UPDATE inventory SET wear = ?, updated_at = datetime('now') WHERE user_id = ? AND item_id = ?
+	`, wear, userID, itemID)
+
+	return err
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Ledger reasons
+const (
+	LedgerReasonOpening     = "opening_balance"
+	LedgerReasonTaskReward  = "task_reward"
+	LedgerReasonTaskPenalty = "task_penalty"
+	LedgerReasonPurchase    = "purchase"
+	LedgerReasonSale        = "sale"
+	LedgerReasonItemEffect  = "item_effect"
+	LedgerReasonCrafting    = "crafting"
+	LedgerReasonRefund      = "refund"
+	LedgerReasonSavingsIn   = "savings_deposit"
+	LedgerReasonSavingsOut  = "savings_release"
+)
+
+// Ledger reference types
+const (
+	LedgerRefTask       = "task"
+	LedgerRefShopItem   = "shop_item"
+	LedgerRefBundle     = "shop_bundle"
+	LedgerRefRecipe     = "recipe"
+	LedgerRefRedemption = "redemption"
+	LedgerRefSavings    = "savings_goal"
+)
+
+// LedgerEntry is one append-only spirit stone movement.
+type LedgerEntry struct {
+	ID           int64
+	UserID       int64
+	Amount       int // Signed change
+	BalanceAfter int
+	Reason       string
+	RefType      string
+	RefID        int64
+	CreatedAt    time.Time
+}
+
+// LedgerFilter narrows FindByUserID results. Empty fields are ignored.
+type LedgerFilter struct {
+	Reason  string
+	RefType string
+	From    string // YYYY-MM-DD, inclusive
+	To      string // YYYY-MM-DD, inclusive
+	Limit   int
+	Offset  int
+}
+
+type LedgerModel struct {
+	db DBTX
+}
+
+func NewLedgerModel(db DBTX) *LedgerModel {
+	return &LedgerModel{db: db}
+}
+
+// Append records a ledger entry
+func (m *LedgerModel) Append(entry *LedgerEntry) error {
+	_, err := m.db.Exec(`
+		INSERT INTO spirit_stone_ledger (user_id, amount, balance_after, reason, ref_type, ref_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+	`, entry.UserID, entry.Amount, entry.BalanceAfter, entry.Reason, entry.RefType, entry.RefID)
+
+	return err
+}
+
+// FindByUserID returns matching entries (newest first) and the total match count
+func (m *LedgerModel) FindByUserID(userID int64, filter LedgerFilter) ([]*LedgerEntry, int, error) {
+	where := ` WHERE user_id = ?`
+	args := []interface{}{userID}
+
+	if filter.Reason != "" {
+		where += ` AND reason = ?`
+		args = append(args, filter.Reason)
+	}
+	if filter.RefType != "" {
+		where += ` AND ref_type = ?`
+		args = append(args, filter.RefType)
+	}
+	if filter.From != "" {
+		where += ` AND date(created_at) >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where += ` AND date(created_at) <= ?`
+		args = append(args, filter.To)
+	}
+
+	var total int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM spirit_stone_ledger`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, amount, balance_after, reason, ref_type, ref_id, created_at
+		FROM spirit_stone_ledger` + where + `
+		ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries, err := scanLedgerEntries(rows)
+	return entries, total, err
+}
+
+// FindAllByUserID returns every entry for a user in insertion order
+func (m *LedgerModel) FindAllByUserID(userID int64) ([]*LedgerEntry, error) {
+	rows, err := m.db.Query(`
+		SELECT id, user_id, amount, balance_after, reason, ref_type, ref_id, created_at
+		FROM spirit_stone_ledger
+		WHERE user_id = ?
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLedgerEntries(rows)
+}
+
+func scanLedgerEntries(rows *sql.Rows) ([]*LedgerEntry, error) {
+	var entries []*LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		err := rows.Scan(
+			&entry.ID, &entry.UserID, &entry.Amount, &entry.BalanceAfter,
+			&entry.Reason, &entry.RefType, &entry.RefID, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// SumByMonth totals a user's entries with the given reason per YYYY-MM
+func (m *LedgerModel) SumByMonth(userID int64, reason, from, to string) (map[string]int, error) {
+	where := ` WHERE user_id = ? AND reason = ?`
+	args := []interface{}{userID, reason}
+	if from != "" {
+		where += ` AND date(created_at) >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		where += ` AND date(created_at) <= ?`
+		args = append(args, to)
+	}
+
+	return sumByMonth(m.db, `SELECT strftime('%Y-%m', created_at), SUM(amount) FROM spirit_stone_ledger`+where+
+		` GROUP BY 1`, args...)
+}
+
+// SumFlowsSince totals the spirit stones a user earned and spent since the
+// given time. Opening balances and moves into or out of savings are neither.
+func (m *LedgerModel) SumFlowsSince(userID int64, since time.Time) (earned, spent int, err error) {
+	err = m.db.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN amount > 0 THEN amount END), 0),
+		       COALESCE(-SUM(CASE WHEN amount < 0 THEN amount END), 0)
+		FROM spirit_stone_ledger
+		WHERE user_id = ? AND created_at >= ? AND reason NOT IN (?, ?, ?)
+	`, userID, since.UTC().Format("2006-01-02 15:04:05"),
+		LedgerReasonOpening, LedgerReasonSavingsIn, LedgerReasonSavingsOut).Scan(&earned, &spent)
+
+	return earned, spent, err
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DropEntry is one row of a drop table: an item that may drop when a task
+// of the matching difficulty and category is completed.
+type DropEntry struct {
+	ID          int64
+	UserID      int64
+	Difficulty  int    // -1 matches any difficulty
+	Category    string // Empty matches any category
+	ItemID      int64
+	Chance      float64 // Percent at neutral luck, 0-100
+	MinQuantity int
+	MaxQuantity int
+	CreatedAt   time.Time
+}
+
+// LootDrop records an item that dropped from a completed task.
+type LootDrop struct {
+	ID        int64
+	UserID    int64
+	TaskID    int64
+	ItemID    int64
+	ItemName  string
+	Quantity  int
+	CreatedAt time.Time
+}
+
+type LootModel struct {
+	db DBTX
+}
+
+func NewLootModel(db DBTX) *LootModel {
+	return &LootModel{db: db}
+}
+
+const dropEntryColumns = `id, user_id, difficulty, category, item_id, chance, min_quantity, max_quantity, created_at`
+
+func scanDropEntries(rows *sql.Rows) ([]*DropEntry, error) {
+	var entries []*DropEntry
+	for rows.Next() {
+		var e DropEntry
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Difficulty, &e.Category, &e.ItemID,
+			&e.Chance, &e.MinQuantity, &e.MaxQuantity, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// FindEntriesByUserID returns all drop table entries of a user
+func (m *LootModel) FindEntriesByUserID(userID int64) ([]*DropEntry, error) {
+	rows, err := m.db.Query(`
+		SELECT `+dropEntryColumns+`
+		FROM drop_entries
+		WHERE user_id = ?
+		ORDER BY difficulty ASC, category ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDropEntries(rows)
+}
+
+// FindEntriesForDifficulty returns the entries that apply to a difficulty;
+// category matching is left to the caller since tasks carry several tags.
+func (m *LootModel) FindEntriesForDifficulty(userID int64, difficulty int) ([]*DropEntry, error) {
+	rows, err := m.db.Query(`
+		SELECT `+dropEntryColumns+`
+		FROM drop_entries
+		WHERE user_id = ? AND (difficulty = -1 OR difficulty = ?)
+		ORDER BY id ASC
+	`, userID, difficulty)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDropEntries(rows)
+}
+
+func (m *LootModel) FindEntryByID(id int64) (*DropEntry, error) {
+	rows, err := m.db.Query(`SELECT `+dropEntryColumns+` FROM drop_entries WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries, err := scanDropEntries(rows)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+func (m *LootModel) CreateEntry(entry *DropEntry) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO drop_entries (user_id, difficulty, category, item_id, chance, min_quantity, max_quantity, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, entry.UserID, entry.Difficulty, entry.Category, entry.ItemID, entry.Chance, entry.MinQuantity, entry.MaxQuantity)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *LootModel) UpdateEntry(entry *DropEntry) error {
+	_, err := m.db.Exec(`
+		UPDATE drop_entries
+		SET difficulty = ?, category = ?, item_id = ?, chance = ?, min_quantity = ?, max_quantity = ?
+		WHERE id = ? AND user_id = ?
+	`, entry.Difficulty, entry.Category, entry.ItemID, entry.Chance, entry.MinQuantity, entry.MaxQuantity,
+		entry.ID, entry.UserID)
+
+	return err
+}
+
+func (m *LootModel) DeleteEntry(id, userID int64) error {
+	_, err := m.db.Exec(`DELETE FROM drop_entries WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// RecordDrop stores a dropped item for the timeline
+func (m *LootModel) RecordDrop(drop *LootDrop) error {
+	_, err := m.db.Exec(`
+		INSERT INTO loot_drops (user_id, task_id, item_id, item_name, quantity, created_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))
+	`, drop.UserID, drop.TaskID, drop.ItemID, drop.ItemName, drop.Quantity)
+
+	return err
+}
@@ -18,7 +18,12 @@ func NewDB(path string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// Transactions take the write lock up front (BEGIN IMMEDIATE) so that
+	// read-modify-write sequences inside a unit of work are serialized, and
+	// concurrent writers wait instead of failing with SQLITE_BUSY.
+	dsn := path + "?_txlock=immediate&_pragma=busy_timeout(5000)"
+
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -166,29 +171,300 @@ func Migrate(db *sql.DB) error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS spirit_stone_ledger (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			amount INTEGER NOT NULL,
+			balance_after INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			ref_type TEXT DEFAULT '',
+			ref_id INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS character_buffs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			modifier TEXT NOT NULL,
+			target TEXT DEFAULT '',
+			value INTEGER NOT NULL,
+			stacks INTEGER DEFAULT 1,
+			stacking TEXT DEFAULT 'refresh',
+			remaining_uses INTEGER DEFAULT 0,
+			expires_at DATETIME,
+			source_type TEXT DEFAULT '',
+			source_id INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS character_equipment (
+			user_id INTEGER NOT NULL,
+			slot TEXT NOT NULL,
+			item_id INTEGER NOT NULL,
+			equipped_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(user_id, slot),
+			FOREIGN KEY(user_id) REFERENCES users(id),
+			FOREIGN KEY(item_id) REFERENCES shop_items(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS drop_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			difficulty INTEGER DEFAULT -1,
+			category TEXT DEFAULT '',
+			item_id INTEGER NOT NULL,
+			chance REAL NOT NULL,
+			min_quantity INTEGER DEFAULT 1,
+			max_quantity INTEGER DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id),
+			FOREIGN KEY(item_id) REFERENCES shop_items(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS loot_drops (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			task_id INTEGER NOT NULL,
+			item_id INTEGER NOT NULL,
+			item_name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS crafting_recipes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			ingredients TEXT NOT NULL DEFAULT '[]',
+			spirit_stone_cost INTEGER DEFAULT 0,
+			output_item_id INTEGER NOT NULL,
+			output_quantity INTEGER DEFAULT 1,
+			success_rate REAL DEFAULT 100,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id),
+			FOREIGN KEY(output_item_id) REFERENCES shop_items(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS crafting_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			recipe_id INTEGER NOT NULL,
+			recipe_name TEXT NOT NULL,
+			output_item_id INTEGER NOT NULL,
+			output_name TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			successes INTEGER NOT NULL,
+			quantity INTEGER DEFAULT 0,
+			spirit_stones INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS redemptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			item_id INTEGER NOT NULL,
+			item_name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			total_price INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			approver_id INTEGER DEFAULT 0,
+			note TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS savings_goals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			item_id INTEGER DEFAULT 0,
+			target_amount INTEGER DEFAULT 0,
+			saved_amount INTEGER DEFAULT 0,
+			percent INTEGER NOT NULL,
+			auto_purchase BOOLEAN DEFAULT 0,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			completed_at DATETIME,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS shop_bundles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			icon TEXT DEFAULT '',
+			items TEXT NOT NULL,
+			price INTEGER NOT NULL,
+			stock INTEGER DEFAULT -1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS inventory_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			item_id INTEGER DEFAULT 0,
+			item_name TEXT NOT NULL,
+			quantity INTEGER NOT NULL,
+			spirit_stones INTEGER DEFAULT 0,
+			ref_type TEXT DEFAULT '',
+			ref_id INTEGER DEFAULT 0,
+			refunded INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS inventory_lots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			item_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			expires_at DATETIME NOT NULL,
+			notified BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			channels TEXT NOT NULL DEFAULT '[]',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(user_id, kind),
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS notification_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			channels TEXT,
+			delivered TEXT NOT NULL DEFAULT '[]',
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER DEFAULT 0,
+			next_attempt_at DATETIME,
+			error TEXT DEFAULT '',
+			ref_type TEXT DEFAULT '',
+			ref_id INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			sent_at DATETIME,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS attribute_snapshots (
+			user_id INTEGER NOT NULL,
+			attr_key TEXT NOT NULL,
+			date TEXT NOT NULL,
+			value REAL NOT NULL,
+			realm INTEGER NOT NULL,
+			sub_realm INTEGER NOT NULL,
+			PRIMARY KEY(user_id, date, attr_key),
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT DEFAULT '',
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '[]',
+			enabled INTEGER DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			event TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER DEFAULT 0,
+			next_attempt_at DATETIME,
+			response_code INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			delivered_at DATETIME,
+			FOREIGN KEY(webhook_id) REFERENCES webhooks(id),
+			FOREIGN KEY(user_id) REFERENCES users(id)
+		)`,
 	}
 
-	// Add sell_price column to shop_items if not exists (migration)
+	tableNames := []string{"users", "character_stats", "character_attributes", "tasks", "task_logs", "sleep_records", "shop_items", "inventory", "purchase_history", "spirit_stone_ledger", "character_buffs", "character_equipment", "drop_entries", "loot_drops", "crafting_recipes", "crafting_logs", "redemptions", "savings_goals", "shop_bundles", "inventory_events", "inventory_lots", "notification_preferences", "notification_outbox", "attribute_snapshots", "webhooks", "webhook_deliveries"}
+
+	for i, stmt := range statements {
+		fmt.Printf("  Creating table '%s'...\n", tableNames[i])
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration error on table '%s': %w", tableNames[i], err)
+		}
+		fmt.Printf("  ✅ Table '%s' created\n", tableNames[i])
+	}
+
+	// Add columns introduced after the initial schema (migration).
+	// Runs after table creation so fresh databases get them too.
 	migrations := []string{
 		`ALTER TABLE shop_items ADD COLUMN sell_price INTEGER DEFAULT 0`,
 		`ALTER TABLE tasks ADD COLUMN sort_order INTEGER DEFAULT 0`,
 		`ALTER TABLE character_attributes ADD COLUMN today_gain REAL DEFAULT 0`,
 		`ALTER TABLE character_attributes ADD COLUMN last_gain_date TEXT DEFAULT ''`,
+		`ALTER TABLE character_stats ADD COLUMN next_reward_bonus INTEGER DEFAULT 0`,
+		`ALTER TABLE character_stats ADD COLUMN streak_shields INTEGER DEFAULT 0`,
+		`ALTER TABLE shop_items ADD COLUMN effects TEXT DEFAULT ''`,
+		`ALTER TABLE shop_items ADD COLUMN slot TEXT DEFAULT ''`,
+		`ALTER TABLE shop_items ADD COLUMN passives TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN partner_id INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN partner_accepted INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN bark_server TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN bark_crypt_key TEXT DEFAULT ''`,
+		`ALTER TABLE shop_items ADD COLUMN restock_period TEXT DEFAULT ''`,
+		`ALTER TABLE shop_items ADD COLUMN restock_amount INTEGER DEFAULT 0`,
+		`ALTER TABLE shop_items ADD COLUMN restock_max INTEGER DEFAULT 0`,
+		`ALTER TABLE shop_items ADD COLUMN restocked_at DATETIME`,
+		`ALTER TABLE shop_items ADD COLUMN discount_percent INTEGER DEFAULT 0`,
+		`ALTER TABLE shop_items ADD COLUMN discount_start DATETIME`,
+		`ALTER TABLE shop_items ADD COLUMN discount_end DATETIME`,
+		`ALTER TABLE shop_items ADD COLUMN expires_after INTEGER DEFAULT 0`,
+		`ALTER TABLE shop_items ADD COLUMN durability INTEGER DEFAULT 0`,
+		`ALTER TABLE inventory ADD COLUMN wear INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN email TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN email_verified INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN email_unsubscribed INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN email_verify_code TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN email_verify_expire DATETIME`,
+		`ALTER TABLE users ADD COLUMN ntfy_topic_url TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN ntfy_token TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN gotify_server TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN gotify_token TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN timezone TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN quiet_start TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN quiet_end TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN digest_daily INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN digest_weekly INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN digest_time TEXT DEFAULT '08:00'`,
+		`ALTER TABLE users ADD COLUMN digest_weekday INTEGER DEFAULT 1`,
+		`ALTER TABLE users ADD COLUMN last_daily_digest TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN last_weekly_digest TEXT DEFAULT ''`,
+		`ALTER TABLE notification_preferences ADD COLUMN level TEXT DEFAULT ''`,
+		`ALTER TABLE notification_preferences ADD COLUMN default_channels INTEGER DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_ledger_user ON spirit_stone_ledger(user_id, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_buffs_user ON character_buffs(user_id, modifier, target)`,
+		`CREATE INDEX IF NOT EXISTS idx_inventory_events_user ON inventory_events(user_id, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_inventory_lots_item ON inventory_lots(user_id, item_id, expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_user ON webhook_deliveries(user_id, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(status, next_attempt_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_outbox_user ON notification_outbox(user_id, id)`,
+		// Purchases recorded before inventory events existed
+		`INSERT INTO inventory_events (user_id, kind, item_id, item_name, quantity, spirit_stones, created_at)
+		 SELECT user_id, 'purchase', item_id, item_name, quantity, -total_price, created_at FROM purchase_history
+		 WHERE NOT EXISTS (SELECT 1 FROM inventory_events)`,
+		// Seed the ledger with balances that predate it
+		`INSERT INTO spirit_stone_ledger (user_id, amount, balance_after, reason)
+		 SELECT user_id, spirit_stones, spirit_stones, 'opening_balance' FROM character_stats
+		 WHERE spirit_stones != 0 AND user_id NOT IN (SELECT DISTINCT user_id FROM spirit_stone_ledger)`,
 	}
 	for _, m := range migrations {
 		db.Exec(m) // Ignore errors (column may already exist)
 	}
 
-	tableNames := []string{"users", "character_stats", "character_attributes", "tasks", "task_logs", "sleep_records", "shop_items", "inventory", "purchase_history"}
-
-	for i, stmt := range statements {
-		fmt.Printf("  Creating table '%s'...\n", tableNames[i])
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("migration error on table '%s': %w", tableNames[i], err)
-		}
-		fmt.Printf("  ✅ Table '%s' created\n", tableNames[i])
-	}
-
 	// Verify tables were created
 	fmt.Println("🔍 Verifying tables...")
 	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' ORDER BY name")
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// NotificationPreference overrides how one notification kind is delivered.
+// Kinds without a row use the defaults of the kind.
+type NotificationPreference struct {
+	UserID   int64
+	Kind     string
+	Channels []string // Nil keeps the default channels, empty mutes the kind
+	Level    string   // Urgency override, empty keeps the kind's own
+}
+
+// Outbox statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"  // Gave up after retrying
+	OutboxSkipped = "skipped" // No channel delivers the kind any more
+)
+
+// OutboxItem is one notification to one user, kept from the moment it is
+// produced until every channel delivered it or retrying gave up.
+type OutboxItem struct {
+	ID            int64
+	UserID        int64
+	Kind          string
+	Payload       string   // JSON of the notification
+	Channels      []string // Channels still to deliver to; nil until the first attempt resolves them
+	Delivered     []string // Channels that delivered it
+	Status        string
+	Attempts      int
+	NextAttemptAt sql.NullTime
+	Error         string
+	RefType       string // What it is about, e.g. "task"; a newer pending item about the same thing replaces it
+	RefID         int64
+	CreatedAt     time.Time
+	SentAt        sql.NullTime
+}
+
+type NotificationModel struct {
+	db DBTX
+}
+
+func NewNotificationModel(db DBTX) *NotificationModel {
+	return &NotificationModel{db: db}
+}
+
+const preferenceColumns = `user_id, kind, channels, COALESCE(default_channels, 0), COALESCE(level, '')`
+
+func scanPreference(scan func(dest ...interface{}) error) (*NotificationPreference, error) {
+	var p NotificationPreference
+	var channels string
+	var defaultChannels bool
+	if err := scan(&p.UserID, &p.Kind, &channels, &defaultChannels, &p.Level); err != nil {
+		return nil, err
+	}
+	if !defaultChannels {
+		if err := json.Unmarshal([]byte(channels), &p.Channels); err != nil {
+			return nil, err
+		}
+		if p.Channels == nil {
+			p.Channels = []string{}
+		}
+	}
+	return &p, nil
+}
+
+// FindPreferences returns every override of a user
+func (m *NotificationModel) FindPreferences(userID int64) ([]*NotificationPreference, error) {
+	rows, err := m.db.Query(`
+		SELECT `+preferenceColumns+` FROM notification_preferences WHERE user_id = ? ORDER BY kind
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []*NotificationPreference
+	for rows.Next() {
+		p, err := scanPreference(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+
+	return prefs, rows.Err()
+}
+
+// FindPreference returns the override of one kind, nil when the defaults apply
+func (m *NotificationModel) FindPreference(userID int64, kind string) (*NotificationPreference, error) {
+	p, err := scanPreference(m.db.QueryRow(`
+		SELECT `+preferenceColumns+` FROM notification_preferences WHERE user_id = ? AND kind = ?
+	`, userID, kind).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// SavePreference creates or replaces the override of one kind
+func (m *NotificationModel) SavePreference(p *NotificationPreference) error {
+	channels := []byte("[]")
+	if p.Channels != nil {
+		var err error
+		if channels, err = json.Marshal(p.Channels); err != nil {
+			return err
+		}
+	}
+
+	_, err := m.db.Exec(`
+		INSERT INTO notification_preferences (user_id, kind, channels, default_channels, level, updated_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))
+		ON CONFLICT(user_id, kind) DO UPDATE SET
+			channels = excluded.channels, default_channels = excluded.default_channels,
+			level = excluded.level, updated_at = excluded.updated_at
+	`, p.UserID, p.Kind, string(channels), p.Channels == nil, p.Level)
+
+	return err
+}
+
+// DeletePreference restores the defaults of one kind
+func (m *NotificationModel) DeletePreference(userID int64, kind string) error {
+	_, err := m.db.Exec(`DELETE FROM notification_preferences WHERE user_id = ? AND kind = ?`, userID, kind)
+	return err
+}
+
+const outboxColumns = `id, user_id, kind, payload, channels, delivered, status, attempts, next_attempt_at,
+       error, ref_type, ref_id, created_at, sent_at`
+
+func scanOutboxItem(scanner interface{ Scan(...interface{}) error }) (*OutboxItem, error) {
+	var item OutboxItem
+	var channels sql.NullString
+	var delivered string
+	err := scanner.Scan(&item.ID, &item.UserID, &item.Kind, &item.Payload, &channels, &delivered,
+		&item.Status, &item.Attempts, &item.NextAttemptAt, &item.Error, &item.RefType, &item.RefID,
+		&item.CreatedAt, &item.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	if channels.Valid {
+		if err := json.Unmarshal([]byte(channels.String), &item.Channels); err != nil {
+			return nil, err
+		}
+		if item.Channels == nil {
+			item.Channels = []string{}
+		}
+	}
+	if err := json.Unmarshal([]byte(delivered), &item.Delivered); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func scanOutboxItems(rows *sql.Rows) ([]*OutboxItem, error) {
+	defer rows.Close()
+
+	var items []*OutboxItem
+	for rows.Next() {
+		item, err := scanOutboxItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Enqueue adds item to the outbox. An item with a reference replaces the
+// payload of a pending item about the same thing that no channel delivered
+// yet, keeping its schedule, instead of queueing a second one.
+func (m *NotificationModel) Enqueue(item *OutboxItem) error {
+	return inTx(m.db, func(tx DBTX) error {
+		if item.RefType != "" {
+			err := tx.QueryRow(`
+				SELECT id FROM notification_outbox
+				WHERE user_id = ? AND kind = ? AND ref_type = ? AND ref_id = ? AND status = ? AND delivered = '[]'
+			`, item.UserID, item.Kind, item.RefType, item.RefID, OutboxPending).Scan(&item.ID)
+			if err == nil {
+				_, err = tx.Exec(`UPDATE notification_outbox SET payload = ? WHERE id = ?`, item.Payload, item.ID)
+				return err
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		if item.Status == "" {
+			item.Status = OutboxPending
+		}
+		result, err := tx.Exec(`
+			INSERT INTO notification_outbox (user_id, kind, payload, status, next_attempt_at, ref_type, ref_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, item.UserID, item.Kind, item.Payload, item.Status, item.NextAttemptAt.Time.UTC(), item.RefType, item.RefID)
+		if err != nil {
+			return err
+		}
+
+		item.ID, err = result.LastInsertId()
+		return err
+	})
+}
+
+// FindDueOutbox returns up to limit pending items due at now, oldest first
+func (m *NotificationModel) FindDueOutbox(now time.Time, limit int) ([]*OutboxItem, error) {
+	rows, err := m.db.Query(`
+		SELECT `+outboxColumns+` FROM notification_outbox
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?
+	`, OutboxPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxItems(rows)
+}
+
+// UpdateOutbox stores the delivery state of item after an attempt
+func (m *NotificationModel) UpdateOutbox(item *OutboxItem) error {
+	var channels interface{}
+	if item.Channels != nil {
+		data, err := json.Marshal(item.Channels)
+		if err != nil {
+			return err
+		}
+		channels = string(data)
+	}
+	if item.Delivered == nil {
+		item.Delivered = []string{}
+	}
+	delivered, err := json.Marshal(item.Delivered)
+	if err != nil {
+		return err
+	}
+	var nextAt, sentAt interface{}
+	if item.NextAttemptAt.Valid {
+		nextAt = item.NextAttemptAt.Time.UTC()
+	}
+	if item.SentAt.Valid {
+		sentAt = item.SentAt.Time.UTC()
+	}
+
+	_, err = m.db.Exec(`
+		UPDATE notification_outbox
+		SET channels = ?, delivered = ?, status = ?, attempts = ?, next_attempt_at = ?, error = ?, sent_at = ?
+		WHERE id = ?
+	`, channels, string(delivered), item.Status, item.Attempts, nextAt, item.Error, sentAt, item.ID)
+
+	return err
+}
+
+// FindOutbox returns a page of a user's items, newest first, and the total
+// count. An empty status matches all.
+func (m *NotificationModel) FindOutbox(userID int64, status string, limit, offset int) ([]*OutboxItem, int, error) {
+	where := ` WHERE user_id = ?`
+	args := []interface{}{userID}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM notification_outbox`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := m.db.Query(`SELECT `+outboxColumns+` FROM notification_outbox`+where+
+		` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, err := scanOutboxItems(rows)
+	return items, total, err
+}
+
+// CountDeferred returns how many notifications of a user wait for the quiet
+// hours to end
+func (m *NotificationModel) CountDeferred(userID int64, now time.Time) (int, error) {
+	var count int
+	err := m.db.QueryRow(`
+		SELECT COUNT(*) FROM notification_outbox
+		WHERE user_id = ? AND status = ? AND attempts = 0 AND next_attempt_at > ?
+	`, userID, OutboxPending, now.UTC()).Scan(&count)
+	return count, err
+}
+
+// ReleaseDeferred makes the deferred notifications of a user due at
+func (m *NotificationModel) ReleaseDeferred(userID int64, at time.Time) error {
+	_, err := m.db.Exec(`
+		UPDATE notification_outbox SET next_attempt_at = ?
+		WHERE user_id = ? AND status = ? AND attempts = 0 AND next_attempt_at > ?
+	`, at.UTC(), userID, OutboxPending, at.UTC())
+	return err
+}
+
+// DeleteOutboxBefore prunes finished items created before t
+func (m *NotificationModel) DeleteOutboxBefore(t time.Time) error {
+	_, err := m.db.Exec(`DELETE FROM notification_outbox WHERE status != ? AND created_at < ?`, OutboxPending, t.UTC())
+	return err
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Redemption statuses. A redemption starts as awaiting_approval when the
+// buyer has a partner, pending otherwise.
+const (
+	RedemptionAwaitingApproval = "awaiting_approval"
+	RedemptionPending          = "pending"
+	RedemptionFulfilled        = "fulfilled"
+	RedemptionCancelled        = "cancelled" // Withdrawn by the buyer, refunded
+	RedemptionRejected         = "rejected"  // Declined by the partner, refunded
+)
+
+// Redemption is a purchased real-world reward waiting to be handed over.
+type Redemption struct {
+	ID         int64
+	UserID     int64
+	ItemID     int64
+	ItemName   string
+	Quantity   int
+	TotalPrice int
+	Status     string
+	ApproverID int64 // Partner who approves and fulfils; 0 when the buyer handles it alone
+	Note       string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime // Set once fulfilled, cancelled or rejected
+}
+
+// RedemptionFilter narrows list results. Empty fields are ignored.
+type RedemptionFilter struct {
+	UserID     int64
+	ApproverID int64
+	Status     string
+}
+
+type RedemptionModel struct {
+	db DBTX
+}
+
+func NewRedemptionModel(db DBTX) *RedemptionModel {
+	return &RedemptionModel{db: db}
+}
+
+const redemptionColumns = `id, user_id, item_id, item_name, quantity, total_price, status, approver_id, note,
+       created_at, resolved_at`
+
+func scanRedemptions(rows *sql.Rows) ([]*Redemption, error) {
+	var redemptions []*Redemption
+	for rows.Next() {
+		var r Redemption
+		err := rows.Scan(
+			&r.ID, &r.UserID, &r.ItemID, &r.ItemName, &r.Quantity, &r.TotalPrice, &r.Status, &r.ApproverID, &r.Note,
+			&r.CreatedAt, &r.ResolvedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, &r)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// Find returns the redemptions matching filter, newest first
+func (m *RedemptionModel) Find(filter RedemptionFilter) ([]*Redemption, error) {
+	where := ` WHERE 1 = 1`
+	var args []interface{}
+
+	if filter.UserID > 0 {
+		where += ` AND user_id = ?`
+		args = append(args, filter.UserID)
+	}
+	if filter.ApproverID > 0 {
+		where += ` AND approver_id = ?`
+		args = append(args, filter.ApproverID)
+	}
+	if filter.Status != "" {
+		where += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+
+	rows, err := m.db.Query(`SELECT `+redemptionColumns+` FROM redemptions`+where+` ORDER BY id DESC LIMIT 200`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRedemptions(rows)
+}
+
+func (m *RedemptionModel) FindByID(id int64) (*Redemption, error) {
+	rows, err := m.db.Query(`SELECT `+redemptionColumns+` FROM redemptions WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions, err := scanRedemptions(rows)
+	if err != nil || len(redemptions) == 0 {
+		return nil, err
+	}
+	return redemptions[0], nil
+}
+
+func (m *RedemptionModel) Create(r *Redemption) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO redemptions (user_id, item_id, item_name, quantity, total_price, status, approver_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, r.UserID, r.ItemID, r.ItemName, r.Quantity, r.TotalPrice, r.Status, r.ApproverID, r.Note)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Transition moves a redemption from one status to another. It returns
+// ErrConflict when the redemption is no longer in the from status.
+func (m *RedemptionModel) Transition(id int64, from, to, note string) error {
+	resolved := to == RedemptionFulfilled || to == RedemptionCancelled || to == RedemptionRejected
+
+	result, err := m.db.Exec(`
+		UPDATE redemptions
+		SET status = ?, note = CASE WHEN ? = '' THEN note ELSE ? END,
+		    resolved_at = CASE WHEN ? THEN datetime('now') ELSE resolved_at END
+		WHERE id = ? AND status = ?
+	`, to, note, note, resolved, id, from)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
+// SumByMonth totals the price of a user's redemptions in a status per
+// YYYY-MM of their creation
+func (m *RedemptionModel) SumByMonth(userID int64, status, from, to string) (map[string]int, error) {
+	where := ` WHERE user_id = ? AND status = ?`
+	args := []interface{}{userID, status}
+	if from != "" {
+		where += ` AND date(created_at) >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		where += ` AND date(created_at) <= ?`
+		args = append(args, to)
+	}
+
+	return sumByMonth(m.db, `SELECT strftime('%Y-%m', created_at), SUM(total_price) FROM redemptions`+where+
+		` GROUP BY 1`, args...)
+}
+
+func sumByMonth(db DBTX, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[string]int)
+	for rows.Next() {
+		var month string
+		var sum int
+		if err := rows.Scan(&month, &sum); err != nil {
+			return nil, err
+		}
+		sums[month] = sum
+	}
+
+	return sums, rows.Err()
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Savings goal statuses
+const (
+	SavingsActive    = "active"    // Still receiving deposits
+	SavingsReached   = "reached"   // Target met; stones stay locked until purchased or released
+	SavingsPurchased = "purchased" // Linked item bought with the saved stones
+	SavingsCancelled = "cancelled" // Saved stones released back to the balance
+)
+
+// SavingsGoal earmarks a share of every task reward. Saved stones are locked:
+// they left the spendable balance through the ledger and only return on release.
+type SavingsGoal struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	ItemID       int64 // Linked shop item; 0 for a free-form goal
+	TargetAmount int   // Free-form goals only; linked goals target the item price
+	SavedAmount  int
+	Percent      int // Share of each task reward deposited
+	AutoPurchase bool
+	Status       string
+	CreatedAt    time.Time
+	CompletedAt  sql.NullTime
+}
+
+type SavingsModel struct {
+	db DBTX
+}
+
+func NewSavingsModel(db DBTX) *SavingsModel {
+	return &SavingsModel{db: db}
+}
+
+const savingsColumns = `id, user_id, name, item_id, target_amount, saved_amount, percent, auto_purchase, status,
+       created_at, completed_at`
+
+func scanSavingsGoals(rows *sql.Rows) ([]*SavingsGoal, error) {
+	var goals []*SavingsGoal
+	for rows.Next() {
+		var g SavingsGoal
+		err := rows.Scan(
+			&g.ID, &g.UserID, &g.Name, &g.ItemID, &g.TargetAmount, &g.SavedAmount, &g.Percent, &g.AutoPurchase, &g.Status,
+			&g.CreatedAt, &g.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, &g)
+	}
+
+	return goals, rows.Err()
+}
+
+// FindByUserID returns every goal of a user, open goals first
+func (m *SavingsModel) FindByUserID(userID int64) ([]*SavingsGoal, error) {
+	rows, err := m.db.Query(`
+		SELECT `+savingsColumns+`
+		FROM savings_goals
+		WHERE user_id = ?
+		ORDER BY CASE status WHEN 'active' THEN 0 WHEN 'reached' THEN 1 ELSE 2 END, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSavingsGoals(rows)
+}
+
+// FindActiveByUserID returns the goals still receiving deposits, oldest first
+func (m *SavingsModel) FindActiveByUserID(userID int64) ([]*SavingsGoal, error) {
+	rows, err := m.db.Query(`
+		SELECT `+savingsColumns+`
+		FROM savings_goals
+		WHERE user_id = ? AND status = 'active'
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSavingsGoals(rows)
+}
+
+func (m *SavingsModel) FindByID(id int64) (*SavingsGoal, error) {
+	rows, err := m.db.Query(`SELECT `+savingsColumns+` FROM savings_goals WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals, err := scanSavingsGoals(rows)
+	if err != nil || len(goals) == 0 {
+		return nil, err
+	}
+	return goals[0], nil
+}
+
+// LockedByUserID sums the stones held by goals that have not been closed
+func (m *SavingsModel) LockedByUserID(userID int64) (int, error) {
+	var locked int
+	err := m.db.QueryRow(`
+		SELECT COALESCE(SUM(saved_amount), 0) FROM savings_goals
+		WHERE user_id = ? AND status IN ('active', 'reached')
+	`, userID).Scan(&locked)
+
+	return locked, err
+}
+
+func (m *SavingsModel) Create(goal *SavingsGoal) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO savings_goals (user_id, name, item_id, target_amount, saved_amount, percent, auto_purchase, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, goal.UserID, goal.Name, goal.ItemID, goal.TargetAmount, goal.SavedAmount, goal.Percent, goal.AutoPurchase, goal.Status)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *SavingsModel) Update(goal *SavingsGoal) error {
+	_, err := m.db.Exec(`
+		UPDATE savings_goals
+		SET name = ?, target_amount = ?, saved_amount = ?, percent = ?, auto_purchase = ?, status = ?,
+		    completed_at = CASE WHEN ? IN ('purchased', 'cancelled') AND completed_at IS NULL THEN datetime('now') ELSE completed_at END
+		WHERE id = ?
+	`, goal.Name, goal.TargetAmount, goal.SavedAmount, goal.Percent, goal.AutoPurchase, goal.Status,
+		goal.Status, goal.ID)
+
+	return err
+}
@@ -2,23 +2,133 @@ package model
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+// EffectSpec is one effect applied when an item is used.
+type EffectSpec struct {
+	Type     string `json:"type"`             // Registered effect key, e.g. attribute_boost
+	Target   string `json:"target,omitempty"` // Effect specific target, e.g. an attribute key
+	Value    int    `json:"value"`
+	Duration int    `json:"duration,omitempty"` // Buff effects: hours until expiry
+	Uses     int    `json:"uses,omitempty"`     // Buff effects: completed tasks until expiry
+	Stacking string `json:"stacking,omitempty"` // Buff effects: see BuffStacking*
+}
+
 type ShopItem struct {
 	ID          int64
 	UserID      int64
 	Name        string
 	Description string
-	Price       int    // Spirit stone cost
-	SellPrice   int    // Spirit stone sell back price (for equipment)
-	ItemType    string // consumable, equipment
-	Effect      string // hp_restore, energy_restore, attribute_boost, etc.
-	EffectValue int    // Amount of effect
-	Icon        string // Emoji or icon identifier
-	Image       string // Image file path
-	Stock       int    // -1 for unlimited
+	Price       int               // Spirit stone cost
+	SellPrice   int               // Spirit stone sell back price (for equipment)
+	ItemType    string            // consumable, equipment
+	Effect      string            // Primary effect key, see internal/effect
+	EffectValue int               // Amount of the primary effect
+	Effects     []EffectSpec      // Composite effects; overrides Effect/EffectValue when set
+	Slot        string            // Equipment slot, see EquipSlots
+	Passives    []PassiveModifier // Applied while equipped
+	Icon        string            // Emoji or icon identifier
+	Image       string            // Image file path
+	Stock       int               // -1 for unlimited
 	CreatedAt   time.Time
+
+	RestockPeriod   string       // Empty, daily or weekly
+	RestockAmount   int          // Units added to a limited stock each period
+	RestockMax      int          // Restocking stops at this stock, 0 for no cap
+	RestockedAt     sql.NullTime // Last restock, the next one happens in a later period
+	DiscountPercent int          // Percent off while the sale window is open
+	DiscountStart   sql.NullTime // Sale start, unbounded when null
+	DiscountEnd     sql.NullTime // Sale end, unbounded when null
+
+	ExpiresAfter int // Hours an acquired unit stays in the bag, 0 for never
+	Durability   int // Equipment: completed tasks a unit lasts while equipped, 0 for unbreakable
+}
+
+const (
+	RestockDaily  = "daily"
+	RestockWeekly = "weekly"
+)
+
+// EffectSpecs returns the effects applied when the item is used.
+func (i *ShopItem) EffectSpecs() []EffectSpec {
+	if len(i.Effects) > 0 {
+		return i.Effects
+	}
+	if i.Effect == "" {
+		return nil
+	}
+	return []EffectSpec{{Type: i.Effect, Value: i.EffectValue}}
+}
+
+// OnSale reports whether the item's discount applies at now.
+func (i *ShopItem) OnSale(now time.Time) bool {
+	if i.DiscountPercent <= 0 {
+		return false
+	}
+	if i.DiscountStart.Valid && now.Before(i.DiscountStart.Time) {
+		return false
+	}
+	if i.DiscountEnd.Valid && !now.Before(i.DiscountEnd.Time) {
+		return false
+	}
+	return true
+}
+
+// EffectivePrice is the unit price charged at now.
+func (i *ShopItem) EffectivePrice(now time.Time) int {
+	if !i.OnSale(now) {
+		return i.Price
+	}
+	return i.Price * (100 - i.DiscountPercent) / 100
+}
+
+// shopItemColumns is the shared column list for shop_items queries.
+const shopItemColumns = `id, user_id, name, description, price, COALESCE(sell_price, 0), item_type, effect, effect_value,
+       COALESCE(effects, ''), COALESCE(slot, ''), COALESCE(passives, ''), icon, image, stock, created_at,
+       COALESCE(restock_period, ''), COALESCE(restock_amount, 0), COALESCE(restock_max, 0), restocked_at,
+       COALESCE(discount_percent, 0), discount_start, discount_end, COALESCE(expires_after, 0), COALESCE(durability, 0)`
+
+func scanShopItem(scanner interface{ Scan(...interface{}) error }) (*ShopItem, error) {
+	var item ShopItem
+	var effects, passives string
+	err := scanner.Scan(
+		&item.ID, &item.UserID, &item.Name, &item.Description, &item.Price, &item.SellPrice, &item.ItemType,
+		&item.Effect, &item.EffectValue, &effects, &item.Slot, &passives, &item.Icon, &item.Image, &item.Stock, &item.CreatedAt,
+		&item.RestockPeriod, &item.RestockAmount, &item.RestockMax, &item.RestockedAt,
+		&item.DiscountPercent, &item.DiscountStart, &item.DiscountEnd, &item.ExpiresAfter, &item.Durability,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if effects != "" {
+		if err := json.Unmarshal([]byte(effects), &item.Effects); err != nil {
+			return nil, err
+		}
+	}
+	if passives != "" {
+		if err := json.Unmarshal([]byte(passives), &item.Passives); err != nil {
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+func encodeEffects(specs []EffectSpec) (string, error) {
+	if len(specs) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(specs)
+	return string(b), err
+}
+
+func encodePassives(passives []PassiveModifier) (string, error) {
+	if len(passives) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(passives)
+	return string(b), err
 }
 
 type InventoryItem struct {
@@ -26,34 +136,25 @@ type InventoryItem struct {
 	UserID    int64
 	ItemID    int64
 	Quantity  int
+	Wear      int // Durability used up on the unit in use
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
-type PurchaseHistory struct {
-	ID         int64
-	UserID     int64
-	ItemID     int64
-	ItemName   string
-	Quantity   int
-	TotalPrice int
-	CreatedAt  time.Time
-}
-
 type ShopModel struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewShopModel(db *sql.DB) *ShopModel {
+func NewShopModel(db DBTX) *ShopModel {
 	return &ShopModel{db: db}
 }
 
 // GetItemsByUserID returns all shop items created by a specific user
 func (m *ShopModel) GetItemsByUserID(userID int64) ([]*ShopItem, error) {
 	rows, err := m.db.Query(`
-		SELECT id, user_id, name, description, price, COALESCE(sell_price, 0), item_type, effect, effect_value, icon, image, stock, created_at
+		SELECT `+shopItemColumns+`
 		FROM shop_items
-		WHERE user_id = ? AND stock != 0
+		WHERE user_id = ? AND (stock != 0 OR COALESCE(restock_period, '') != '')
 		ORDER BY created_at DESC
 	`, userID)
 	if err != nil {
@@ -63,15 +164,11 @@ func (m *ShopModel) GetItemsByUserID(userID int64) ([]*ShopItem, error) {
 
 	var items []*ShopItem
 	for rows.Next() {
-		var item ShopItem
-		err := rows.Scan(
-			&item.ID, &item.UserID, &item.Name, &item.Description, &item.Price, &item.SellPrice, &item.ItemType,
-			&item.Effect, &item.EffectValue, &item.Icon, &item.Image, &item.Stock, &item.CreatedAt,
-		)
+		item, err := scanShopItem(rows)
 		if err != nil {
 			return nil, err
 		}
-		items = append(items, &item)
+		items = append(items, item)
 	}
 
 	return items, rows.Err()
@@ -79,16 +176,8 @@ func (m *ShopModel) GetItemsByUserID(userID int64) ([]*ShopItem, error) {
 
 // GetItemByID returns a specific shop item
 func (m *ShopModel) GetItemByID(id int64) (*ShopItem, error) {
-	var item ShopItem
-	err := m.db.QueryRow(`
-		SELECT id, user_id, name, description, price, COALESCE(sell_price, 0), item_type, effect, effect_value, icon, image, stock, created_at
-		FROM shop_items
-		WHERE id = ?
-	`, id).Scan(
-		&item.ID, &item.UserID, &item.Name, &item.Description, &item.Price, &item.SellPrice, &item.ItemType,
-		&item.Effect, &item.EffectValue, &item.Icon, &item.Image, &item.Stock, &item.CreatedAt,
-	)
-
+	row := m.db.QueryRow(`SELECT `+shopItemColumns+` FROM shop_items WHERE id = ?`, id)
+	item, err := scanShopItem(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -96,16 +185,31 @@ func (m *ShopModel) GetItemByID(id int64) (*ShopItem, error) {
 		return nil, err
 	}
 
-	return &item, nil
+	return item, nil
 }
 
 // CreateItem creates a new shop item
 func (m *ShopModel) CreateItem(item *ShopItem) (int64, error) {
+	effects, err := encodeEffects(item.Effects)
+	if err != nil {
+		return 0, err
+	}
+	passives, err := encodePassives(item.Passives)
+	if err != nil {
+		return 0, err
+	}
+
 	result, err := m.db.Exec(`
-		INSERT INTO shop_items (user_id, name, description, price, sell_price, item_type, effect, effect_value, icon, image, stock, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+		INSERT INTO shop_items (user_id, name, description, price, sell_price, item_type, effect, effect_value, effects,
+		                        slot, passives, icon, image, stock, restock_period, restock_amount, restock_max,
+		                        restocked_at, discount_percent, discount_start, discount_end, expires_after, durability,
+		                        created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
 	`, item.UserID, item.Name, item.Description, item.Price, item.SellPrice, item.ItemType,
-		item.Effect, item.EffectValue, item.Icon, item.Image, item.Stock)
+		item.Effect, item.EffectValue, effects, item.Slot, passives, item.Icon, item.Image, item.Stock,
+		item.RestockPeriod, item.RestockAmount, item.RestockMax, utcNullTime(item.RestockedAt),
+		item.DiscountPercent, utcNullTime(item.DiscountStart), utcNullTime(item.DiscountEnd), item.ExpiresAfter,
+		item.Durability)
 
 	if err != nil {
 		return 0, err
@@ -116,13 +220,27 @@ func (m *ShopModel) CreateItem(item *ShopItem) (int64, error) {
 
 // UpdateItem updates an existing shop item
 func (m *ShopModel) UpdateItem(item *ShopItem) error {
-	_, err := m.db.Exec(`
+	effects, err := encodeEffects(item.Effects)
+	if err != nil {
+		return err
+	}
+	passives, err := encodePassives(item.Passives)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
 		UPDATE shop_items
-		SET name = ?, description = ?, price = ?, sell_price = ?, item_type = ?, effect = ?, effect_value = ?, icon = ?, image = ?, stock = ?
+		SET name = ?, description = ?, price = ?, sell_price = ?, item_type = ?, effect = ?, effect_value = ?, effects = ?,
+		    slot = ?, passives = ?, icon = ?, image = ?, stock = ?, restock_period = ?, restock_amount = ?,
+		    restock_max = ?, restocked_at = ?, discount_percent = ?, discount_start = ?, discount_end = ?,
+		    expires_after = ?, durability = ?
 		WHERE id = ? AND user_id = ?
 	`, item.Name, item.Description, item.Price, item.SellPrice, item.ItemType,
-		item.Effect, item.EffectValue, item.Icon, item.Image, item.Stock,
-		item.ID, item.UserID)
+		item.Effect, item.EffectValue, effects, item.Slot, passives, item.Icon, item.Image, item.Stock,
+		item.RestockPeriod, item.RestockAmount, item.RestockMax, utcNullTime(item.RestockedAt),
+		item.DiscountPercent, utcNullTime(item.DiscountStart), utcNullTime(item.DiscountEnd),
+		item.ExpiresAfter, item.Durability, item.ID, item.UserID)
 
 	return err
 }
@@ -136,21 +254,75 @@ func (m *ShopModel) DeleteItem(id, userID int64) error {
 	return err
 }
 
-// UpdateItemStock updates the stock of an item
+// UpdateItemStock decrements the stock of an item.
+// Returns ErrConflict if the remaining stock is insufficient.
 func (m *ShopModel) UpdateItemStock(id int64, quantity int) error {
-	_, err := m.db.Exec(`
+	result, err := m.db.Exec(`
 		UPDATE shop_items
 		SET stock = stock - ?
 		WHERE id = ? AND stock >= ?
 	`, quantity, id, quantity)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
+// RestoreItemStock puts refunded units back into a limited stock
+func (m *ShopModel) RestoreItemStock(id int64, quantity int) error {
+	_, err := m.db.Exec(`
+		UPDATE shop_items
+		SET stock = stock + ?
+		WHERE id = ? AND stock != -1
+	`, quantity, id)
 
 	return err
 }
 
+// FindRestockable returns every limited item with a restock rule
+func (m *ShopModel) FindRestockable() ([]*ShopItem, error) {
+	rows, err := m.db.Query(`
+		SELECT ` + shopItemColumns + `
+		FROM shop_items
+		WHERE COALESCE(restock_period, '') != '' AND COALESCE(restock_amount, 0) > 0 AND stock != -1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*ShopItem
+	for rows.Next() {
+		item, err := scanShopItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// RestockItem sets the stock of an item for a restock period.
+// Returns ErrConflict if the stock changed since it was read.
+func (m *ShopModel) RestockItem(id int64, from, to int, at time.Time) error {
+	result, err := m.db.Exec(`
+		UPDATE shop_items
+		SET stock = ?, restocked_at = ?
+		WHERE id = ? AND stock = ?
+	`, to, at.UTC(), id, from)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
 // GetUserInventory returns all items in user's inventory
 func (m *ShopModel) GetUserInventory(userID int64) ([]*InventoryItem, error) {
 	rows, err := m.db.Query(`
-		SELECT id, user_id, item_id, quantity, created_at, updated_at
+		SELECT id, user_id, item_id, quantity, COALESCE(wear, 0), created_at, updated_at
 		FROM inventory
 		WHERE user_id = ? AND quantity > 0
 		ORDER BY created_at DESC
@@ -164,7 +336,7 @@ func (m *ShopModel) GetUserInventory(userID int64) ([]*InventoryItem, error) {
 	for rows.Next() {
 		var item InventoryItem
 		err := rows.Scan(
-			&item.ID, &item.UserID, &item.ItemID, &item.Quantity,
+			&item.ID, &item.UserID, &item.ItemID, &item.Quantity, &item.Wear,
 			&item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
@@ -180,11 +352,11 @@ func (m *ShopModel) GetUserInventory(userID int64) ([]*InventoryItem, error) {
 func (m *ShopModel) GetInventoryItemByItemID(userID, itemID int64) (*InventoryItem, error) {
 	var item InventoryItem
 	err := m.db.QueryRow(`
-		SELECT id, user_id, item_id, quantity, created_at, updated_at
+		SELECT id, user_id, item_id, quantity, COALESCE(wear, 0), created_at, updated_at
 		FROM inventory
 		WHERE user_id = ? AND item_id = ?
 	`, userID, itemID).Scan(
-		&item.ID, &item.UserID, &item.ItemID, &item.Quantity,
+		&item.ID, &item.UserID, &item.ItemID, &item.Quantity, &item.Wear,
 		&item.CreatedAt, &item.UpdatedAt,
 	)
 
@@ -220,70 +392,27 @@ func (m *ShopModel) AddToInventory(userID, itemID int64, quantity int) error {
 			VALUES (?, ?, ?, datetime('now'), datetime('now'))
 		`, userID, itemID, quantity)
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	return m.addLot(userID, itemID, quantity)
 }
 
-// RemoveFromInventory removes quantity from user's inventory
+// RemoveFromInventory removes quantity from user's inventory.
+// Returns ErrConflict if the user holds fewer than quantity.
 func (m *ShopModel) RemoveFromInventory(userID, itemID int64, quantity int) error {
-	_, err := m.db.Exec(`
+	result, err := m.db.Exec(`
 		UPDATE inventory
 		SET quantity = quantity - ?, updated_at = datetime('now')
 		WHERE user_id = ? AND item_id = ? AND quantity >= ?
 	`, quantity, userID, itemID, quantity)
-
-	return err
-}
-
-// RecordPurchase records a purchase in history
-func (m *ShopModel) RecordPurchase(userID, itemID int64, itemName string, quantity, totalPrice int) error {
-	_, err := m.db.Exec(`
-		INSERT INTO purchase_history (user_id, item_id, item_name, quantity, total_price, created_at)
-		VALUES (?, ?, ?, ?, ?, datetime('now'))
-	`, userID, itemID, itemName, quantity, totalPrice)
-
-	return err
-}
-
-// GetPurchaseHistory returns user's purchase history
-func (m *ShopModel) GetPurchaseHistory(userID int64, limit int) ([]*PurchaseHistory, error) {
-	query := `
-		SELECT id, user_id, item_id, item_name, quantity, total_price, created_at
-		FROM purchase_history
-		WHERE user_id = ?
-		ORDER BY created_at DESC
-	`
-
-	if limit > 0 {
-		query += ` LIMIT ?`
-	}
-
-	var rows *sql.Rows
-	var err error
-
-	if limit > 0 {
-		rows, err = m.db.Query(query, userID, limit)
-	} else {
-		rows, err = m.db.Query(query, userID)
-	}
-
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	var history []*PurchaseHistory
-	for rows.Next() {
-		var record PurchaseHistory
-		err := rows.Scan(
-			&record.ID, &record.UserID, &record.ItemID, &record.ItemName,
-			&record.Quantity, &record.TotalPrice, &record.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		history = append(history, &record)
+	if err := requireAffected(result); err != nil {
+		return err
 	}
 
-	return history, rows.Err()
+	return m.consumeLots(userID, itemID, quantity)
 }
@@ -17,10 +17,10 @@ type SleepRecord struct {
 }
 
 type SleepModel struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewSleepModel(db *sql.DB) *SleepModel {
+func NewSleepModel(db DBTX) *SleepModel {
 	return &SleepModel{db: db}
 }
 
@@ -0,0 +1,59 @@
+package model
+
+// AttributeSnapshot is the value of one attribute at the start of a day,
+// kept so reports can tell how attributes changed over a period.
+type AttributeSnapshot struct {
+	UserID   int64
+	AttrKey  string
+	Date     string // YYYY-MM-DD
+	Value    float64
+	Realm    int
+	SubRealm int
+}
+
+// SnapshotAttributes records every user's attributes for date. Dates that
+// already have a snapshot are left alone.
+func (m *CharacterModel) SnapshotAttributes(date string) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT OR IGNORE INTO attribute_snapshots (user_id, attr_key, date, value, realm, sub_realm)
+		SELECT user_id, attr_key, ?, value, realm, sub_realm FROM character_attributes
+	`, date)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// FindSnapshotAt returns the user's latest snapshot taken on or before date,
+// or the earliest one when none is that old. Nil without any snapshot.
+func (m *CharacterModel) FindSnapshotAt(userID int64, date string) ([]*AttributeSnapshot, error) {
+	rows, err := m.db.Query(`
+		SELECT user_id, attr_key, date, value, realm, sub_realm FROM attribute_snapshots
+		WHERE user_id = ? AND date = COALESCE(
+			(SELECT MAX(date) FROM attribute_snapshots WHERE user_id = ? AND date <= ?),
+			(SELECT MIN(date) FROM attribute_snapshots WHERE user_id = ?)
+		)
+	`, userID, userID, date, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*AttributeSnapshot
+	for rows.Next() {
+		var s AttributeSnapshot
+		if err := rows.Scan(&s.UserID, &s.AttrKey, &s.Date, &s.Value, &s.Realm, &s.SubRealm); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &s)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// DeleteSnapshotsBefore prunes snapshots older than date
+func (m *CharacterModel) DeleteSnapshotsBefore(date string) error {
+	_, err := m.db.Exec(`DELETE FROM attribute_snapshots WHERE date < ?`, date)
+	return err
+}
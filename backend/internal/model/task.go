@@ -51,10 +51,10 @@ type TaskLog struct {
 }
 
 type TaskModel struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewTaskModel(db *sql.DB) *TaskModel {
+func NewTaskModel(db DBTX) *TaskModel {
 	return &TaskModel{db: db}
 }
 
@@ -239,13 +239,15 @@ type TaskWithUser struct {
 	Username string
 }
 
+// FindTasksNeedingReminder returns active tasks with a deadline and a reminder
+// set. Whether the owner can be reached is left to the notification dispatcher.
 func (m *TaskModel) FindTasksNeedingReminder() ([]*TaskWithUser, error) {
 	rows, err := m.db.Query(`
-		SELECT `+taskColumnsAliased+`,
+		SELECT ` + taskColumnsAliased + `,
 		       u.tg_chat_id, u.username
 		FROM tasks t
 		JOIN users u ON t.user_id = u.id
-		WHERE t.status = 'active' AND t.deadline IS NOT NULL AND t.remind_before > 0 AND u.tg_chat_id > 0
+		WHERE t.status = 'active' AND t.deadline IS NOT NULL AND t.remind_before > 0
 	`)
 
 	if err != nil {
@@ -329,36 +331,26 @@ func (m *TaskModel) ResetDailyCompletionCounts(today string) error {
 
 // ReorderTasks sets sort_order for a list of task IDs belonging to a user.
 func (m *TaskModel) ReorderTasks(userID int64, taskIDs []int64) error {
-	tx, err := m.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.Prepare(`UPDATE tasks SET sort_order = ?, updated_at = datetime('now') WHERE id = ? AND user_id = ?`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	for i, id := range taskIDs {
-		result, err := stmt.Exec(i+1, id, userID)
-		if err != nil {
-			return err
-		}
-		affected, _ := result.RowsAffected()
-		if affected == 0 {
-			return fmt.Errorf("task %d not found or unauthorized", id)
+	return inTx(m.db, func(tx DBTX) error {
+		for i, id := range taskIDs {
+			result, err := tx.Exec(`UPDATE tasks SET sort_order = ?, updated_at = datetime('now') WHERE id = ? AND user_id = ?`, i+1, id, userID)
+			if err != nil {
+				return err
+			}
+			affected, _ := result.RowsAffected()
+			if affected == 0 {
+				return fmt.Errorf("task %d not found or unauthorized", id)
+			}
 		}
-	}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // FindExpiredChallengeTasks finds all active challenge tasks that have passed their deadline
 func (m *TaskModel) FindExpiredChallengeTasks() ([]*Task, error) {
 	rows, err := m.db.Query(`
-		SELECT `+taskColumns+`
+		SELECT ` + taskColumns + `
 		FROM tasks
 		WHERE type = 'challenge'
 		  AND status = 'active'
@@ -382,3 +374,29 @@ func (m *TaskModel) FindExpiredChallengeTasks() ([]*Task, error) {
 
 	return tasks, rows.Err()
 }
+
+// SumRewardsSince totals the listed spirit stone rewards of tasks completed
+// since the given time
+func (m *TaskModel) SumRewardsSince(userID int64, since time.Time) (int, error) {
+	var total int
+	err := m.db.QueryRow(`
+		SELECT COALESCE(SUM(t.reward_spirit_stones), 0)
+		FROM task_logs tl
+		JOIN tasks t ON tl.task_id = t.id
+		WHERE tl.user_id = ? AND tl.action = 'complete' AND tl.created_at >= ?
+	`, userID, since.UTC().Format("2006-01-02 15:04:05")).Scan(&total)
+
+	return total, err
+}
+
+// CountLogsSince counts the user's task log entries with the given action
+// since the given time
+func (m *TaskModel) CountLogsSince(userID int64, action string, since time.Time) (int, error) {
+	var count int
+	err := m.db.QueryRow(`
+		SELECT COUNT(*) FROM task_logs
+		WHERE user_id = ? AND action = ? AND created_at >= ?
+	`, userID, action, since.UTC().Format("2006-01-02 15:04:05")).Scan(&count)
+
+	return count, err
+}
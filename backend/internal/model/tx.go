@@ -0,0 +1,122 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// DBTX is the subset of *sql.DB and *sql.Tx used by the models, so the same
+// model code can run standalone or inside a unit of work.
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// UnitOfWork exposes every model bound to a single transaction.
+type UnitOfWork struct {
+	User       *UserModel
+	Character  *CharacterModel
+	Task       *TaskModel
+	Sleep      *SleepModel
+	Shop       *ShopModel
+	Ledger     *LedgerModel
+	Buff       *BuffModel
+	Equipment  *EquipmentModel
+	Loot       *LootModel
+	Crafting   *CraftingModel
+	Redemption *RedemptionModel
+	Savings    *SavingsModel
+	Webhook    *WebhookModel
+
+	tx DBTX
+}
+
+func newUnitOfWork(tx DBTX) *UnitOfWork {
+	return &UnitOfWork{
+		User:       NewUserModel(tx),
+		Character:  NewCharacterModel(tx),
+		Task:       NewTaskModel(tx),
+		Sleep:      NewSleepModel(tx),
+		Shop:       NewShopModel(tx),
+		Ledger:     NewLedgerModel(tx),
+		Buff:       NewBuffModel(tx),
+		Equipment:  NewEquipmentModel(tx),
+		Loot:       NewLootModel(tx),
+		Crafting:   NewCraftingModel(tx),
+		Redemption: NewRedemptionModel(tx),
+		Savings:    NewSavingsModel(tx),
+		Webhook:    NewWebhookModel(tx),
+		tx:         tx,
+	}
+}
+
+// Savepoint runs fn inside a nested savepoint of a RunInTx unit of work. When fn fails, only its writes
+// are rolled back and the rest of the unit of work carries on.
+func (u *UnitOfWork) Savepoint(fn func() error) error {
+	if _, err := u.tx.Exec(`SAVEPOINT uow`); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := u.tx.Exec(`ROLLBACK TO uow`); rbErr != nil {
+			return fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+		}
+		u.tx.Exec(`RELEASE uow`)
+		return err
+	}
+
+	_, err := u.tx.Exec(`RELEASE uow`)
+	return err
+}
+
+// Models returns every model bound to db outside a transaction, for reads
+// that should not take the write lock.
+func Models(db *sql.DB) *UnitOfWork {
+	return newUnitOfWork(db)
+}
+
+// RunInTx runs fn inside one transaction. The transaction is committed when fn
+// returns nil and rolled back otherwise (including on panic).
+func RunInTx(db *sql.DB, fn func(uow *UnitOfWork) error) error {
+	return inTx(db, func(tx DBTX) error {
+		return fn(newUnitOfWork(tx))
+	})
+}
+
+// inTx begins a transaction when db is a *sql.DB, or reuses the caller's
+// transaction when the model is already bound to one.
+func inTx(db DBTX, fn func(tx DBTX) error) error {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ErrConflict is returned by guarded updates whose WHERE clause no longer
+// matches, e.g. insufficient balance or stock at write time.
+var ErrConflict = errors.New("concurrent modification conflict")
+
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
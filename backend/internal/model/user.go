@@ -6,37 +6,67 @@ import (
 )
 
 type User struct {
-	ID           int64
-	Username     string
-	PasswordHash string
-	DisplayName  string
-	Avatar       string
-	TgChatID     int64
-	TgUsername   string
-	TgBindCode   string
-	TgBindExpire sql.NullTime
-	BarkKey      string // Bark push notification device key
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID                int64
+	Username          string
+	PasswordHash      string
+	DisplayName       string
+	Avatar            string
+	TgChatID          int64
+	TgUsername        string
+	TgBindCode        string
+	TgBindExpire      sql.NullTime
+	BarkKey           string // Bark push notification device key
+	BarkServer        string // Self-hosted Bark server URL, empty for the official one
+	BarkCryptKey      string // AES key for encrypted Bark pushes, empty to push in plain text
+	Email             string
+	EmailVerified     bool
+	EmailUnsubscribed bool   // Opted out of all notification mail
+	NtfyTopicURL      string // e.g. https://ntfy.sh/my-topic
+	NtfyToken         string // Access token for protected topics
+	GotifyServer      string
+	GotifyToken       string // Application token
+	Timezone          string // IANA name, empty for the server's zone
+	QuietStart        string // "HH:MM", empty when quiet hours are off
+	QuietEnd          string
+	DigestDaily       bool   // Morning briefing enabled
+	DigestWeekly      bool   // Weekly review enabled
+	DigestTime        string // "HH:MM" in Timezone
+	DigestWeekday     int    // Day of the weekly review, 0 = Sunday
+	LastDailyDigest   string // Local date of the last briefing
+	LastWeeklyDigest  string // Local date of the last weekly review
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 type UserModel struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewUserModel(db *sql.DB) *UserModel {
+func NewUserModel(db DBTX) *UserModel {
 	return &UserModel{db: db}
 }
 
-func (m *UserModel) FindByUsername(username string) (*User, error) {
+const userColumns = `id, username, password_hash, display_name, avatar, tg_chat_id, tg_username, tg_bind_code,
+       tg_bind_expire, bark_key, COALESCE(bark_server, ''), COALESCE(bark_crypt_key, ''),
+       COALESCE(email, ''), COALESCE(email_verified, 0), COALESCE(email_unsubscribed, 0),
+       COALESCE(ntfy_topic_url, ''), COALESCE(ntfy_token, ''), COALESCE(gotify_server, ''), COALESCE(gotify_token, ''),
+       COALESCE(timezone, ''), COALESCE(quiet_start, ''), COALESCE(quiet_end, ''),
+       COALESCE(digest_daily, 0), COALESCE(digest_weekly, 0), COALESCE(digest_time, '08:00'), COALESCE(digest_weekday, 1),
+       COALESCE(last_daily_digest, ''), COALESCE(last_weekly_digest, ''),
+       created_at, updated_at`
+
+func scanUser(scanner interface{ Scan(...interface{}) error }) (*User, error) {
 	var user User
-	err := m.db.QueryRow(`
-		SELECT id, username, password_hash, display_name, avatar, tg_chat_id, tg_username, tg_bind_code, tg_bind_expire, bark_key, created_at, updated_at
-		FROM users WHERE username = ?
-	`, username).Scan(
+	err := scanner.Scan(
 		&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName, &user.Avatar,
 		&user.TgChatID, &user.TgUsername, &user.TgBindCode, &user.TgBindExpire,
-		&user.BarkKey, &user.CreatedAt, &user.UpdatedAt,
+		&user.BarkKey, &user.BarkServer, &user.BarkCryptKey,
+		&user.Email, &user.EmailVerified, &user.EmailUnsubscribed,
+		&user.NtfyTopicURL, &user.NtfyToken, &user.GotifyServer, &user.GotifyToken,
+		&user.Timezone, &user.QuietStart, &user.QuietEnd,
+		&user.DigestDaily, &user.DigestWeekly, &user.DigestTime, &user.DigestWeekday,
+		&user.LastDailyDigest, &user.LastWeeklyDigest,
+		&user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -49,46 +79,25 @@ func (m *UserModel) FindByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+func (m *UserModel) FindByUsername(username string) (*User, error) {
+	return scanUser(m.db.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users WHERE username = ?
+	`, username))
+}
+
 func (m *UserModel) FindByID(id int64) (*User, error) {
-	var user User
-	err := m.db.QueryRow(`
-		SELECT id, username, password_hash, display_name, avatar, tg_chat_id, tg_username, tg_bind_code, tg_bind_expire, bark_key, created_at, updated_at
+	return scanUser(m.db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users WHERE id = ?
-	`, id).Scan(
-		&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName, &user.Avatar,
-		&user.TgChatID, &user.TgUsername, &user.TgBindCode, &user.TgBindExpire,
-		&user.BarkKey, &user.CreatedAt, &user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	`, id))
 }
 
 func (m *UserModel) FindByTgChatID(chatID int64) (*User, error) {
-	var user User
-	err := m.db.QueryRow(`
-		SELECT id, username, password_hash, display_name, avatar, tg_chat_id, tg_username, tg_bind_code, tg_bind_expire, bark_key, created_at, updated_at
+	return scanUser(m.db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users WHERE tg_chat_id = ?
-	`, chatID).Scan(
-		&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName, &user.Avatar,
-		&user.TgChatID, &user.TgUsername, &user.TgBindCode, &user.TgBindExpire,
-		&user.BarkKey, &user.CreatedAt, &user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	`, chatID))
 }
 
 func (m *UserModel) Create(username, passwordHash string) (int64, error) {
@@ -125,24 +134,10 @@ func (m *UserModel) SetBindCode(userID int64, code string, expire time.Time) err
 }
 
 func (m *UserModel) FindByBindCode(code string) (*User, error) {
-	var user User
-	err := m.db.QueryRow(`
-		SELECT id, username, password_hash, display_name, avatar, tg_chat_id, tg_username, tg_bind_code, tg_bind_expire, bark_key, created_at, updated_at
+	return scanUser(m.db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users WHERE tg_bind_code = ? AND tg_bind_expire > datetime('now')
-	`, code).Scan(
-		&user.ID, &user.Username, &user.PasswordHash, &user.DisplayName, &user.Avatar,
-		&user.TgChatID, &user.TgUsername, &user.TgBindCode, &user.TgBindExpire,
-		&user.BarkKey, &user.CreatedAt, &user.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	return &user, nil
+	`, code))
 }
 
 func (m *UserModel) ClearBindCode(userID int64) error {
@@ -172,20 +167,218 @@ func (m *UserModel) UpdatePassword(userID int64, newPasswordHash string) error {
 	return err
 }
 
-func (m *UserModel) UpdateBarkKey(userID int64, barkKey string) error {
+// UpdateBarkConfig sets the device key together with the server and encryption key
+func (m *UserModel) UpdateBarkConfig(userID int64, barkKey, server, cryptKey string) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET bark_key = ?, bark_server = ?, bark_crypt_key = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, barkKey, server, cryptKey, userID)
+
+	return err
+}
+
+// UpdateNtfyConfig sets the ntfy topic and its access token; empty values clear them
+func (m *UserModel) UpdateNtfyConfig(userID int64, topicURL, token string) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET ntfy_topic_url = ?, ntfy_token = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, topicURL, token, userID)
+
+	return err
+}
+
+// UpdateGotifyConfig sets the Gotify server and application token; empty values clear them
+func (m *UserModel) UpdateGotifyConfig(userID int64, server, token string) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET gotify_server = ?, gotify_token = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, server, token, userID)
+
+	return err
+}
+
+// UpdateQuietHours sets the quiet hours and the timezone they are read in;
+// empty start and end turn quiet hours off
+func (m *UserModel) UpdateQuietHours(userID int64, start, end, timezone string) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET quiet_start = ?, quiet_end = ?, timezone = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, start, end, timezone, userID)
+
+	return err
+}
+
+// UpdateDigestSettings sets which digests the user gets and when
+func (m *UserModel) UpdateDigestSettings(userID int64, daily, weekly bool, clock string, weekday int) error {
 	_, err := m.db.Exec(`
-		UPDATE users SET bark_key = ?, updated_at = datetime('now')
+		UPDATE users SET digest_daily = ?, digest_weekly = ?, digest_time = ?, digest_weekday = ?, updated_at = datetime('now')
 		WHERE id = ?
-	`, barkKey, userID)
+	`, daily, weekly, clock, weekday, userID)
 
 	return err
 }
 
-func (m *UserModel) GetBarkKey(userID int64) (string, error) {
-	var barkKey string
-	err := m.db.QueryRow(`SELECT bark_key FROM users WHERE id = ?`, userID).Scan(&barkKey)
+// MarkDailyDigest records the local date of the last morning briefing
+func (m *UserModel) MarkDailyDigest(userID int64, date string) error {
+	_, err := m.db.Exec(`UPDATE users SET last_daily_digest = ? WHERE id = ?`, date, userID)
+	return err
+}
+
+// MarkWeeklyDigest records the local date of the last weekly review
+func (m *UserModel) MarkWeeklyDigest(userID int64, date string) error {
+	_, err := m.db.Exec(`UPDATE users SET last_weekly_digest = ? WHERE id = ?`, date, userID)
+	return err
+}
+
+// FindDigestSubscribers returns the users with the daily or weekly digest enabled
+func (m *UserModel) FindDigestSubscribers() ([]*User, error) {
+	rows, err := m.db.Query(`
+		SELECT ` + userColumns + `
+		FROM users WHERE digest_daily = 1 OR digest_weekly = 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetEmail stores an unverified address with the code that verifies it
+func (m *UserModel) SetEmail(userID int64, email, code string, expire time.Time) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET email = ?, email_verified = 0, email_verify_code = ?, email_verify_expire = ?,
+		                 updated_at = datetime('now')
+		WHERE id = ?
+	`, email, code, expire.UTC().Format("2006-01-02 15:04:05"), userID)
+
+	return err
+}
+
+// VerifyEmail marks the address verified when code matches and has not
+// expired, and re-subscribes the user. Reports whether the code was accepted.
+func (m *UserModel) VerifyEmail(userID int64, code string) (bool, error) {
+	result, err := m.db.Exec(`
+		UPDATE users SET email_verified = 1, email_unsubscribed = 0, email_verify_code = '',
+		                 email_verify_expire = NULL, updated_at = datetime('now')
+		WHERE id = ? AND email != '' AND email_verify_code = ? AND email_verify_expire > datetime('now')
+	`, userID, code)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// SetEmailUnsubscribed opts the user out of (or back into) all notification mail
+func (m *UserModel) SetEmailUnsubscribed(userID int64, unsubscribed bool) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET email_unsubscribed = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, unsubscribed, userID)
+
+	return err
+}
+
+// ClearEmail removes the address and any pending verification
+func (m *UserModel) ClearEmail(userID int64) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET email = '', email_verified = 0, email_unsubscribed = 0, email_verify_code = '',
+		                 email_verify_expire = NULL, updated_at = datetime('now')
+		WHERE id = ?
+	`, userID)
+
+	return err
+}
+
+// UpdatePartnerID asks partnerID to approve the user's redemptions; 0 clears
+// it. The link stays pending until the partner accepts it.
+func (m *UserModel) UpdatePartnerID(userID, partnerID int64) error {
+	_, err := m.db.Exec(`
+		UPDATE users SET partner_id = ?, partner_accepted = 0, updated_at = datetime('now')
+		WHERE id = ?
+	`, partnerID, userID)
+
+	return err
+}
+
+// GetPartnerID returns the partner who accepted the link, 0 while there is
+// none or it is still pending
+func (m *UserModel) GetPartnerID(userID int64) (int64, error) {
+	partnerID, accepted, err := m.GetPartnerLink(userID)
+	if err != nil || !accepted {
+		return 0, err
+	}
+	return partnerID, nil
+}
+
+// GetPartnerLink returns the partner the user named, accepted or not
+func (m *UserModel) GetPartnerLink(userID int64) (partnerID int64, accepted bool, err error) {
+	err = m.db.QueryRow(`
+		SELECT COALESCE(partner_id, 0), COALESCE(partner_accepted, 0) FROM users WHERE id = ?
+	`, userID).Scan(&partnerID, &accepted)
+	if err != nil {
+		return 0, false, err
+	}
+	return partnerID, accepted, nil
+}
+
+// FindByPartnerID returns the users who named partnerID as their partner,
+// either those who wait for an answer or those already accepted
+func (m *UserModel) FindByPartnerID(partnerID int64, accepted bool) ([]*User, error) {
+	rows, err := m.db.Query(`
+		SELECT `+userColumns+`
+		FROM users WHERE partner_id = ? AND COALESCE(partner_accepted, 0) = ?
+		ORDER BY id
+	`, partnerID, accepted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// AcceptPartner confirms the pending link from userID to partnerID.
+// Returns ErrConflict when there is no such pending link.
+func (m *UserModel) AcceptPartner(userID, partnerID int64) error {
+	result, err := m.db.Exec(`
+		UPDATE users SET partner_accepted = 1, updated_at = datetime('now')
+		WHERE id = ? AND partner_id = ? AND COALESCE(partner_accepted, 0) = 0
+	`, userID, partnerID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeclinePartner removes the link from userID to partnerID, pending or
+// accepted. Returns ErrConflict when there is no such link.
+func (m *UserModel) DeclinePartner(userID, partnerID int64) error {
+	result, err := m.db.Exec(`
+		UPDATE users SET partner_id = 0, partner_accepted = 0, updated_at = datetime('now')
+		WHERE id = ? AND partner_id = ?
+	`, userID, partnerID)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return barkKey, nil
+	return requireAffected(result)
 }
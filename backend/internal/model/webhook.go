@@ -0,0 +1,235 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Webhook is a user's endpoint that receives signed game events.
+type Webhook struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	URL       string
+	Secret    string // Signs the payloads
+	Events    []string
+	Enabled   bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Delivery statuses
+const (
+	WebhookDeliveryPending = "pending" // Waiting for the first attempt or a retry
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed" // Gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for one webhook. Pending rows form the
+// retry queue; all rows together are the delivery log.
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	UserID        int64
+	Event         string
+	Payload       string // JSON body as sent
+	Status        string
+	Attempts      int
+	NextAttemptAt sql.NullTime
+	ResponseCode  int    // HTTP status of the last attempt, 0 when no response
+	Error         string // Error of the last failed attempt
+	CreatedAt     time.Time
+	DeliveredAt   sql.NullTime
+}
+
+type WebhookModel struct {
+	db DBTX
+}
+
+func NewWebhookModel(db DBTX) *WebhookModel {
+	return &WebhookModel{db: db}
+}
+
+const webhookColumns = `id, user_id, name, url, secret, events, enabled, created_at, updated_at`
+
+func scanWebhook(scan func(dest ...interface{}) error) (*Webhook, error) {
+	var w Webhook
+	var events string
+	err := scan(&w.ID, &w.UserID, &w.Name, &w.URL, &w.Secret, &events, &w.Enabled, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (m *WebhookModel) Create(w *Webhook) (int64, error) {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := m.db.Exec(`
+		INSERT INTO webhooks (user_id, name, url, secret, events, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`, w.UserID, w.Name, w.URL, w.Secret, string(events), w.Enabled)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (m *WebhookModel) Update(w *Webhook) error {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
+		UPDATE webhooks SET name = ?, url = ?, secret = ?, events = ?, enabled = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, w.Name, w.URL, w.Secret, string(events), w.Enabled, w.ID)
+
+	return err
+}
+
+// Delete removes a webhook together with its delivery log
+func (m *WebhookModel) Delete(id int64) error {
+	return inTx(m.db, func(tx DBTX) error {
+		if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+		return err
+	})
+}
+
+func (m *WebhookModel) FindByID(id int64) (*Webhook, error) {
+	row := m.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+	w, err := scanWebhook(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+func (m *WebhookModel) FindByUserID(userID int64) ([]*Webhook, error) {
+	rows, err := m.db.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+
+	return hooks, rows.Err()
+}
+
+// FindSubscribed returns the enabled webhooks of a user that receive event
+func (m *WebhookModel) FindSubscribed(userID int64, event string) ([]*Webhook, error) {
+	hooks, err := m.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(hooks, func(w *Webhook) bool {
+		return !w.Enabled || !slices.Contains(w.Events, event)
+	}), nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, user_id, event, payload, status, attempts, next_attempt_at,
+       response_code, error, created_at, delivered_at`
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.ResponseCode, &d.Error, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Enqueue adds a pending delivery due immediately
+func (m *WebhookModel) Enqueue(d *WebhookDelivery) (int64, error) {
+	result, err := m.db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, user_id, event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+	`, d.WebhookID, d.UserID, d.Event, d.Payload, WebhookDeliveryPending, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// FindDue returns pending deliveries whose next attempt is due, oldest first
+func (m *WebhookModel) FindDue(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	rows, err := m.db.Query(`
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, WebhookDeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// FindDeliveries returns the newest deliveries of a webhook
+func (m *WebhookModel) FindDeliveries(webhookID int64, limit int) ([]*WebhookDelivery, error) {
+	rows, err := m.db.Query(`
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// RecordAttempt stores the outcome of one attempt. A zero next time ends the
+// delivery with status; otherwise it stays pending until then.
+func (m *WebhookModel) RecordAttempt(id int64, status string, code int, errMsg string, next time.Time) error {
+	var nextAt, deliveredAt interface{}
+	if !next.IsZero() {
+		nextAt = next.UTC()
+	}
+	if status == WebhookDeliverySuccess {
+		deliveredAt = time.Now().UTC()
+	}
+
+	_, err := m.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, response_code = ?, error = ?, next_attempt_at = ?, delivered_at = ?
+		WHERE id = ?
+	`, status, code, errMsg, nextAt, deliveredAt, id)
+
+	return err
+}
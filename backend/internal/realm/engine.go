@@ -70,9 +70,9 @@ func GetFullRealmName(realmIndex, subRealm int) string {
 
 // AttrGainResult holds the output of ProcessAttrGain.
 type AttrGainResult struct {
-	NewValue       float64
-	NewAccPool     float64
-	NewRealmExp    int
+	NewValue        float64
+	NewAccPool      float64
+	NewRealmExp     int
 	NewIsBottleneck bool
 }
 
@@ -106,3 +106,42 @@ func ProcessAttrGain(currentValue, gain float64, realmIndex, realmExp int, isBot
 		NewIsBottleneck: true,
 	}
 }
+
+// BreakthroughResult holds the output of TryBreakthrough.
+type BreakthroughResult struct {
+	NewRealm        int
+	NewValue        float64
+	NewAccPool      float64
+	NewRealmExp     int
+	NewIsBottleneck bool
+}
+
+// TryBreakthrough advances a bottlenecked attribute to the next realm once its
+// realm exp reaches BreakthroughExpRequired. The new value starts at the next
+// realm's minimum plus whatever sat in the accumulation pool.
+// Returns false when the attribute cannot break through yet.
+func TryBreakthrough(realmIndex, realmExp int, isBottleneck bool, accPool float64) (BreakthroughResult, bool) {
+	if !isBottleneck || realmIndex >= MaxRealm {
+		return BreakthroughResult{}, false
+	}
+	required := BreakthroughExpRequired(realmIndex)
+	if realmExp < required {
+		return BreakthroughResult{}, false
+	}
+
+	newRealm := realmIndex + 1
+	newValue := AttrMin(newRealm) + accPool
+	cap := AttrCap(newRealm)
+	newBottleneck := newValue >= cap
+	if newBottleneck {
+		newValue = cap
+	}
+
+	return BreakthroughResult{
+		NewRealm:        newRealm,
+		NewValue:        newValue,
+		NewAccPool:      0,
+		NewRealmExp:     realmExp - required,
+		NewIsBottleneck: newBottleneck,
+	}, true
+}
@@ -3,43 +3,110 @@ package svc
 import (
 	"database/sql"
 	"life-system-backend/internal/config"
+	"life-system-backend/internal/effect"
 	"life-system-backend/internal/model"
 	"life-system-backend/pkg/bark"
+	"life-system-backend/pkg/email"
+	"life-system-backend/pkg/gotify"
+	"life-system-backend/pkg/notify"
+	"life-system-backend/pkg/ntfy"
 	"life-system-backend/pkg/ratelimit"
 	"life-system-backend/pkg/telegram"
+	"life-system-backend/pkg/webhook"
 )
 
+// DefaultBarkServer is the official Bark server
+const DefaultBarkServer = "https://api.day.app"
+
 type ServiceContext struct {
-	Config         config.Config
-	DB             *sql.DB
-	UserModel      *model.UserModel
-	CharacterModel *model.CharacterModel
-	TaskModel      *model.TaskModel
-	SleepModel     *model.SleepModel
-	ShopModel      *model.ShopModel
-	TelegramBot    *telegram.Bot
-	BarkClient     *bark.Client
-	RateLimiter    *ratelimit.Limiter
+	Config            config.Config
+	DB                *sql.DB
+	UserModel         *model.UserModel
+	CharacterModel    *model.CharacterModel
+	TaskModel         *model.TaskModel
+	SleepModel        *model.SleepModel
+	ShopModel         *model.ShopModel
+	LedgerModel       *model.LedgerModel
+	BuffModel         *model.BuffModel
+	EquipmentModel    *model.EquipmentModel
+	LootModel         *model.LootModel
+	CraftingModel     *model.CraftingModel
+	RedemptionModel   *model.RedemptionModel
+	SavingsModel      *model.SavingsModel
+	NotificationModel *model.NotificationModel
+	WebhookModel      *model.WebhookModel
+	ItemEffects       *effect.Registry
+	TelegramBot       *telegram.Bot
+	BarkClient        *bark.Client
+	EmailSender       *email.Sender // Nil when email is disabled
+	Notifier          *notify.Dispatcher
+	Webhooks          *webhook.Queue
+	RateLimiter       *ratelimit.Limiter
 }
 
 func NewServiceContext(cfg config.Config, db *sql.DB, bot *telegram.Bot) *ServiceContext {
-	// Bark client always initialized with official server
-	barkClient := bark.NewClient("https://api.day.app")
+	// Bark client for users without a self-hosted server
+	barkServer := cfg.Bark.ServerURL
+	if barkServer == "" {
+		barkServer = DefaultBarkServer
+	}
+	barkClient := bark.NewClient(barkServer)
+
+	// Notifications go to Bark, ntfy, Gotify and, when enabled, Telegram and email
+	userModel := model.NewUserModel(db)
+	notificationModel := model.NewNotificationModel(db)
+	var channels []notify.Channel
+	if bot != nil {
+		channels = append(channels, notify.NewTelegramChannel(bot))
+	}
+	channels = append(channels,
+		notify.NewBarkChannel(barkClient),
+		notify.NewNtfyChannel(ntfy.NewClient()),
+		notify.NewGotifyChannel(gotify.NewClient()),
+	)
+	var emailSender *email.Sender
+	if cfg.Email.Enabled {
+		emailSender = email.NewSender(email.Config{
+			Host:     cfg.Email.Host,
+			Port:     cfg.Email.Port,
+			Username: cfg.Email.Username,
+			Password: cfg.Email.Password,
+			From:     cfg.Email.From,
+			TLS:      cfg.Email.TLS,
+		})
+		channels = append(channels, notify.NewEmailChannel(emailSender, cfg.Email.BaseURL, cfg.Auth.Secret))
+	}
+	notifier := notify.NewDispatcher(userModel, notificationModel, channels...)
+
+	webhookModel := model.NewWebhookModel(db)
 
 	// Rate limiter with configurable limits (defaults applied by go-zero)
 	rateLimiter := ratelimit.NewLimiter(cfg.RateLimit.MaxLoginFailures, cfg.RateLimit.MaxDailyRegisters)
 
 	ctx := &ServiceContext{
-		Config:         cfg,
-		DB:             db,
-		UserModel:      model.NewUserModel(db),
-		CharacterModel: model.NewCharacterModel(db),
-		TaskModel:      model.NewTaskModel(db),
-		SleepModel:     model.NewSleepModel(db),
-		ShopModel:      model.NewShopModel(db),
-		TelegramBot:    bot,
-		BarkClient:     barkClient,
-		RateLimiter:    rateLimiter,
+		Config:            cfg,
+		DB:                db,
+		UserModel:         userModel,
+		CharacterModel:    model.NewCharacterModel(db),
+		TaskModel:         model.NewTaskModel(db),
+		SleepModel:        model.NewSleepModel(db),
+		ShopModel:         model.NewShopModel(db),
+		LedgerModel:       model.NewLedgerModel(db),
+		BuffModel:         model.NewBuffModel(db),
+		EquipmentModel:    model.NewEquipmentModel(db),
+		LootModel:         model.NewLootModel(db),
+		CraftingModel:     model.NewCraftingModel(db),
+		RedemptionModel:   model.NewRedemptionModel(db),
+		SavingsModel:      model.NewSavingsModel(db),
+		NotificationModel: notificationModel,
+		WebhookModel:      webhookModel,
+		ItemEffects:       effect.NewDefaultRegistry(),
+		TelegramBot:       bot,
+		BarkClient:        barkClient,
+		EmailSender:       emailSender,
+		Notifier:          notifier,
+		Webhooks:          webhook.NewQueue(webhookModel),
+		RateLimiter:       rateLimiter,
 	}
 
 	// Set the service context reference in the bot to avoid circular import
@@ -50,6 +117,17 @@ func NewServiceContext(cfg config.Config, db *sql.DB, bot *telegram.Bot) *Servic
 	return ctx
 }
 
+// Transact runs fn in a single database transaction spanning all models.
+func (s *ServiceContext) Transact(fn func(uow *model.UnitOfWork) error) error {
+	return model.RunInTx(s.DB, fn)
+}
+
+// Read returns every model bound to the database outside a transaction.
+// Use it for read-only requests; mutations go through Transact.
+func (s *ServiceContext) Read() *model.UnitOfWork {
+	return model.Models(s.DB)
+}
+
 // Implement ServiceContextInterface for telegram package
 func (s *ServiceContext) GetDB() *sql.DB {
 	return s.DB
@@ -37,15 +37,32 @@ type AuthResp struct {
 
 // Character
 type CharacterResp struct {
-	UserID           int64           `json:"userId"`
-	SpiritStones     int             `json:"spiritStones"`
-	Fatigue          int             `json:"fatigue"`
-	FatigueCap       int             `json:"fatigueCap"`
-	FatigueLevel     int             `json:"fatigueLevel"`
-	OverdraftPenalty float64         `json:"overdraftPenalty"`
-	Title            string          `json:"title"`
-	LastActivityDate string          `json:"lastActivityDate"`
-	Attributes       []AttributeResp `json:"attributes"`
+	UserID            int64              `json:"userId"`
+	SpiritStones      int                `json:"spiritStones"`
+	SavedSpiritStones int                `json:"savedSpiritStones"` // Locked in savings goals, not spendable
+	Fatigue           int                `json:"fatigue"`
+	FatigueCap        int                `json:"fatigueCap"`
+	FatigueLevel      int                `json:"fatigueLevel"`
+	OverdraftPenalty  float64            `json:"overdraftPenalty"`
+	Title             string             `json:"title"`
+	LastActivityDate  string             `json:"lastActivityDate"`
+	Attributes        []AttributeResp    `json:"attributes"`
+	Buffs             []BuffResp         `json:"buffs"`
+	Equipment         []EquippedItemResp `json:"equipment"`
+}
+
+// BuffResp is an active timed or use-limited modifier on task rewards
+type BuffResp struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Modifier      string `json:"modifier"` // spirit_stones / attr_gain / fatigue_cost
+	Target        string `json:"target"`   // Attribute key for attr_gain
+	Value         int    `json:"value"`    // Total percent across stacks
+	Stacks        int    `json:"stacks"`
+	RemainingUses int    `json:"remainingUses"` // 0 means not limited by uses
+	ExpiresAt     string `json:"expiresAt"`     // Empty when not limited by time
+	Description   string `json:"description"`
+	IsDebuff      bool   `json:"isDebuff"`
 }
 
 type AttributeResp struct {
@@ -76,53 +93,53 @@ type SpiritStoneDisplay struct {
 
 // Task
 type CreateTaskReq struct {
-	Title              string  `json:"title"`
-	Description        string  `json:"description"`
-	Category           string  `json:"category"`
-	Type               string  `json:"type"`
-	Deadline           string  `json:"deadline"`
-	PrimaryAttribute   string  `json:"primaryAttribute"`
-	Difficulty         int     `json:"difficulty"`
-	RewardExp          int     `json:"rewardExp"`
-	RewardSpiritStones int     `json:"rewardSpiritStones"`
-	RewardPhysique     float64 `json:"rewardPhysique"`
-	RewardWillpower    float64 `json:"rewardWillpower"`
-	RewardIntelligence float64 `json:"rewardIntelligence"`
-	RewardPerception   float64 `json:"rewardPerception"`
-	RewardCharisma     float64 `json:"rewardCharisma"`
-	RewardAgility      float64 `json:"rewardAgility"`
-	PenaltyExp         int     `json:"penaltyExp"`
-	PenaltySpiritStones int    `json:"penaltySpiritStones"`
-	FatigueCost        int     `json:"fatigueCost"`
-	DailyLimit         int     `json:"dailyLimit"`
-	TotalLimit         int     `json:"totalLimit"`
-	RemindBefore       int     `json:"remindBefore"`
-	RemindInterval     int     `json:"remindInterval"`
+	Title               string  `json:"title"`
+	Description         string  `json:"description"`
+	Category            string  `json:"category"`
+	Type                string  `json:"type"`
+	Deadline            string  `json:"deadline"`
+	PrimaryAttribute    string  `json:"primaryAttribute"`
+	Difficulty          int     `json:"difficulty"`
+	RewardExp           int     `json:"rewardExp"`
+	RewardSpiritStones  int     `json:"rewardSpiritStones"`
+	RewardPhysique      float64 `json:"rewardPhysique"`
+	RewardWillpower     float64 `json:"rewardWillpower"`
+	RewardIntelligence  float64 `json:"rewardIntelligence"`
+	RewardPerception    float64 `json:"rewardPerception"`
+	RewardCharisma      float64 `json:"rewardCharisma"`
+	RewardAgility       float64 `json:"rewardAgility"`
+	PenaltyExp          int     `json:"penaltyExp"`
+	PenaltySpiritStones int     `json:"penaltySpiritStones"`
+	FatigueCost         int     `json:"fatigueCost"`
+	DailyLimit          int     `json:"dailyLimit"`
+	TotalLimit          int     `json:"totalLimit"`
+	RemindBefore        int     `json:"remindBefore"`
+	RemindInterval      int     `json:"remindInterval"`
 }
 
 type UpdateTaskReq struct {
-	Title              *string  `json:"title,omitempty"`
-	Description        *string  `json:"description,omitempty"`
-	Category           *string  `json:"category,omitempty"`
-	Type               *string  `json:"type,omitempty"`
-	Deadline           *string  `json:"deadline,omitempty"`
-	PrimaryAttribute   *string  `json:"primaryAttribute,omitempty"`
-	Difficulty         *int     `json:"difficulty,omitempty"`
-	RewardExp          *int     `json:"rewardExp,omitempty"`
-	RewardSpiritStones *int     `json:"rewardSpiritStones,omitempty"`
-	RewardPhysique     *float64 `json:"rewardPhysique,omitempty"`
-	RewardWillpower    *float64 `json:"rewardWillpower,omitempty"`
-	RewardIntelligence *float64 `json:"rewardIntelligence,omitempty"`
-	RewardPerception   *float64 `json:"rewardPerception,omitempty"`
-	RewardCharisma     *float64 `json:"rewardCharisma,omitempty"`
-	RewardAgility      *float64 `json:"rewardAgility,omitempty"`
-	PenaltyExp         *int     `json:"penaltyExp,omitempty"`
-	PenaltySpiritStones *int    `json:"penaltySpiritStones,omitempty"`
-	FatigueCost        *int     `json:"fatigueCost,omitempty"`
-	DailyLimit         *int     `json:"dailyLimit,omitempty"`
-	TotalLimit         *int     `json:"totalLimit,omitempty"`
-	RemindBefore       *int     `json:"remindBefore,omitempty"`
-	RemindInterval     *int     `json:"remindInterval,omitempty"`
+	Title               *string  `json:"title,omitempty"`
+	Description         *string  `json:"description,omitempty"`
+	Category            *string  `json:"category,omitempty"`
+	Type                *string  `json:"type,omitempty"`
+	Deadline            *string  `json:"deadline,omitempty"`
+	PrimaryAttribute    *string  `json:"primaryAttribute,omitempty"`
+	Difficulty          *int     `json:"difficulty,omitempty"`
+	RewardExp           *int     `json:"rewardExp,omitempty"`
+	RewardSpiritStones  *int     `json:"rewardSpiritStones,omitempty"`
+	RewardPhysique      *float64 `json:"rewardPhysique,omitempty"`
+	RewardWillpower     *float64 `json:"rewardWillpower,omitempty"`
+	RewardIntelligence  *float64 `json:"rewardIntelligence,omitempty"`
+	RewardPerception    *float64 `json:"rewardPerception,omitempty"`
+	RewardCharisma      *float64 `json:"rewardCharisma,omitempty"`
+	RewardAgility       *float64 `json:"rewardAgility,omitempty"`
+	PenaltyExp          *int     `json:"penaltyExp,omitempty"`
+	PenaltySpiritStones *int     `json:"penaltySpiritStones,omitempty"`
+	FatigueCost         *int     `json:"fatigueCost,omitempty"`
+	DailyLimit          *int     `json:"dailyLimit,omitempty"`
+	TotalLimit          *int     `json:"totalLimit,omitempty"`
+	RemindBefore        *int     `json:"remindBefore,omitempty"`
+	RemindInterval      *int     `json:"remindInterval,omitempty"`
 }
 
 type TaskResp struct {
@@ -172,28 +189,31 @@ type ReorderTasksReq struct {
 // POST /api/tasks/quick
 //
 // Difficulty template (auto-filled):
-//   0★: fatigue=1,  spiritStones=10,   attrBonus=0
-//   1★: fatigue=5,  spiritStones=50,   attrBonus=0.1
-//   2★: fatigue=10, spiritStones=120,  attrBonus=0.2
-//   3★: fatigue=20, spiritStones=300,  attrBonus=0.4
-//   4★: fatigue=40, spiritStones=800,  attrBonus=0.7
-//   5★: fatigue=90, spiritStones=2500, attrBonus=1.0
+//
+//	0★: fatigue=1,  spiritStones=10,   attrBonus=0
+//	1★: fatigue=5,  spiritStones=50,   attrBonus=0.1
+//	2★: fatigue=10, spiritStones=120,  attrBonus=0.2
+//	3★: fatigue=20, spiritStones=300,  attrBonus=0.4
+//	4★: fatigue=40, spiritStones=800,  attrBonus=0.7
+//	5★: fatigue=90, spiritStones=2500, attrBonus=1.0
 //
 // Categories (attribute keys, each selected one gets attrBonus):
-//   "physique"     - 体魄 💪 (exercise, health, diet)
-//   "willpower"    - 意志 🧠 (discipline, habits, meditation)
-//   "intelligence" - 智力 📚 (study, reading, coding)
-//   "perception"   - 感知 👁 (observation, art, reflection)
-//   "charisma"     - 魅力 ✨ (communication, networking)
-//   "agility"      - 敏捷 🏃 (speed, execution, coordination)
+//
+//	"physique"     - 体魄 💪 (exercise, health, diet)
+//	"willpower"    - 意志 🧠 (discipline, habits, meditation)
+//	"intelligence" - 智力 📚 (study, reading, coding)
+//	"perception"   - 感知 👁 (observation, art, reflection)
+//	"charisma"     - 魅力 ✨ (communication, networking)
+//	"agility"      - 敏捷 🏃 (speed, execution, coordination)
 //
 // Task types:
-//   "once"       - (default) Create + complete in one shot, immediate rewards
-//   "repeatable" - Create only, stays active for repeated completion via POST /api/tasks/complete/:id
-//   "challenge"  - Create only, has deadline, penalties on failure
+//
+//	"once"       - (default) Create + complete in one shot, immediate rewards
+//	"repeatable" - Create only, stays active for repeated completion via POST /api/tasks/complete/:id
+//	"challenge"  - Create only, has deadline, penalties on failure
 type QuickTaskReq struct {
 	Title      string   `json:"title"`      // Optional, auto-generated if empty
-	Difficulty int      `json:"difficulty"`  // 0-5 stars
+	Difficulty int      `json:"difficulty"` // 0-5 stars
 	Categories []string `json:"categories"` // Attribute keys
 	Type       string   `json:"type"`       // once (default), repeatable, challenge
 	DailyLimit int      `json:"dailyLimit"` // For repeatable: max completions per day (0=unlimited)
@@ -217,12 +237,17 @@ type TgStatusResp struct {
 
 // Bark Push Notification
 type SetBarkKeyReq struct {
-	BarkKey string `json:"barkKey"`
+	BarkKey   string `json:"barkKey"`
+	ServerURL string `json:"serverUrl,optional"` // Self-hosted server, empty for the default one
+	CryptKey  string `json:"cryptKey,optional"`  // AES-CBC key (16/24/32 chars), empty to push in plain text
 }
 
 type BarkStatusResp struct {
-	Enabled bool   `json:"enabled"`
-	BarkKey string `json:"barkKey"` // Masked for security
+	Enabled   bool   `json:"enabled"`
+	BarkKey   string `json:"barkKey"`   // Masked for security
+	ServerURL string `json:"serverUrl"` // Server in use
+	Custom    bool   `json:"custom"`    // ServerURL is the user's own server
+	Encrypted bool   `json:"encrypted"`
 }
 
 type TestBarkReq struct {
@@ -230,6 +255,207 @@ type TestBarkReq struct {
 	Body  string `json:"body"`
 }
 
+// ntfy push
+type SetNtfyReq struct {
+	TopicURL string `json:"topicUrl"`       // e.g. https://ntfy.sh/my-topic
+	Token    string `json:"token,optional"` // Access token for protected topics
+}
+
+type NtfyStatusResp struct {
+	Enabled   bool   `json:"enabled"`
+	TopicURL  string `json:"topicUrl"`
+	Protected bool   `json:"protected"` // An access token is set
+}
+
+// Gotify push
+type SetGotifyReq struct {
+	ServerURL string `json:"serverUrl"`
+	Token     string `json:"token"` // Application token
+}
+
+type GotifyStatusResp struct {
+	Enabled   bool   `json:"enabled"`
+	ServerURL string `json:"serverUrl"`
+	Token     string `json:"token"` // Masked for security
+}
+
+// TestPushReq customizes the ntfy and Gotify test push
+type TestPushReq struct {
+	Title string `json:"title,optional"`
+	Body  string `json:"body,optional"`
+}
+
+// Email notifications
+type SetEmailReq struct {
+	Email string `json:"email"`
+}
+
+type SetEmailResp struct {
+	Email     string `json:"email"`
+	ExpiresIn int    `json:"expiresIn"` // Seconds the verification code stays valid
+}
+
+type VerifyEmailReq struct {
+	Code string `json:"code"`
+}
+
+type EmailStatusResp struct {
+	Available    bool   `json:"available"` // Mail is enabled on this server
+	Email        string `json:"email"`
+	Verified     bool   `json:"verified"`
+	Unsubscribed bool   `json:"unsubscribed"` // Opted out of all notification mail
+}
+
+type UnsubscribeEmailReq struct {
+	UserID int64  `form:"uid"`
+	Kind   string `form:"kind"`
+	Sig    string `form:"sig"`
+}
+
+// Notification preferences
+type NotificationChannelResp struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Configured bool   `json:"configured"` // The user set up this channel
+}
+
+type NotificationKindResp struct {
+	Kind         string   `json:"kind"`
+	Name         string   `json:"name"`
+	Channels     []string `json:"channels"` // Channels in effect, empty when muted
+	Defaults     []string `json:"defaults"`
+	Custom       bool     `json:"custom"` // Channels were chosen by the user
+	Level        string   `json:"level"`  // Urgency in effect
+	DefaultLevel string   `json:"defaultLevel"`
+	LevelCustom  bool     `json:"levelCustom"` // Urgency was chosen by the user
+}
+
+type NotificationLevelResp struct {
+	Level string `json:"level"`
+	Label string `json:"label"`
+}
+
+type NotificationPreferencesResp struct {
+	Channels []NotificationChannelResp `json:"channels"`
+	Levels   []NotificationLevelResp   `json:"levels"`
+	Kinds    []NotificationKindResp    `json:"kinds"`
+}
+
+type UpdateNotificationPreferenceReq struct {
+	Kind     string   `json:"kind"`
+	Channels []string `json:"channels,optional"` // Omit to keep, empty mutes the kind
+	Level    *string  `json:"level,optional"`    // Empty goes back to the kind's own
+	Reset    bool     `json:"reset,optional"`    // Go back to the defaults
+}
+
+type NotificationListReq struct {
+	Status   string `form:"status,optional"` // pending, sent, failed, skipped
+	Page     int    `form:"page,optional"`
+	PageSize int    `form:"pageSize,optional"`
+}
+
+type NotificationItemResp struct {
+	ID            int64    `json:"id"`
+	Kind          string   `json:"kind"`
+	KindName      string   `json:"kindName"`
+	Title         string   `json:"title"`
+	Body          string   `json:"body"`
+	Level         string   `json:"level"`
+	Status        string   `json:"status"`
+	Deferred      bool     `json:"deferred"`  // Waiting for the quiet hours to end
+	Delivered     []string `json:"delivered"` // Channels that delivered it
+	Pending       []string `json:"pending"`   // Channels still being retried
+	Attempts      int      `json:"attempts"`
+	Error         string   `json:"error,omitempty"`
+	NextAttemptAt string   `json:"nextAttemptAt,omitempty"`
+	CreatedAt     string   `json:"createdAt"`
+	SentAt        string   `json:"sentAt,omitempty"`
+}
+
+type NotificationListResp struct {
+	Items    []NotificationItemResp `json:"items"`
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"pageSize"`
+}
+
+type QuietHoursResp struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"` // HH:MM
+	End      string `json:"end"`
+	Timezone string `json:"timezone"` // Empty for the server's zone
+	Active   bool   `json:"active"`   // Quiet hours are on right now
+	Deferred int    `json:"deferred"` // Notifications waiting for the quiet hours to end
+}
+
+type SetQuietHoursReq struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start,optional"`
+	End      string `json:"end,optional"`
+	Timezone string `json:"timezone,optional"`
+}
+
+// Reports
+type ReportTaskResp struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Type     string `json:"type"`
+	Deadline string `json:"deadline,omitempty"` // RFC3339
+	Overdue  bool   `json:"overdue"`
+}
+
+type DailyReportResp struct {
+	Date          string           `json:"date"`          // User's local date
+	Deadlines     []ReportTaskResp `json:"deadlines"`     // Active tasks due by the end of the day
+	Repeatable    []ReportTaskResp `json:"repeatable"`    // Repeatable tasks not done yet today
+	StreaksAtRisk []ReportTaskResp `json:"streaksAtRisk"` // Repeatable tasks done yesterday but not yet today
+	Fatigue       int              `json:"fatigue"`
+	FatigueCap    int              `json:"fatigueCap"`
+	DecayRisk     bool             `json:"decayRisk"` // Nothing done today yet; attributes decay after a full idle day
+	StreakShields int              `json:"streakShields"`
+	SpiritStones  int              `json:"spiritStones"`
+}
+
+type ReportAttrResp struct {
+	Key              string  `json:"key"`
+	Name             string  `json:"name"`
+	Value            float64 `json:"value"`
+	Gain             float64 `json:"gain"` // Change over the period, negative after decay
+	Realm            string  `json:"realm"`
+	RealmBefore      string  `json:"realmBefore"`
+	Breakthrough     bool    `json:"breakthrough"` // Reached a higher realm in the period
+	Bottleneck       bool    `json:"bottleneck"`
+	RealmExp         int     `json:"realmExp"`
+	RealmExpRequired int     `json:"realmExpRequired"` // Exp needed to break through the bottleneck
+}
+
+type WeeklyReportResp struct {
+	From           string           `json:"from"` // YYYY-MM-DD, user's local dates
+	To             string           `json:"to"`
+	CompletedTasks int              `json:"completedTasks"`
+	FailedTasks    int              `json:"failedTasks"`
+	Attributes     []ReportAttrResp `json:"attributes"`
+	HistorySince   string           `json:"historySince"` // First day attribute changes are known for
+	StonesEarned   int              `json:"stonesEarned"`
+	StonesSpent    int              `json:"stonesSpent"`
+	SpiritStones   int              `json:"spiritStones"` // Balance now
+}
+
+type ReportSettingsResp struct {
+	Daily    bool   `json:"daily"`
+	Weekly   bool   `json:"weekly"`
+	Time     string `json:"time"`     // HH:MM in the user's timezone
+	Weekday  int    `json:"weekday"`  // Day of the weekly review, 0 = Sunday
+	Timezone string `json:"timezone"` // Set with the quiet hours, empty for the server's zone
+}
+
+type UpdateReportSettingsReq struct {
+	Daily   *bool   `json:"daily,omitempty"`
+	Weekly  *bool   `json:"weekly,omitempty"`
+	Time    *string `json:"time,omitempty"`
+	Weekday *int    `json:"weekday,omitempty"`
+}
+
 // Sleep
 type RecordSleepReq struct {
 	SleepStart string `json:"sleepStart"` // ISO8601 format
@@ -254,64 +480,196 @@ type SleepRecordListResp struct {
 
 // Shop
 type ShopItemResp struct {
-	ID          int64  `json:"id"`
+	ID                int64                 `json:"id"`
+	Name              string                `json:"name"`
+	Description       string                `json:"description"`
+	Price             int                   `json:"price"`
+	SellPrice         int                   `json:"sellPrice"`
+	ItemType          string                `json:"itemType"`
+	Effect            string                `json:"effect"`
+	EffectValue       int                   `json:"effectValue"`
+	EffectName        string                `json:"effectName"`
+	EffectDescription string                `json:"effectDescription"`
+	Effects           []ItemEffectSpecResp  `json:"effects"`  // Every effect applied on use
+	Slot              string                `json:"slot"`     // Equipment slot, empty when not equippable
+	Passives          []PassiveModifierResp `json:"passives"` // Applied while equipped
+	Icon              string                `json:"icon"`
+	Image             string                `json:"image"`
+	Stock             int                   `json:"stock"`
+	EffectivePrice    int                   `json:"effectivePrice"` // Price charged now, after an active discount
+	OnSale            bool                  `json:"onSale"`
+	DiscountPercent   int                   `json:"discountPercent"`
+	DiscountStart     string                `json:"discountStart"` // Empty when unbounded
+	DiscountEnd       string                `json:"discountEnd"`   // Empty when unbounded
+	RestockPeriod     string                `json:"restockPeriod"` // daily / weekly, empty when never restocked
+	RestockAmount     int                   `json:"restockAmount"`
+	RestockMax        int                   `json:"restockMax"`    // 0 for no cap
+	NextRestockAt     string                `json:"nextRestockAt"` // Empty without a restock rule
+	ExpiresAfter      int                   `json:"expiresAfter"`  // Hours an acquired unit lasts, 0 for never
+	Durability        int                   `json:"durability"`    // Equipment: completed tasks a unit lasts, 0 for unbreakable
+}
+
+// ItemEffectSpec is one effect of a composite item
+type ItemEffectSpec struct {
+	Type     string `json:"type"`            // See ItemEffectResp.Key
+	Target   string `json:"target,optional"` // Required when the effect needs a target
+	Value    int    `json:"value,optional"`
+	Duration int    `json:"duration,optional"` // Buff effects: hours
+	Uses     int    `json:"uses,optional"`     // Buff effects: completed tasks
+	Stacking string `json:"stacking,optional"` // Buff effects: refresh / extend / stack
+}
+
+type ItemEffectSpecResp struct {
+	Type        string `json:"type"`
+	Target      string `json:"target"`
+	Value       int    `json:"value"`
+	Duration    int    `json:"duration"`
+	Uses        int    `json:"uses"`
+	Stacking    string `json:"stacking"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	Price       int    `json:"price"`
-	SellPrice   int    `json:"sellPrice"`
-	ItemType    string `json:"itemType"`
-	Icon        string `json:"icon"`
-	Image       string `json:"image"`
-	Stock       int    `json:"stock"`
 }
 
 type CreateShopItemReq struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Price       int    `json:"price"`
-	SellPrice   int    `json:"sellPrice"`
-	ItemType    string `json:"itemType"`
-	Icon        string `json:"icon"`
-	Image       string `json:"image"`
-	Stock       int    `json:"stock"`
+	Name            string               `json:"name"`
+	Description     string               `json:"description"`
+	Price           int                  `json:"price"`
+	SellPrice       int                  `json:"sellPrice"`
+	ItemType        string               `json:"itemType"`
+	Effect          string               `json:"effect,optional"`      // See ItemEffectResp.Key, defaults to "none"
+	EffectValue     int                  `json:"effectValue,optional"` // Required when the effect needs a value
+	Effects         []ItemEffectSpec     `json:"effects,optional"`     // Composite effects, overrides effect/effectValue
+	Slot            string               `json:"slot,optional"`        // Required for equipment that can be equipped
+	Passives        []PassiveModifierReq `json:"passives,optional"`
+	Icon            string               `json:"icon"`
+	Image           string               `json:"image"`
+	Stock           int                  `json:"stock"`
+	RestockPeriod   string               `json:"restockPeriod,optional"` // daily / weekly, requires a limited stock
+	RestockAmount   int                  `json:"restockAmount,optional"`
+	RestockMax      int                  `json:"restockMax,optional"`      // Restocking stops at this stock, 0 for no cap
+	DiscountPercent int                  `json:"discountPercent,optional"` // 0-100
+	DiscountStart   string               `json:"discountStart,optional"`   // RFC3339, empty for no start
+	DiscountEnd     string               `json:"discountEnd,optional"`     // RFC3339, empty for no end
+	ExpiresAfter    int                  `json:"expiresAfter,optional"`    // Hours, 0 for never
+	Durability      int                  `json:"durability,optional"`      // Equipment only, 0 for unbreakable
 }
 
 type UpdateShopItemReq struct {
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Price       *int    `json:"price,omitempty"`
-	SellPrice   *int    `json:"sellPrice,omitempty"`
-	ItemType    *string `json:"itemType,omitempty"`
-	Icon        *string `json:"icon,omitempty"`
-	Image       *string `json:"image,omitempty"`
-	Stock       *int    `json:"stock,omitempty"`
+	Name            *string              `json:"name,omitempty"`
+	Description     *string              `json:"description,omitempty"`
+	Price           *int                 `json:"price,omitempty"`
+	SellPrice       *int                 `json:"sellPrice,omitempty"`
+	ItemType        *string              `json:"itemType,omitempty"`
+	Effect          *string              `json:"effect,omitempty"`
+	EffectValue     *int                 `json:"effectValue,omitempty"`
+	Effects         []ItemEffectSpec     `json:"effects,omitempty"` // Replaces all effects when present
+	Slot            *string              `json:"slot,omitempty"`
+	Passives        []PassiveModifierReq `json:"passives,omitempty"` // Replaces all passives when present
+	Icon            *string              `json:"icon,omitempty"`
+	Image           *string              `json:"image,omitempty"`
+	Stock           *int                 `json:"stock,omitempty"`
+	RestockPeriod   *string              `json:"restockPeriod,omitempty"` // Empty string removes the rule
+	RestockAmount   *int                 `json:"restockAmount,omitempty"`
+	RestockMax      *int                 `json:"restockMax,omitempty"`
+	DiscountPercent *int                 `json:"discountPercent,omitempty"`
+	DiscountStart   *string              `json:"discountStart,omitempty"` // Empty string clears the start
+	DiscountEnd     *string              `json:"discountEnd,omitempty"`   // Empty string clears the end
+	ExpiresAfter    *int                 `json:"expiresAfter,omitempty"`  // Only affects units acquired afterwards
+	Durability      *int                 `json:"durability,omitempty"`
+}
+
+// ItemEffectResp describes an effect an item can have when used
+type ItemEffectResp struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	NeedsValue  bool     `json:"needsValue"` // Whether effectValue must be > 0
+	NeedsTarget bool     `json:"needsTarget"`
+	Targets     []string `json:"targets"` // Allowed targets when needsTarget
 }
 
 type ShopItemListResp struct {
-	Items []ShopItemResp `json:"items"`
+	Items   []ShopItemResp   `json:"items"`
+	Bundles []ShopBundleResp `json:"bundles"`
+	Effects []ItemEffectResp `json:"effects"` // All known effects
 }
 
-type PurchaseItemReq struct {
+// BundleItemReq is one item contained in a bundle
+type BundleItemReq struct {
 	ItemID   int64 `json:"itemId"`
 	Quantity int   `json:"quantity"`
 }
 
+type BundleItemResp struct {
+	ItemID   int64  `json:"itemId"`
+	Name     string `json:"name"`
+	Icon     string `json:"icon"`
+	Quantity int    `json:"quantity"`
+}
+
+type ShopBundleResp struct {
+	ID            int64            `json:"id"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	Icon          string           `json:"icon"`
+	Items         []BundleItemResp `json:"items"`
+	Price         int              `json:"price"`
+	OriginalPrice int              `json:"originalPrice"` // Current price of the items bought separately
+	Stock         int              `json:"stock"`
+}
+
+type ShopBundleListResp struct {
+	Bundles []ShopBundleResp `json:"bundles"`
+}
+
+type CreateShopBundleReq struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,optional"`
+	Icon        string          `json:"icon,optional"`
+	Items       []BundleItemReq `json:"items"`
+	Price       int             `json:"price"`
+	Stock       int             `json:"stock,default=-1"`
+}
+
+type UpdateShopBundleReq struct {
+	Name        *string         `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Icon        *string         `json:"icon,omitempty"`
+	Items       []BundleItemReq `json:"items,omitempty"` // Replaces all items when present
+	Price       *int            `json:"price,omitempty"`
+	Stock       *int            `json:"stock,omitempty"`
+}
+
+// PurchaseItemReq buys either an item or a bundle
+type PurchaseItemReq struct {
+	ItemID   int64 `json:"itemId,optional"`
+	BundleID int64 `json:"bundleId,optional"`
+	Quantity int   `json:"quantity"`
+}
+
 type PurchaseResult struct {
-	Success              bool   `json:"success"`
-	Message              string `json:"message"`
+	Success               bool   `json:"success"`
+	Message               string `json:"message"`
 	RemainingSpiritStones int    `json:"remainingSpiritStones"`
+	RedemptionID          int64  `json:"redemptionId,omitempty"` // Set for redeemable items
 }
 
 type InventoryItemResp struct {
-	ID          int64  `json:"id"`
-	ItemID      int64  `json:"itemId"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ItemType    string `json:"itemType"`
-	SellPrice   int    `json:"sellPrice"`
-	Icon        string `json:"icon"`
-	Image       string `json:"image"`
-	Quantity    int    `json:"quantity"`
+	ID               int64  `json:"id"`
+	ItemID           int64  `json:"itemId"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	ItemType         string `json:"itemType"`
+	SellPrice        int    `json:"sellPrice"`
+	Icon             string `json:"icon"`
+	Image            string `json:"image"`
+	Quantity         int    `json:"quantity"`
+	Slot             string `json:"slot"`
+	Equipped         bool   `json:"equipped"`
+	ExpiresAt        string `json:"expiresAt"`        // Earliest expiry, empty when nothing expires
+	ExpiringQuantity int    `json:"expiringQuantity"` // Units that expire at ExpiresAt
+	Durability       int    `json:"durability"`       // 0 for unbreakable
+	DurabilityLeft   int    `json:"durabilityLeft"`   // Tasks left on the unit in use
 }
 
 type SellItemReq struct {
@@ -320,8 +678,8 @@ type SellItemReq struct {
 }
 
 type SellItemResult struct {
-	Success              bool   `json:"success"`
-	Message              string `json:"message"`
+	Success               bool   `json:"success"`
+	Message               string `json:"message"`
 	RemainingSpiritStones int    `json:"remainingSpiritStones"`
 }
 
@@ -340,22 +698,354 @@ type UseItemResult struct {
 	Character CharacterResp `json:"character"`
 }
 
-type PurchaseRecordResp struct {
+type EquipItemReq struct {
+	ItemID int64 `json:"itemId"`
+}
+
+type UnequipItemReq struct {
+	Slot string `json:"slot"`
+}
+
+type EquipResult struct {
+	Success   bool          `json:"success"`
+	Message   string        `json:"message"`
+	Character CharacterResp `json:"character"`
+}
+
+// PassiveModifierReq is a modifier granted while an item is equipped
+type PassiveModifierReq struct {
+	Modifier string `json:"modifier"`        // spirit_stones / attr_gain / fatigue_cost
+	Target   string `json:"target,optional"` // Attribute key for attr_gain
+	Value    int    `json:"value"`           // Signed percent
+}
+
+type PassiveModifierResp struct {
+	Modifier    string `json:"modifier"`
+	Target      string `json:"target"`
+	Value       int    `json:"value"`
+	Description string `json:"description"`
+}
+
+type EquippedItemResp struct {
+	Slot           string                `json:"slot"`
+	SlotName       string                `json:"slotName"`
+	ItemID         int64                 `json:"itemId"`
+	Name           string                `json:"name"`
+	Icon           string                `json:"icon"`
+	Image          string                `json:"image"`
+	Passives       []PassiveModifierResp `json:"passives"`
+	Durability     int                   `json:"durability"` // 0 for unbreakable
+	DurabilityLeft int                   `json:"durabilityLeft"`
+}
+
+type RefundReq struct {
+	EventID  int64 `json:"eventId"` // Purchase event from the history
+	Quantity int   `json:"quantity,default=1"`
+}
+
+type RefundResult struct {
+	Success               bool   `json:"success"`
+	Message               string `json:"message"`
+	RefundedSpiritStones  int    `json:"refundedSpiritStones"`
+	RemainingSpiritStones int    `json:"remainingSpiritStones"`
+}
+
+type InventoryHistoryReq struct {
+	Kind     string `form:"kind,optional"` // purchase, use, sell, refund, drop, craft
+	ItemID   int64  `form:"itemId,optional"`
+	From     string `form:"from,optional"` // YYYY-MM-DD
+	To       string `form:"to,optional"`   // YYYY-MM-DD
+	Page     int    `form:"page,optional"`
+	PageSize int    `form:"pageSize,optional"`
+}
+
+type InventoryEventResp struct {
+	ID                 int64  `json:"id"`
+	Kind               string `json:"kind"`
+	ItemID             int64  `json:"itemId"` // 0 for bundle purchases
+	ItemName           string `json:"itemName"`
+	Quantity           int    `json:"quantity"`     // Signed change, negative when items leave the bag
+	SpiritStones       int    `json:"spiritStones"` // Signed spirit stone change
+	TotalPrice         int    `json:"totalPrice"`   // Absolute spirit stones paid or received
+	RefType            string `json:"refType"`
+	RefID              int64  `json:"refId"`
+	RefundableQuantity int    `json:"refundableQuantity"` // Units that can still be refunded now
+	CreatedAt          string `json:"createdAt"`
+}
+
+type InventoryHistoryResp struct {
+	History  []InventoryEventResp `json:"history"`
+	Total    int                  `json:"total"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"pageSize"`
+}
+
+// Loot
+type DropEntryResp struct {
+	ID          int64   `json:"id"`
+	Difficulty  int     `json:"difficulty"` // -1 matches any difficulty
+	Category    string  `json:"category"`   // Empty matches any category
+	ItemID      int64   `json:"itemId"`
+	ItemName    string  `json:"itemName"`
+	ItemIcon    string  `json:"itemIcon"`
+	Chance      float64 `json:"chance"` // Percent at luck 100
+	MinQuantity int     `json:"minQuantity"`
+	MaxQuantity int     `json:"maxQuantity"`
+}
+
+type DropEntryListResp struct {
+	Entries []DropEntryResp `json:"entries"`
+}
+
+type CreateDropEntryReq struct {
+	Difficulty  int     `json:"difficulty,default=-1"`
+	Category    string  `json:"category,optional"`
+	ItemID      int64   `json:"itemId"`
+	Chance      float64 `json:"chance"`
+	MinQuantity int     `json:"minQuantity,default=1"`
+	MaxQuantity int     `json:"maxQuantity,optional"` // Defaults to minQuantity
+}
+
+type UpdateDropEntryReq struct {
+	Difficulty  *int     `json:"difficulty,omitempty"`
+	Category    *string  `json:"category,omitempty"`
+	ItemID      *int64   `json:"itemId,omitempty"`
+	Chance      *float64 `json:"chance,omitempty"`
+	MinQuantity *int     `json:"minQuantity,omitempty"`
+	MaxQuantity *int     `json:"maxQuantity,omitempty"`
+}
+
+// DropResp is an item that dropped from a completed task
+type DropResp struct {
+	ItemID   int64  `json:"itemId"`
+	Name     string `json:"name"`
+	Icon     string `json:"icon"`
+	Quantity int    `json:"quantity"`
+}
+
+// Crafting
+type IngredientReq struct {
+	ItemID   int64 `json:"itemId"`
+	Quantity int   `json:"quantity"`
+}
+
+type IngredientResp struct {
+	ItemID   int64  `json:"itemId"`
+	Name     string `json:"name"`
+	Icon     string `json:"icon"`
+	Quantity int    `json:"quantity"`
+	Owned    int    `json:"owned"` // Quantity currently in the inventory
+}
+
+type RecipeResp struct {
+	ID              int64            `json:"id"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	Ingredients     []IngredientResp `json:"ingredients"`
+	SpiritStoneCost int              `json:"spiritStoneCost"`
+	OutputItemID    int64            `json:"outputItemId"`
+	OutputName      string           `json:"outputName"`
+	OutputIcon      string           `json:"outputIcon"`
+	OutputQuantity  int              `json:"outputQuantity"`
+	SuccessRate     float64          `json:"successRate"` // Percent
+	CanCraft        bool             `json:"canCraft"`    // Enough materials and spirit stones for one attempt
+}
+
+type RecipeListResp struct {
+	Recipes []RecipeResp `json:"recipes"`
+}
+
+type CreateRecipeReq struct {
+	Name            string          `json:"name"`
+	Description     string          `json:"description,optional"`
+	Ingredients     []IngredientReq `json:"ingredients"`
+	SpiritStoneCost int             `json:"spiritStoneCost,optional"`
+	OutputItemID    int64           `json:"outputItemId"`
+	OutputQuantity  int             `json:"outputQuantity,default=1"`
+	SuccessRate     float64         `json:"successRate,default=100"`
+}
+
+type UpdateRecipeReq struct {
+	Name            *string          `json:"name,omitempty"`
+	Description     *string          `json:"description,omitempty"`
+	Ingredients     *[]IngredientReq `json:"ingredients,omitempty"`
+	SpiritStoneCost *int             `json:"spiritStoneCost,omitempty"`
+	OutputItemID    *int64           `json:"outputItemId,omitempty"`
+	OutputQuantity  *int             `json:"outputQuantity,omitempty"`
+	SuccessRate     *float64         `json:"successRate,omitempty"`
+}
+
+type CraftReq struct {
+	RecipeID int64 `json:"recipeId"`
+	Times    int   `json:"times,default=1"`
+}
+
+type CraftResult struct {
+	Success               bool   `json:"success"`
+	Message               string `json:"message"`
+	Attempts              int    `json:"attempts"`
+	Successes             int    `json:"successes"`
+	Produced              int    `json:"produced"` // Output items added to the inventory
+	RemainingSpiritStones int    `json:"remainingSpiritStones"`
+}
+
+// Redemption
+type RedemptionListReq struct {
+	Role   string `form:"role,optional"`   // mine (default) or partner
+	Status string `form:"status,optional"` // awaiting_approval, pending, fulfilled, cancelled, rejected
+}
+
+type RedemptionResp struct {
 	ID         int64  `json:"id"`
+	UserID     int64  `json:"userId"`
+	BuyerName  string `json:"buyerName"`
+	ItemID     int64  `json:"itemId"`
 	ItemName   string `json:"itemName"`
 	Quantity   int    `json:"quantity"`
 	TotalPrice int    `json:"totalPrice"`
+	Status     string `json:"status"`
+	ApproverID int64  `json:"approverId"`
+	Note       string `json:"note"`
 	CreatedAt  string `json:"createdAt"`
+	ResolvedAt string `json:"resolvedAt"`
 }
 
-type PurchaseHistoryResp struct {
-	History []PurchaseRecordResp `json:"history"`
+type RedemptionListResp struct {
+	Redemptions []RedemptionResp `json:"redemptions"`
+}
+
+type RedemptionActionReq struct {
+	Note string `json:"note,optional"`
+}
+
+type SetPartnerReq struct {
+	Username string `json:"username"`
+}
+
+type PartnerResp struct {
+	PartnerID   int64  `json:"partnerId"` // 0 when no partner is set
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Status      string `json:"status"` // pending until the partner accepts, then accepted
+}
+
+// PartnerRequestResp is a user who named the caller as their partner
+type PartnerRequestResp struct {
+	UserID      int64  `json:"userId"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Status      string `json:"status"` // pending or accepted
+}
+
+type PartnerRequestListResp struct {
+	Requests []PartnerRequestResp `json:"requests"`
+}
+
+type RedemptionReportReq struct {
+	From string `form:"from,optional"` // YYYY-MM-DD
+	To   string `form:"to,optional"`   // YYYY-MM-DD
+}
+
+// RedemptionMonthResp amounts are spirit stones, 1 下品灵石 = 1 RMB
+type RedemptionMonthResp struct {
+	Month     string `json:"month"` // YYYY-MM
+	Earned    int    `json:"earned"`
+	Penalties int    `json:"penalties"`
+	Spent     int    `json:"spent"`
+}
+
+type RedemptionReportResp struct {
+	Earned    int                   `json:"earned"`    // Task rewards
+	Penalties int                   `json:"penalties"` // Challenge penalties
+	Spent     int                   `json:"spent"`     // Fulfilled redemptions
+	Pending   int                   `json:"pending"`   // Redemptions not yet fulfilled
+	Months    []RedemptionMonthResp `json:"months"`
+}
+
+// Savings
+type SavingsGoalResp struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name"`
+	ItemID       int64   `json:"itemId"` // 0 for a free-form goal
+	ItemName     string  `json:"itemName"`
+	ItemIcon     string  `json:"itemIcon"`
+	TargetAmount int     `json:"targetAmount"`
+	SavedAmount  int     `json:"savedAmount"`
+	Progress     float64 `json:"progress"` // Percent of target saved
+	Percent      int     `json:"percent"`  // Share of each task reward deposited
+	AutoPurchase bool    `json:"autoPurchase"`
+	Status       string  `json:"status"`       // active, reached, purchased, cancelled
+	DailyDeposit float64 `json:"dailyDeposit"` // Expected deposit per day from recent income
+	EtaDays      int     `json:"etaDays"`      // -1 when there is no recent income
+	EtaDate      string  `json:"etaDate"`      // YYYY-MM-DD, empty when unknown
+	CreatedAt    string  `json:"createdAt"`
+	CompletedAt  string  `json:"completedAt"`
+}
+
+type SavingsGoalListResp struct {
+	Goals        []SavingsGoalResp `json:"goals"`
+	TotalSaved   int               `json:"totalSaved"`
+	DailyIncome  float64           `json:"dailyIncome"` // Average task reward per day over the ETA window
+	TotalPercent int               `json:"totalPercent"`
+}
+
+type CreateSavingsGoalReq struct {
+	Name         string `json:"name,optional"` // Defaults to the item name
+	ItemID       int64  `json:"itemId,optional"`
+	TargetAmount int    `json:"targetAmount,optional"` // Required for free-form goals
+	Percent      int    `json:"percent"`
+	AutoPurchase bool   `json:"autoPurchase,optional"`
+}
+
+type UpdateSavingsGoalReq struct {
+	Name         *string `json:"name,omitempty"`
+	TargetAmount *int    `json:"targetAmount,omitempty"`
+	Percent      *int    `json:"percent,omitempty"`
+	AutoPurchase *bool   `json:"autoPurchase,omitempty"`
+}
+
+type SavingsDepositReq struct {
+	Amount int `json:"amount"`
+}
+
+type SavingsResult struct {
+	Success               bool            `json:"success"`
+	Message               string          `json:"message"`
+	Goal                  SavingsGoalResp `json:"goal"`
+	RemainingSpiritStones int             `json:"remainingSpiritStones"`
+}
+
+// Spirit stone ledger
+type LedgerListReq struct {
+	Reason   string `form:"reason,optional"`
+	RefType  string `form:"refType,optional"`
+	From     string `form:"from,optional"` // YYYY-MM-DD
+	To       string `form:"to,optional"`   // YYYY-MM-DD
+	Page     int    `form:"page,optional"`
+	PageSize int    `form:"pageSize,optional"`
+}
+
+type LedgerEntryResp struct {
+	ID           int64  `json:"id"`
+	Amount       int    `json:"amount"`
+	BalanceAfter int    `json:"balanceAfter"`
+	Reason       string `json:"reason"` // opening_balance, task_reward, task_penalty, purchase, sale, item_effect, crafting, refund, savings_deposit, savings_release
+	RefType      string `json:"refType"`
+	RefID        int64  `json:"refId"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+type LedgerListResp struct {
+	Entries  []LedgerEntryResp `json:"entries"`
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"pageSize"`
 }
 
 // Timeline
 type TimelineEvent struct {
 	ID          string           `json:"id"`
-	Type        string           `json:"type"` // task_complete, task_fail, task_delete, sleep, purchase
+	Type        string           `json:"type"` // task_complete, task_fail, task_delete, sleep, purchase, sell, refund, loot_drop, craft
 	Title       string           `json:"title"`
 	Description string           `json:"description"`
 	Rewards     *TimelineRewards `json:"rewards,omitempty"`
@@ -375,6 +1065,64 @@ type TimelineResp struct {
 	SleepRecords      int             `json:"sleepRecords"`
 }
 
+// Webhooks
+type WebhookResp struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"` // Key of the X-Life-Signature HMAC
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	CreatedAt string   `json:"createdAt"`
+	UpdatedAt string   `json:"updatedAt"`
+}
+
+type WebhookEventResp struct {
+	Event string `json:"event"`
+	Name  string `json:"name"`
+}
+
+type WebhookListResp struct {
+	Webhooks []WebhookResp      `json:"webhooks"`
+	Events   []WebhookEventResp `json:"events"` // Events that can be subscribed to
+}
+
+type CreateWebhookReq struct {
+	Name    string   `json:"name,optional"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled,optional"` // Defaults to true
+}
+
+type UpdateWebhookReq struct {
+	Name         *string  `json:"name,omitempty"`
+	URL          *string  `json:"url,omitempty"`
+	Events       []string `json:"events,omitempty"` // Replaces all events when present
+	Enabled      *bool    `json:"enabled,omitempty"`
+	RotateSecret bool     `json:"rotateSecret,optional"` // Generate a new signing secret
+}
+
+type WebhookDeliveryListReq struct {
+	Limit int `form:"limit,optional"` // Defaults to 50
+}
+
+type WebhookDeliveryResp struct {
+	ID            int64  `json:"id"`
+	Event         string `json:"event"`
+	Status        string `json:"status"` // pending, success, failed
+	Attempts      int    `json:"attempts"`
+	ResponseCode  int    `json:"responseCode"` // 0 when no response was received
+	Error         string `json:"error"`
+	Payload       string `json:"payload"`
+	CreatedAt     string `json:"createdAt"`
+	NextAttemptAt string `json:"nextAttemptAt"` // Empty unless a retry is scheduled
+	DeliveredAt   string `json:"deliveredAt"`
+}
+
+type WebhookDeliveryListResp struct {
+	Deliveries []WebhookDeliveryResp `json:"deliveries"`
+}
+
 // Common
 type CommonResp struct {
 	Code    int         `json:"code"`
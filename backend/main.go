@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/zeromicro/go-zero/core/conf"
@@ -20,6 +23,7 @@ import (
 )
 
 var configFile = flag.String("f", "etc/config.yaml", "the config file")
+var checkLedger = flag.Bool("check-ledger", false, "verify spirit stone balances against the ledger and exit")
 
 func main() {
 	flag.Parse()
@@ -47,16 +51,25 @@ func main() {
 
 	log.Println("Database initialized successfully")
 
+	if *checkLedger {
+		os.Exit(runLedgerCheck(cfg, db))
+	}
+
 	// Initialize Telegram bot if enabled
 	var bot *telegram.Bot
 	if cfg.Telegram.Enabled {
 		var err error
-		bot, err = telegram.NewBot(cfg.Telegram.BotToken, db)
+		opts := telegram.Options{
+			APIEndpoint:   cfg.Telegram.APIEndpoint,
+			WebhookSecret: cfg.Telegram.WebhookSecret,
+		}
+		if cfg.Telegram.WebhookURL != "" {
+			opts.WebhookURL = strings.TrimRight(cfg.Telegram.WebhookURL, "/") + telegram.WebhookPath
+		}
+		bot, err = telegram.NewBot(cfg.Telegram.BotToken, db, opts)
 		if err != nil {
 			log.Fatalf("Failed to initialize Telegram bot: %v", err)
 		}
-		bot.Start()
-		log.Println("Telegram bot started")
 	}
 
 	// Create service context
@@ -66,10 +79,23 @@ func main() {
 	if bot != nil {
 		taskCompleter := logic.NewTelegramTaskCompleter(svcCtx)
 		bot.SetTaskCompleter(taskCompleter)
+		bot.SetTaskManager(logic.NewTelegramTaskManager(svcCtx))
+		bot.SetCharacterViewer(logic.NewTelegramCharacterViewer(svcCtx))
+		bot.SetShopService(logic.NewTelegramShopService(svcCtx))
+
+		// Start only once every handler is wired
+		if err := bot.Start(); err != nil {
+			log.Fatalf("Failed to start Telegram bot: %v", err)
+		}
+		if bot.Webhook() {
+			log.Printf("Telegram bot started (webhook at %s)", cfg.Telegram.WebhookURL)
+		} else {
+			log.Println("Telegram bot started (long polling)")
+		}
 	}
 
 	// Initialize scheduler (always runs for daily reset and challenge task expiry)
-	sched := scheduler.NewScheduler(bot, svcCtx, 0)
+	sched := scheduler.NewScheduler(svcCtx, 0)
 	sched.Start()
 	log.Println("Task scheduler started (reminders, daily reset, challenge expiry)")
 
@@ -112,3 +138,24 @@ func main() {
 	log.Printf("REST server listening on %s:%d", cfg.Host, cfg.Port)
 	server.Start()
 }
+
+// runLedgerCheck recomputes every balance from the ledger and returns the process exit code.
+func runLedgerCheck(cfg config.Config, db *sql.DB) int {
+	svcCtx := svc.NewServiceContext(cfg, db, nil)
+	mismatches, err := logic.NewLedgerLogic(svcCtx).VerifyLedger(context.Background())
+	if err != nil {
+		log.Printf("Ledger check failed: %v", err)
+		return 2
+	}
+
+	if len(mismatches) == 0 {
+		log.Println("✅ Ledger check passed: all balances match the ledger")
+		return 0
+	}
+
+	for _, m := range mismatches {
+		log.Printf("❌ user %d: %s", m.UserID, m.Detail)
+	}
+	log.Printf("Ledger check found %d inconsistent balance(s)", len(mismatches))
+	return 1
+}
@@ -19,17 +19,17 @@ type Client struct {
 
 // PushOptions contains optional parameters for push notifications
 type PushOptions struct {
-	Title     string // Push title (larger font)
-	Subtitle  string // Push subtitle
-	Sound     string // Notification sound (e.g., "alarm", "bell", "birdsong")
-	Icon      string // Custom icon URL
-	Group     string // Notification group
-	URL       string // Click to open URL
-	Level     string // "active", "timeSensitive", "passive", "critical"
-	Call      bool   // Repeat sound for 30 seconds (like phone call)
-	Badge     int    // App badge number
-	Copy      string // Text to copy
-	AutoCopy  bool   // Auto copy to clipboard
+	Title    string // Push title (larger font)
+	Subtitle string // Push subtitle
+	Sound    string // Notification sound (e.g., "alarm", "bell", "birdsong")
+	Icon     string // Custom icon URL
+	Group    string // Notification group
+	URL      string // Click to open URL
+	Level    string // "active", "timeSensitive", "passive", "critical"
+	Call     bool   // Repeat sound for 30 seconds (like phone call)
+	Badge    int    // App badge number
+	Copy     string // Text to copy
+	AutoCopy bool   // Auto copy to clipboard
 }
 
 // Response from Bark API
@@ -43,7 +43,7 @@ type Response struct {
 func NewClient(serverURL string) *Client {
 	// Remove trailing slash
 	serverURL = strings.TrimRight(serverURL, "/")
-	
+
 	return &Client{
 		serverURL: serverURL,
 		httpClient: &http.Client{
@@ -52,8 +52,42 @@ func NewClient(serverURL string) *Client {
 	}
 }
 
+// ServerURL returns the server the client pushes to
+func (c *Client) ServerURL() string {
+	return c.serverURL
+}
+
 // Push sends a notification to the device
 func (c *Client) Push(deviceKey string, body string, opts *PushOptions) error {
+	return c.post(deviceKey, pushValues(body, opts))
+}
+
+// PushEncrypted sends the notification as ciphertext that only the device can
+// read, so the relay server never sees the content. cryptKey must match the
+// AES-CBC key configured in the Bark app.
+func (c *Client) PushEncrypted(deviceKey, cryptKey string, body string, opts *PushOptions) error {
+	payload := make(map[string]string)
+	for k, v := range pushValues(body, opts) {
+		payload[k] = v[0]
+	}
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	ciphertext, iv, err := Encrypt(cryptKey, plaintext)
+	if err != nil {
+		return err
+	}
+
+	data := url.Values{}
+	data.Set("ciphertext", ciphertext)
+	data.Set("iv", iv)
+	return c.post(deviceKey, data)
+}
+
+// pushValues builds the form fields of a push
+func pushValues(body string, opts *PushOptions) url.Values {
 	if opts == nil {
 		opts = &PushOptions{}
 	}
@@ -61,7 +95,7 @@ func (c *Client) Push(deviceKey string, body string, opts *PushOptions) error {
 	// Build form data
 	data := url.Values{}
 	data.Set("body", body)
-	
+
 	if opts.Title != "" {
 		data.Set("title", opts.Title)
 	}
@@ -96,9 +130,14 @@ func (c *Client) Push(deviceKey string, body string, opts *PushOptions) error {
 		data.Set("autoCopy", "1")
 	}
 
+	return data
+}
+
+// post sends form data to the device endpoint and checks Bark's reply
+func (c *Client) post(deviceKey string, data url.Values) error {
 	// Send POST request
 	pushURL := fmt.Sprintf("%s/%s", c.serverURL, deviceKey)
-	
+
 	req, err := http.NewRequest("POST", pushURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
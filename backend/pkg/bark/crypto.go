@@ -0,0 +1,54 @@
+package bark
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+const ivChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// ValidCryptKey reports whether key can be used for encrypted pushes.
+// Bark accepts 16, 24 or 32 character keys (AES-128/192/256).
+func ValidCryptKey(key string) bool {
+	switch len(key) {
+	case 16, 24, 32:
+		return true
+	}
+	return false
+}
+
+// Encrypt encrypts plaintext the way the Bark app decrypts it: AES-CBC with
+// PKCS7 padding and a random 16 character IV. Returns the base64 ciphertext
+// and the IV, which is sent alongside in plain text.
+func Encrypt(key string, plaintext []byte) (string, string, error) {
+	if !ValidCryptKey(key) {
+		return "", "", fmt.Errorf("bark crypt key must be 16, 24 or 32 characters")
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	for i := range iv {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(ivChars))))
+		if err != nil {
+			return "", "", err
+		}
+		iv[i] = ivChars[n.Int64()]
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return base64.StdEncoding.EncodeToString(ciphertext), string(iv), nil
+}
@@ -0,0 +1,225 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testSecret = "unsubscribe-secret"
+
+// received is what the fake SMTP server was handed in one session
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTP accepts one SMTP session on a local port. It offers no
+// STARTTLS or AUTH, so the sender talks plain SMTP to it.
+func startFakeSMTP(t *testing.T) (port int, done <-chan received) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		ch <- serveSMTP(textproto.NewConn(conn))
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port, ch
+}
+
+func serveSMTP(c *textproto.Conn) received {
+	var r received
+	c.PrintfLine("220 localhost fake SMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return r
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			c.PrintfLine("250-localhost")
+			c.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			r.from = envelopeAddress(line)
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			r.to = append(r.to, envelopeAddress(line))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return r
+			}
+			r.data = string(data)
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return r
+		default:
+			c.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// envelopeAddress extracts the address from "MAIL FROM:<a@b> ..."
+func envelopeAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+var hrefPattern = regexp.MustCompile(`href="([^"]+)"`)
+
+func TestSendRenderedTemplate(t *testing.T) {
+	port, done := startFakeSMTP(t)
+
+	link := UnsubscribeURL("https://life.example.com/", testSecret, 42, "task_reminder")
+	body, err := Render(TemplateReminder, &View{
+		Title:          "任务即将截止",
+		Paragraphs:     []string{"晨跑 将于 1 小时后截止"},
+		KindName:       "任务提醒",
+		UnsubscribeURL: link,
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	sender := NewSender(Config{Host: "127.0.0.1", Port: port, From: "Life System <noreply@example.com>"})
+	err = sender.Send(&Message{
+		To:      "Tester <tester@example.com>",
+		Subject: "任务即将截止",
+		HTML:    body,
+		Headers: map[string]string{"List-Unsubscribe": "<" + link + ">"},
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	r := <-done
+
+	// Envelope
+	if r.from != "noreply@example.com" {
+		t.Errorf("MAIL FROM = %q, want noreply@example.com", r.from)
+	}
+	if len(r.to) != 1 || r.to[0] != "tester@example.com" {
+		t.Errorf("RCPT TO = %v, want [tester@example.com]", r.to)
+	}
+
+	// Headers
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(r.data)))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "任务即将截止" {
+		t.Errorf("Subject = %q (%v), want 任务即将截止", subject, err)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<"+link+">" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := msg.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+
+	// Body
+	encoded, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	// The dot reader has already turned CRLF into LF
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\n") {
+		if len(line) > 76 {
+			t.Errorf("base64 line of %d characters, want at most 76", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\n", ""))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if string(decoded) != body {
+		t.Error("body differs from the rendered template")
+	}
+	for _, want := range []string{"任务即将截止", "晨跑 将于 1 小时后截止", "「任务提醒」", "#f5a623"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %q", want)
+		}
+	}
+
+	// The link in the body is the signed one
+	m := hrefPattern.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatal("body has no unsubscribe link")
+	}
+	if got := html.UnescapeString(m[1]); got != link {
+		t.Errorf("unsubscribe link = %q, want %q", got, link)
+	}
+}
+
+func TestUnsubscribeLinkSignature(t *testing.T) {
+	link, err := url.Parse(UnsubscribeURL("https://life.example.com", testSecret, 42, "task_reminder"))
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	if link.Path != "/api/email/unsubscribe" {
+		t.Errorf("path = %q, want /api/email/unsubscribe", link.Path)
+	}
+
+	q := link.Query()
+	uid, err := strconv.ParseInt(q.Get("uid"), 10, 64)
+	if err != nil {
+		t.Fatalf("uid: %v", err)
+	}
+	kind, sig := q.Get("kind"), q.Get("sig")
+	if !VerifyUnsubscribe(testSecret, uid, kind, sig) {
+		t.Fatal("signed link does not verify")
+	}
+
+	altered := "0" + sig[1:]
+	if sig[0] == '0' {
+		altered = "1" + sig[1:]
+	}
+
+	tampered := []struct {
+		name   string
+		secret string
+		uid    int64
+		kind   string
+		sig    string
+	}{
+		{"other user", testSecret, uid + 1, kind, sig},
+		{"other kind", testSecret, uid, UnsubscribeAll, sig},
+		{"altered signature", testSecret, uid, kind, altered},
+		{"missing signature", testSecret, uid, kind, ""},
+		{"other secret", "another-secret", uid, kind, sig},
+	}
+	for _, tt := range tampered {
+		if VerifyUnsubscribe(tt.secret, tt.uid, tt.kind, tt.sig) {
+			t.Errorf("%s: tampered link verifies", tt.name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// Template names
+const (
+	TemplateReminder        = "reminder"
+	TemplateChallengeFailed = "challenge_failed"
+	TemplateDailyDigest     = "daily_digest"
+	TemplateNotification    = "notification" // Generic fallback
+	TemplateVerify          = "verify"
+)
+
+// View is the data every template renders from
+type View struct {
+	Title             string
+	Paragraphs        []string
+	Items             []string // Digest lines
+	Code              string   // Verification code
+	KindName          string   // Shown in the footer as the reason for the mail
+	UnsubscribeURL    string
+	UnsubscribeAllURL string
+	Accent            string // Header colour; defaults per template
+}
+
+var accents = map[string]string{
+	TemplateReminder:        "#f5a623",
+	TemplateChallengeFailed: "#c0392b",
+	TemplateDailyDigest:     "#3a7d5c",
+}
+
+// Render executes the named template, falling back to the generic one for
+// unknown names.
+func Render(name string, view *View) (string, error) {
+	if templates.Lookup(name+".html") == nil {
+		name = TemplateNotification
+	}
+	if view.Accent == "" {
+		view.Accent = accents[name]
+		if view.Accent == "" {
+			view.Accent = "#4a4e69"
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name+".html", view); err != nil {
+		return "", fmt.Errorf("render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
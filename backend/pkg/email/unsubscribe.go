@@ -0,0 +1,36 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// UnsubscribeAll is the kind of links that opt out of all notification mail
+const UnsubscribeAll = "all"
+
+// SignUnsubscribe returns the signature for an unsubscribe link of userID
+// and kind, so links cannot be forged for other users.
+func SignUnsubscribe(secret string, userID int64, kind string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("unsubscribe:" + strconv.FormatInt(userID, 10) + ":" + kind))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyUnsubscribe checks a signature produced by SignUnsubscribe
+func VerifyUnsubscribe(secret string, userID int64, kind, sig string) bool {
+	expected := SignUnsubscribe(secret, userID, kind)
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+// UnsubscribeURL builds the signed link under baseURL
+func UnsubscribeURL(baseURL, secret string, userID int64, kind string) string {
+	q := url.Values{}
+	q.Set("uid", strconv.FormatInt(userID, 10))
+	q.Set("kind", kind)
+	q.Set("sig", SignUnsubscribe(secret, userID, kind))
+	return strings.TrimRight(baseURL, "/") + "/api/email/unsubscribe?" + q.Encode()
+}
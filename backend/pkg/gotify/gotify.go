@@ -0,0 +1,70 @@
+package gotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client sends messages to Gotify servers
+type Client struct {
+	httpClient *http.Client
+}
+
+// Message is one notification. Gotify clients stay silent below priority 4
+// and show a heads-up notification from priority 8.
+type Message struct {
+	Title    string `json:"title,omitempty"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"` // 0-10
+}
+
+// errorResponse is returned by Gotify on failures
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"errorDescription"`
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Send posts msg to serverURL with an application token
+func (c *Client) Send(serverURL, appToken string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	pushURL := strings.TrimRight(serverURL, "/") + "/message"
+	req, err := http.NewRequest("POST", pushURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", appToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		var result errorResponse
+		if json.Unmarshal(reply, &result) == nil && result.ErrorDescription != "" {
+			return fmt.Errorf("gotify API error: %s (status: %d)", result.ErrorDescription, resp.StatusCode)
+		}
+		return fmt.Errorf("gotify API error: status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/pkg/bark"
+)
+
+// BarkChannel pushes notifications to the user's Bark device, through the
+// user's own server when one is set.
+type BarkChannel struct {
+	client  *bark.Client // Default server
+	mu      sync.Mutex
+	clients map[string]*bark.Client
+}
+
+func NewBarkChannel(client *bark.Client) *BarkChannel {
+	return &BarkChannel{
+		client:  client,
+		clients: make(map[string]*bark.Client),
+	}
+}
+
+func (c *BarkChannel) Name() string {
+	return ChannelBark
+}
+
+func (c *BarkChannel) Enabled(user *model.User) bool {
+	return user.BarkKey != ""
+}
+
+func (c *BarkChannel) Send(user *model.User, n *Notification) error {
+	client := c.clientFor(user.BarkServer)
+	if user.BarkCryptKey != "" {
+		return client.PushEncrypted(user.BarkKey, user.BarkCryptKey, n.Body, barkOptions(n))
+	}
+	return client.Push(user.BarkKey, n.Body, barkOptions(n))
+}
+
+// clientFor returns the client of a self-hosted server, reusing clients
+// across pushes. An empty server means the default one.
+func (c *BarkChannel) clientFor(server string) *bark.Client {
+	server = strings.TrimRight(server, "/")
+	if server == "" || server == c.client.ServerURL() {
+		return c.client
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.clients[server]
+	if !ok {
+		client = bark.NewClient(server)
+		c.clients[server] = client
+	}
+	return client
+}
+
+// barkOptions maps the level of n onto Bark's sound and interruption level
+func barkOptions(n *Notification) *bark.PushOptions {
+	opts := &bark.PushOptions{
+		Title: n.Title,
+		Group: string(n.Kind),
+		Level: string(n.Level),
+	}
+
+	switch {
+	case n.Alarm:
+		opts.Sound = "alarm"
+		opts.Call = true // Repeat sound for 30 seconds
+	case n.Level == LevelCritical:
+		opts.Sound = "alarm"
+	case n.Level == LevelTimeSensitive:
+		opts.Sound = "bell"
+	}
+	return opts
+}
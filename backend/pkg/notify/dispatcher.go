@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// UserStore loads notification recipients
+type UserStore interface {
+	FindByID(id int64) (*model.User, error)
+}
+
+// Store loads per-kind overrides and keeps the notification outbox
+type Store interface {
+	FindPreference(userID int64, kind string) (*model.NotificationPreference, error)
+	Enqueue(item *model.OutboxItem) error
+	FindDueOutbox(now time.Time, limit int) ([]*model.OutboxItem, error)
+	UpdateOutbox(item *model.OutboxItem) error
+}
+
+// Dispatcher routes notifications to the channels each user enabled. They
+// are queued in the outbox first and delivered from there, with retries.
+type Dispatcher struct {
+	users       UserStore
+	store       Store
+	channels    []Channel
+	mu          sync.Mutex // One delivery run at a time, so no item is sent twice
+	onDelivered func(item *model.OutboxItem)
+}
+
+func NewDispatcher(users UserStore, store Store, channels ...Channel) *Dispatcher {
+	return &Dispatcher{
+		users:    users,
+		store:    store,
+		channels: channels,
+	}
+}
+
+// Register adds a channel after construction
+func (d *Dispatcher) Register(ch Channel) {
+	d.channels = append(d.channels, ch)
+}
+
+// Channel returns the registered channel called name
+func (d *Dispatcher) Channel(name string) Channel {
+	for _, ch := range d.channels {
+		if ch.Name() == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+// ChannelNames lists the registered channels
+func (d *Dispatcher) ChannelNames() []string {
+	names := make([]string, 0, len(d.channels))
+	for _, ch := range d.channels {
+		names = append(names, ch.Name())
+	}
+	return names
+}
+
+// Notify queues n for the user with the given ID
+func (d *Dispatcher) Notify(userID int64, n *Notification) error {
+	user, err := d.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %d not found", userID)
+	}
+
+	return d.Send(user, n)
+}
+
+// NotifyAsync queues in the background and only logs failures. Used after a
+// transaction committed, where notifying must not hold up the response.
+func (d *Dispatcher) NotifyAsync(userID int64, n *Notification) {
+	go func() {
+		if err := d.Notify(userID, n); err != nil {
+			log.Printf("Error sending %s notification to user %d: %v", n.Kind, userID, err)
+		}
+	}()
+}
+
+// Send queues n for the channels the user enabled for its kind, at the
+// urgency the user chose for it, and starts delivering it. Non-urgent
+// notifications arriving in the user's quiet hours wait in the outbox until
+// the quiet hours end. Kinds no channel delivers to the user are dropped.
+func (d *Dispatcher) Send(user *model.User, n *Notification) error {
+	pref, err := d.store.FindPreference(user.ID, string(n.Kind))
+	if err != nil {
+		return err
+	}
+	if len(d.resolve(user, n.Kind, pref)) == 0 {
+		return nil
+	}
+
+	n = withLevel(n, pref)
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	due := now
+	if quiet := UserQuietHours(user); quiet != nil && !n.Level.Urgent() && quiet.Active(now) {
+		due = quiet.Ends(now)
+	}
+
+	err = d.store.Enqueue(&model.OutboxItem{
+		UserID:        user.ID,
+		Kind:          string(n.Kind),
+		Payload:       string(payload),
+		NextAttemptAt: sql.NullTime{Time: due, Valid: true},
+		RefType:       n.RefType,
+		RefID:         n.RefID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !due.After(now) {
+		d.Kick()
+	}
+	return nil
+}
+
+// Held reports whether Send would defer n for user right now
+func (d *Dispatcher) Held(user *model.User, n *Notification) (bool, error) {
+	quiet := UserQuietHours(user)
+	if quiet == nil || !quiet.Active(time.Now()) {
+		return false, nil
+	}
+
+	pref, err := d.store.FindPreference(user.ID, string(n.Kind))
+	if err != nil {
+		return false, err
+	}
+	return !withLevel(n, pref).Level.Urgent(), nil
+}
+
+// Resolve returns the channels that deliver kind to user: those the user
+// configured, narrowed by the user's preference or the kind's defaults.
+func (d *Dispatcher) Resolve(user *model.User, kind Kind) ([]Channel, error) {
+	pref, err := d.store.FindPreference(user.ID, string(kind))
+	if err != nil {
+		return nil, err
+	}
+	return d.resolve(user, kind, pref), nil
+}
+
+func (d *Dispatcher) resolve(user *model.User, kind Kind, pref *model.NotificationPreference) []Channel {
+	var wanted []string
+	if pref != nil && pref.Channels != nil {
+		wanted = pref.Channels
+	} else if info, ok := LookupKind(kind); ok && info.Defaults != nil {
+		wanted = info.Defaults
+	} else {
+		wanted = d.ChannelNames()
+	}
+
+	var channels []Channel
+	for _, ch := range d.channels {
+		if slices.Contains(wanted, ch.Name()) && ch.Enabled(user) {
+			channels = append(channels, ch)
+		}
+	}
+	return channels
+}
+
+// withLevel returns n at the urgency in effect for the user. A level chosen
+// by the user replaces the sender's entirely, alarm included.
+func withLevel(n *Notification, pref *model.NotificationPreference) *Notification {
+	out := *n
+	if out.Level == "" {
+		if info, ok := LookupKind(n.Kind); ok {
+			out.Level = info.Level
+		}
+	}
+	if pref != nil && pref.Level != "" {
+		out.Level = Level(pref.Level)
+		out.Alarm = false
+	}
+	return &out
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"strings"
+
+	"life-system-backend/internal/model"
+	"life-system-backend/pkg/email"
+)
+
+// Mailer is the part of email.Sender used for notifications
+type Mailer interface {
+	Send(msg *email.Message) error
+}
+
+// EmailChannel mails notifications to the user's verified address, with a
+// signed unsubscribe link in every mail.
+type EmailChannel struct {
+	mailer  Mailer
+	baseURL string // Public API address the unsubscribe links point to
+	secret  string // Signs unsubscribe links
+}
+
+func NewEmailChannel(mailer Mailer, baseURL, secret string) *EmailChannel {
+	return &EmailChannel{
+		mailer:  mailer,
+		baseURL: baseURL,
+		secret:  secret,
+	}
+}
+
+func (c *EmailChannel) Name() string {
+	return ChannelEmail
+}
+
+func (c *EmailChannel) Enabled(user *model.User) bool {
+	return user.Email != "" && user.EmailVerified && !user.EmailUnsubscribed
+}
+
+func (c *EmailChannel) Send(user *model.User, n *Notification) error {
+	view := &email.View{Title: n.Title}
+	lines := splitLines(n.Body)
+	if n.Kind == KindDailyDigest || n.Kind == KindWeeklyDigest {
+		view.Items = lines
+	} else {
+		view.Paragraphs = lines
+	}
+	if info, ok := LookupKind(n.Kind); ok {
+		view.KindName = info.Name
+	}
+
+	headers := map[string]string{}
+	if c.baseURL != "" {
+		view.UnsubscribeAllURL = email.UnsubscribeURL(c.baseURL, c.secret, user.ID, email.UnsubscribeAll)
+		link := view.UnsubscribeAllURL
+		if n.Kind != "" {
+			view.UnsubscribeURL = email.UnsubscribeURL(c.baseURL, c.secret, user.ID, string(n.Kind))
+			link = view.UnsubscribeURL
+		}
+		// One-click unsubscribe (RFC 8058) from the mail client
+		headers["List-Unsubscribe"] = "<" + link + ">"
+		headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+
+	html, err := email.Render(emailTemplate(n.Kind), view)
+	if err != nil {
+		return err
+	}
+
+	return c.mailer.Send(&email.Message{
+		To:      user.Email,
+		Subject: n.Title,
+		HTML:    html,
+		Headers: headers,
+	})
+}
+
+// emailTemplate picks the template for kind; other kinds use the generic one
+func emailTemplate(kind Kind) string {
+	switch kind {
+	case KindTaskReminder:
+		return email.TemplateReminder
+	case KindChallengeFailed:
+		return email.TemplateChallengeFailed
+	case KindDailyDigest, KindWeeklyDigest:
+		return email.TemplateDailyDigest
+	}
+	return email.TemplateNotification
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"life-system-backend/internal/model"
+	"life-system-backend/pkg/gotify"
+)
+
+// GotifyChannel sends notifications to the user's Gotify server.
+type GotifyChannel struct {
+	client *gotify.Client
+}
+
+func NewGotifyChannel(client *gotify.Client) *GotifyChannel {
+	return &GotifyChannel{client: client}
+}
+
+func (c *GotifyChannel) Name() string {
+	return ChannelGotify
+}
+
+func (c *GotifyChannel) Enabled(user *model.User) bool {
+	return user.GotifyServer != "" && user.GotifyToken != ""
+}
+
+func (c *GotifyChannel) Send(user *model.User, n *Notification) error {
+	return c.client.Send(user.GotifyServer, user.GotifyToken, &gotify.Message{
+		Title:    n.Title,
+		Message:  n.Body,
+		Priority: gotifyPriority(n),
+	})
+}
+
+// gotifyPriority maps the level of n onto Gotify's 0-10 priorities: silent
+// below 4, heads-up from 8
+func gotifyPriority(n *Notification) int {
+	switch {
+	case n.Alarm || n.Level == LevelCritical:
+		return 10
+	case n.Level == LevelTimeSensitive:
+		return 8
+	case n.Level == LevelPassive:
+		return 2
+	}
+	return 5
+}
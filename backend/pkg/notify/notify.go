@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"life-system-backend/internal/model"
+)
+
+// Kind identifies what a notification is about. Users choose channels per kind.
+type Kind string
+
+const (
+	KindTaskReminder      Kind = "task_reminder"      // Task deadline approaching
+	KindChallengeFailed   Kind = "challenge_failed"   // Challenge task missed its deadline
+	KindAttributeDecay    Kind = "attribute_decay"    // Attributes decayed after inactivity
+	KindStreakShield      Kind = "streak_shield"      // A streak shield absorbed decay
+	KindLootDrop          Kind = "loot_drop"          // Items dropped on task completion
+	KindItemExpiring      Kind = "item_expiring"      // Bag items about to expire
+	KindItemExpired       Kind = "item_expired"       // Bag items removed after expiring
+	KindRedemptionPending Kind = "redemption_pending" // A redemption awaits the user's approval
+	KindPartnerRequest    Kind = "partner_request"    // Someone asked the user to be their partner
+	KindDailyDigest       Kind = "daily_digest"       // Morning briefing of the day ahead
+	KindWeeklyDigest      Kind = "weekly_digest"      // Review of the past week
+)
+
+// Channel names
+const (
+	ChannelTelegram = "telegram"
+	ChannelBark     = "bark"
+	ChannelEmail    = "email"
+	ChannelNtfy     = "ntfy"
+	ChannelGotify   = "gotify"
+)
+
+// Level is how intrusive a notification is. Values follow Bark's interruption levels.
+type Level string
+
+const (
+	LevelPassive       Level = "passive"       // Silently added to the notification list
+	LevelActive        Level = "active"        // Normal notification
+	LevelTimeSensitive Level = "timeSensitive" // Breaks through focus modes
+	LevelCritical      Level = "critical"      // Ignores silent and do-not-disturb
+)
+
+// Levels lists the levels from least to most intrusive
+var Levels = []Level{LevelPassive, LevelActive, LevelTimeSensitive, LevelCritical}
+
+// Urgent reports whether notifications of level bypass quiet hours
+func (l Level) Urgent() bool {
+	return l == LevelCritical
+}
+
+// Action is a button attached to a notification on channels that support it.
+type Action struct {
+	Text string
+	Data string // Callback data, e.g. "complete:12"
+}
+
+// Notification is one message to one user, rendered by each channel.
+type Notification struct {
+	Kind    Kind
+	Title   string // Short headline for push channels
+	Body    string
+	Message string // Full text for chat channels; defaults to Title and Body
+	Level   Level
+	Alarm   bool // Ring repeatedly where supported
+	Actions []Action
+	RefType string // What it is about, e.g. "task"; a newer notification about the same thing replaces a queued one
+	RefID   int64
+}
+
+// Text returns the full text used by chat channels
+func (n *Notification) Text() string {
+	if n.Message != "" {
+		return n.Message
+	}
+	if n.Body == "" {
+		return n.Title
+	}
+	return n.Title + "\n" + n.Body
+}
+
+// Channel delivers notifications over one transport.
+type Channel interface {
+	Name() string
+	// Enabled reports whether the user configured this channel
+	Enabled(user *model.User) bool
+	Send(user *model.User, n *Notification) error
+}
+
+// KindInfo describes a notification kind for the preference settings.
+type KindInfo struct {
+	Kind     Kind
+	Name     string
+	Defaults []string // Channels used without a user preference; nil for all
+	Level    Level    // Urgency used without a user preference
+}
+
+// Kinds lists the notification kinds in display order.
+var Kinds = []KindInfo{
+	{Kind: KindTaskReminder, Name: "任务截止提醒", Level: LevelTimeSensitive},
+	{Kind: KindChallengeFailed, Name: "挑战失败", Level: LevelTimeSensitive},
+	{Kind: KindAttributeDecay, Name: "属性衰减", Level: LevelActive},
+	{Kind: KindStreakShield, Name: "护体生效", Level: LevelPassive},
+	{Kind: KindLootDrop, Name: "任务掉落", Defaults: []string{ChannelBark}, Level: LevelPassive},
+	{Kind: KindItemExpiring, Name: "物品即将过期", Level: LevelPassive},
+	{Kind: KindItemExpired, Name: "物品已过期", Level: LevelPassive},
+	{Kind: KindRedemptionPending, Name: "兑换待审批", Level: LevelTimeSensitive},
+	{Kind: KindPartnerRequest, Name: "道侣邀请", Level: LevelActive},
+	{Kind: KindDailyDigest, Name: "每日简报", Level: LevelPassive},
+	{Kind: KindWeeklyDigest, Name: "每周回顾", Level: LevelPassive},
+}
+
+// LookupKind returns the description of kind
+func LookupKind(kind Kind) (KindInfo, bool) {
+	for _, info := range Kinds {
+		if info.Kind == kind {
+			return info, true
+		}
+	}
+	return KindInfo{}, false
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"life-system-backend/internal/model"
+	"life-system-backend/pkg/ntfy"
+)
+
+// NtfyChannel publishes notifications to the user's ntfy topic.
+type NtfyChannel struct {
+	client *ntfy.Client
+}
+
+func NewNtfyChannel(client *ntfy.Client) *NtfyChannel {
+	return &NtfyChannel{client: client}
+}
+
+func (c *NtfyChannel) Name() string {
+	return ChannelNtfy
+}
+
+func (c *NtfyChannel) Enabled(user *model.User) bool {
+	return user.NtfyTopicURL != ""
+}
+
+func (c *NtfyChannel) Send(user *model.User, n *Notification) error {
+	msg := &ntfy.Message{
+		Title:    n.Title,
+		Message:  n.Body,
+		Priority: ntfyPriority(n),
+	}
+	if n.Kind != "" {
+		msg.Tags = []string{string(n.Kind)}
+	}
+	return c.client.Publish(user.NtfyTopicURL, user.NtfyToken, msg)
+}
+
+// ntfyPriority maps the level of n onto ntfy's 1-5 priorities
+func ntfyPriority(n *Notification) int {
+	switch {
+	case n.Alarm || n.Level == LevelCritical:
+		return ntfy.PriorityUrgent
+	case n.Level == LevelTimeSensitive:
+		return ntfy.PriorityHigh
+	case n.Level == LevelPassive:
+		return ntfy.PriorityLow
+	}
+	return ntfy.PriorityDefault
+}
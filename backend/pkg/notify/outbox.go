@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+const (
+	// OutboxMaxAttempts is how often delivery is tried before an item is marked failed
+	OutboxMaxAttempts = 6
+	// outboxFirstRetry doubles after every failed attempt: 1m, 2m, 4m ... ~30m in total
+	outboxFirstRetry = time.Minute
+	outboxBatch      = 100
+)
+
+// OnDelivered sets fn to run once a channel first delivered an item, e.g. to
+// record that a reminder went out. fn runs inside the delivery run.
+func (d *Dispatcher) OnDelivered(fn func(item *model.OutboxItem)) {
+	d.onDelivered = fn
+}
+
+// Kick delivers due outbox items in the background, e.g. right after a
+// notification was queued instead of waiting for the scheduler.
+func (d *Dispatcher) Kick() {
+	go func() {
+		if _, err := d.DeliverDue(time.Now()); err != nil {
+			log.Printf("Error delivering notifications: %v", err)
+		}
+	}()
+}
+
+// DeliverDue attempts every pending outbox item that is due and returns how
+// many were attempted. An item that fails is logged and retried later
+// without holding up the items after it.
+func (d *Dispatcher) DeliverDue(now time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	due, err := d.store.FindDueOutbox(now, outboxBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range due {
+		if err := d.deliver(item, now); err != nil {
+			log.Printf("Error delivering notification %d to user %d: %v", item.ID, item.UserID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// deliver sends item over the channels it still has to reach and records
+// the outcome. Channels are resolved on the first attempt, so preferences
+// changed while an item was deferred still apply.
+func (d *Dispatcher) deliver(item *model.OutboxItem, now time.Time) error {
+	var n Notification
+	if err := json.Unmarshal([]byte(item.Payload), &n); err != nil {
+		return d.finish(item, model.OutboxFailed, err)
+	}
+	user, err := d.users.FindByID(item.UserID)
+	if err != nil {
+		return d.lookupFailed(item, now, fmt.Errorf("find user: %w", err))
+	}
+	if user == nil {
+		return d.finish(item, model.OutboxFailed, fmt.Errorf("user %d not found", item.UserID))
+	}
+
+	if item.Attempts == 0 {
+		// Released early or quiet hours changed since it was queued
+		if quiet := UserQuietHours(user); quiet != nil && !n.Level.Urgent() && quiet.Active(now) {
+			item.NextAttemptAt.Time = quiet.Ends(now)
+			return d.store.UpdateOutbox(item)
+		}
+	}
+	if item.Channels == nil {
+		channels, err := d.Resolve(user, n.Kind)
+		if err != nil {
+			return d.lookupFailed(item, now, fmt.Errorf("resolve channels: %w", err))
+		}
+		item.Channels = make([]string, 0, len(channels))
+		for _, ch := range channels {
+			item.Channels = append(item.Channels, ch.Name())
+		}
+	}
+
+	firstDelivery := !item.SentAt.Valid
+	remaining := []string{}
+	var errs []error
+	for _, name := range item.Channels {
+		ch := d.Channel(name)
+		if ch == nil || !ch.Enabled(user) {
+			// Removed or unconfigured since; there is nothing to retry
+			continue
+		}
+		if err := ch.Send(user, &n); err != nil {
+			remaining = append(remaining, name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		item.Delivered = append(item.Delivered, name)
+		if !item.SentAt.Valid {
+			item.SentAt.Time, item.SentAt.Valid = now, true
+		}
+	}
+	item.Channels = remaining
+	item.Attempts++
+
+	switch {
+	case len(remaining) == 0 && len(item.Delivered) > 0:
+		err = d.finish(item, model.OutboxSent, nil)
+	case len(remaining) == 0:
+		err = d.finish(item, model.OutboxSkipped, nil)
+	default:
+		err = d.scheduleRetry(item, now, errors.Join(errs...))
+	}
+	if err != nil {
+		return err
+	}
+
+	if firstDelivery && item.SentAt.Valid && d.onDelivered != nil {
+		d.onDelivered(item)
+	}
+	return nil
+}
+
+// lookupFailed counts an attempt that failed before any channel was tried,
+// e.g. because the user could not be loaded, and schedules a retry. It
+// returns err so the caller can log it.
+func (d *Dispatcher) lookupFailed(item *model.OutboxItem, now time.Time, err error) error {
+	item.Attempts++
+	if updateErr := d.scheduleRetry(item, now, err); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// scheduleRetry backs off after a failed attempt already counted in
+// item.Attempts, or marks item failed once it is out of attempts
+func (d *Dispatcher) scheduleRetry(item *model.OutboxItem, now time.Time, err error) error {
+	if item.Attempts >= OutboxMaxAttempts {
+		return d.finish(item, model.OutboxFailed, err)
+	}
+	item.Error = err.Error()
+	item.NextAttemptAt.Time = now.Add(outboxFirstRetry << (item.Attempts - 1))
+	return d.store.UpdateOutbox(item)
+}
+
+// finish ends the delivery of item with status
+func (d *Dispatcher) finish(item *model.OutboxItem, status string, err error) error {
+	item.Status = status
+	item.Error = ""
+	if err != nil {
+		item.Error = err.Error()
+	}
+	item.NextAttemptAt.Valid = false
+	return d.store.UpdateOutbox(item)
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// fakeUsers fails to load the users in broken
+type fakeUsers struct {
+	broken map[int64]bool
+}
+
+func (u *fakeUsers) FindByID(id int64) (*model.User, error) {
+	if u.broken[id] {
+		return nil, errors.New("database is locked")
+	}
+	return &model.User{ID: id}, nil
+}
+
+// fakeStore keeps the outbox in memory
+type fakeStore struct {
+	outbox []*model.OutboxItem
+}
+
+func (s *fakeStore) FindPreference(userID int64, kind string) (*model.NotificationPreference, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) Enqueue(item *model.OutboxItem) error {
+	item.ID = int64(len(s.outbox) + 1)
+	item.Status = model.OutboxPending
+	s.outbox = append(s.outbox, item)
+	return nil
+}
+
+func (s *fakeStore) FindDueOutbox(now time.Time, limit int) ([]*model.OutboxItem, error) {
+	var due []*model.OutboxItem
+	for _, item := range s.outbox {
+		if item.Status == model.OutboxPending && !item.NextAttemptAt.Time.After(now) && len(due) < limit {
+			due = append(due, item)
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeStore) UpdateOutbox(item *model.OutboxItem) error {
+	return nil
+}
+
+// recordingChannel delivers everything and remembers to whom
+type recordingChannel struct {
+	sentTo []int64
+}
+
+func (c *recordingChannel) Name() string                  { return "test" }
+func (c *recordingChannel) Enabled(user *model.User) bool { return true }
+
+func (c *recordingChannel) Send(user *model.User, n *Notification) error {
+	c.sentTo = append(c.sentTo, user.ID)
+	return nil
+}
+
+func queue(store *fakeStore, userID int64, at time.Time) *model.OutboxItem {
+	item := &model.OutboxItem{
+		UserID:        userID,
+		Kind:          string(KindTaskReminder),
+		Payload:       `{"Kind":"task_reminder","Title":"任务提醒"}`,
+		NextAttemptAt: sql.NullTime{Time: at, Valid: true},
+	}
+	store.Enqueue(item)
+	return item
+}
+
+func TestDeliverDueSkipsItemsWhoseUserFailsToLoad(t *testing.T) {
+	now := time.Now()
+	store := &fakeStore{}
+	ch := &recordingChannel{}
+	d := NewDispatcher(&fakeUsers{broken: map[int64]bool{1: true}}, store, ch)
+
+	bad := queue(store, 1, now)
+	good := queue(store, 2, now)
+
+	attempted, err := d.DeliverDue(now)
+	if err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if attempted != 2 {
+		t.Errorf("attempted %d, want 2", attempted)
+	}
+
+	if good.Status != model.OutboxSent || len(ch.sentTo) != 1 || ch.sentTo[0] != 2 {
+		t.Errorf("item after the failing one: status %s, sent to %v; want it delivered", good.Status, ch.sentTo)
+	}
+
+	if bad.Status != model.OutboxPending || bad.Attempts != 1 || bad.Error == "" {
+		t.Errorf("failing item: status %s, %d attempts, error %q; want a recorded failed attempt", bad.Status, bad.Attempts, bad.Error)
+	}
+	if want := now.Add(outboxFirstRetry); !bad.NextAttemptAt.Time.Equal(want) {
+		t.Errorf("failing item retries at %v, want %v", bad.NextAttemptAt.Time, want)
+	}
+}
+
+func TestDeliverDueGivesUpOnUnloadableUser(t *testing.T) {
+	now := time.Now()
+	store := &fakeStore{}
+	d := NewDispatcher(&fakeUsers{broken: map[int64]bool{1: true}}, store, &recordingChannel{})
+
+	item := queue(store, 1, now)
+	for i := 0; i < OutboxMaxAttempts; i++ {
+		now = item.NextAttemptAt.Time
+		if _, err := d.DeliverDue(now); err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+	}
+
+	if item.Status != model.OutboxFailed || item.Attempts != OutboxMaxAttempts {
+		t.Errorf("status %s after %d attempts, want %s after %d", item.Status, item.Attempts, model.OutboxFailed, OutboxMaxAttempts)
+	}
+}
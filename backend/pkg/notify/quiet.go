@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// QuietHours is a daily window in which non-urgent notifications are held
+// back. The window wraps past midnight when End is before Start.
+type QuietHours struct {
+	Start    int // Minutes after local midnight
+	End      int
+	Location *time.Location
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// UserLocation returns the timezone of user, the server's zone when unset or unknown
+func UserLocation(user *model.User) *time.Location {
+	if user.Timezone != "" {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// UserQuietHours returns the quiet hours of user, nil when they are off
+func UserQuietHours(user *model.User) *QuietHours {
+	if user.QuietStart == "" || user.QuietEnd == "" {
+		return nil
+	}
+	start, err := ParseClock(user.QuietStart)
+	if err != nil {
+		return nil
+	}
+	end, err := ParseClock(user.QuietEnd)
+	if err != nil || start == end {
+		return nil
+	}
+	return &QuietHours{Start: start, End: end, Location: UserLocation(user)}
+}
+
+// Active reports whether t falls inside the quiet hours
+func (q *QuietHours) Active(t time.Time) bool {
+	local := t.In(q.Location)
+	minute := local.Hour()*60 + local.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+// Ends returns the first end of the quiet hours after t
+func (q *QuietHours) Ends(t time.Time) time.Time {
+	local := t.In(q.Location)
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.End/60, q.End%60, 0, 0, q.Location)
+	if !end.After(local) {
+		end = time.Date(local.Year(), local.Month(), local.Day()+1, q.End/60, q.End%60, 0, 0, q.Location)
+	}
+	return end
+}
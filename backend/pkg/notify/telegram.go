@@ -0,0 +1,43 @@
+package notify
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"life-system-backend/internal/model"
+)
+
+// TelegramSender is the part of telegram.Bot used for notifications
+type TelegramSender interface {
+	SendMessage(chatID int64, text string) error
+	SendMessageWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) error
+}
+
+// TelegramChannel sends notifications to the chat bound to the user.
+type TelegramChannel struct {
+	bot TelegramSender
+}
+
+func NewTelegramChannel(bot TelegramSender) *TelegramChannel {
+	return &TelegramChannel{bot: bot}
+}
+
+func (c *TelegramChannel) Name() string {
+	return ChannelTelegram
+}
+
+func (c *TelegramChannel) Enabled(user *model.User) bool {
+	return user.TgChatID > 0
+}
+
+func (c *TelegramChannel) Send(user *model.User, n *Notification) error {
+	if len(n.Actions) == 0 {
+		return c.bot.SendMessage(user.TgChatID, n.Text())
+	}
+
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(n.Actions))
+	for _, a := range n.Actions {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(a.Text, a.Data))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+
+	return c.bot.SendMessageWithKeyboard(user.TgChatID, n.Text(), keyboard)
+}
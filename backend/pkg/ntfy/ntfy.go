@@ -0,0 +1,115 @@
+package ntfy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Priorities understood by ntfy
+const (
+	PriorityMin     = 1
+	PriorityLow     = 2
+	PriorityDefault = 3
+	PriorityHigh    = 4
+	PriorityUrgent  = 5
+)
+
+// Client publishes messages to ntfy topics
+type Client struct {
+	httpClient *http.Client
+}
+
+// Message is one published notification
+type Message struct {
+	Title    string
+	Message  string
+	Priority int      // 1-5, 0 for the server default
+	Tags     []string // Tags matching emoji short codes are shown as emojis
+	Click    string   // URL opened when the notification is tapped
+}
+
+// publishRequest is the JSON body of a publish to the server root
+type publishRequest struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// SplitTopicURL splits a topic URL like "https://ntfy.sh/my-topic" into the
+// server and the topic name.
+func SplitTopicURL(topicURL string) (server, topic string, err error) {
+	u, err := url.Parse(strings.TrimRight(topicURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid topic url: %s", topicURL)
+	}
+
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 || u.Path[i+1:] == "" {
+		return "", "", fmt.Errorf("topic url has no topic: %s", topicURL)
+	}
+	topic = u.Path[i+1:]
+	u.Path = u.Path[:i]
+	u.RawQuery, u.Fragment = "", ""
+
+	return u.String(), topic, nil
+}
+
+// Publish sends msg to the topic. Messages are published as JSON so titles
+// need no header encoding. token is an access token for protected topics,
+// empty for public ones.
+func (c *Client) Publish(topicURL, token string, msg *Message) error {
+	server, topic, err := SplitTopicURL(topicURL)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&publishRequest{
+		Topic:    topic,
+		Title:    msg.Title,
+		Message:  msg.Message,
+		Priority: msg.Priority,
+		Tags:     msg.Tags,
+		Click:    msg.Click,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", server+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("ntfy API error: %s (status: %d)", strings.TrimSpace(string(reply)), resp.StatusCode)
+	}
+	return nil
+}
@@ -4,37 +4,46 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"life-system-backend/internal/logic"
 	"life-system-backend/internal/model"
 	"life-system-backend/internal/realm"
 	"life-system-backend/internal/svc"
-	"life-system-backend/pkg/bark"
-	"life-system-backend/pkg/telegram"
+	"life-system-backend/pkg/notify"
+	"life-system-backend/pkg/webhook"
+)
+
+const (
+	// snapshotRetentionDays is how long daily attribute snapshots are kept for reports
+	snapshotRetentionDays = 35
+	// outboxRetentionDays is how long finished notifications stay listed
+	outboxRetentionDays = 30
+	// reminderRefType marks reminders in the outbox; their ref ID is the task
+	reminderRefType = "task"
 )
 
 type Scheduler struct {
-	bot           *telegram.Bot
-	barkClient    *bark.Client
-	taskModel     *model.TaskModel
-	charModel     *model.CharacterModel
-	svcCtx        *svc.ServiceContext
-	interval      time.Duration
-	stop          chan struct{}
-	running       bool
-	lastResetDate string
+	notifier         *notify.Dispatcher
+	taskModel        *model.TaskModel
+	charModel        *model.CharacterModel
+	svcCtx           *svc.ServiceContext
+	interval         time.Duration
+	stop             chan struct{}
+	running          bool
+	lastResetDate    string
+	lastSnapshotDate string
+	lastOutboxPrune  string
 }
 
-func NewScheduler(bot *telegram.Bot, svcCtx *svc.ServiceContext, interval time.Duration) *Scheduler {
+func NewScheduler(svcCtx *svc.ServiceContext, interval time.Duration) *Scheduler {
 	if interval == 0 {
 		interval = 1 * time.Minute
 	}
 
-	return &Scheduler{
-		bot:           bot,
-		barkClient:    svcCtx.BarkClient,
+	s := &Scheduler{
+		notifier:      svcCtx.Notifier,
 		taskModel:     svcCtx.TaskModel,
 		charModel:     svcCtx.CharacterModel,
 		svcCtx:        svcCtx,
@@ -43,6 +52,9 @@ func NewScheduler(bot *telegram.Bot, svcCtx *svc.ServiceContext, interval time.D
 		running:       false,
 		lastResetDate: "",
 	}
+	s.notifier.OnDelivered(s.reminderDelivered)
+
+	return s
 }
 
 func (s *Scheduler) Start() {
@@ -64,9 +76,16 @@ func (s *Scheduler) run() {
 			return
 		case <-ticker.C:
 			s.checkDailyReset()
+			s.checkAttributeSnapshots()
 			s.checkAttributeDecay()
 			s.checkExpiredChallengeTasks()
+			s.checkExpiredBuffs()
+			s.checkShopRestock()
+			s.checkExpiringItems()
 			s.checkTasks()
+			s.checkDigests()
+			s.checkNotificationOutbox()
+			s.checkWebhookDeliveries()
 		}
 	}
 }
@@ -86,10 +105,10 @@ func (s *Scheduler) checkTasks() {
 	}
 
 	now := time.Now()
+	recipients := make(map[int64]*model.User)
 
 	for _, tw := range tasksWithUsers {
 		task := tw.Task
-		chatID := tw.TgChatID
 
 		if !task.Deadline.Valid {
 			continue
@@ -120,6 +139,17 @@ func (s *Scheduler) checkTasks() {
 		}
 
 		if shouldRemind {
+			// Users without any channel for reminders are skipped without
+			// bookkeeping, so they get reminded once they set one up
+			user, err := s.reminderRecipient(task.UserID, recipients)
+			if err != nil {
+				log.Printf("Error resolving reminder channels for user %d: %v", task.UserID, err)
+				continue
+			}
+			if user == nil {
+				continue
+			}
+
 			remaining := deadline.Sub(now)
 			var remainingStr string
 
@@ -140,41 +170,77 @@ func (s *Scheduler) checkTasks() {
 			message := fmt.Sprintf("⏰ 提醒：任务「%s」还剩 %s 到期！%s",
 				task.Title, remainingStr, description)
 
-			// Send Telegram notification
-			if s.bot != nil && chatID > 0 {
-				completeBtn := tgbotapi.NewInlineKeyboardButtonData("✅ 完成", fmt.Sprintf("complete:%d", task.ID))
-				deleteBtn := tgbotapi.NewInlineKeyboardButtonData("🗑 删除", fmt.Sprintf("delete:%d", task.ID))
-				keyboard := tgbotapi.NewInlineKeyboardMarkup(
-					tgbotapi.NewInlineKeyboardRow(completeBtn, deleteBtn),
-				)
-
-				if err := s.bot.SendMessageWithKeyboard(chatID, message, keyboard); err != nil {
-					log.Printf("Error sending Telegram reminder: %v", err)
-				}
+			n := &notify.Notification{
+				Kind:    notify.KindTaskReminder,
+				Title:   fmt.Sprintf("⏰ 任务提醒 - 还剩%s", remainingStr),
+				Body:    task.Title + description,
+				Message: message,
+				Level:   notify.LevelTimeSensitive,
+				Alarm:   true,
+				Actions: []notify.Action{
+					{Text: "✅ 完成", Data: fmt.Sprintf("complete:%d", task.ID)},
+					{Text: "🗑 删除", Data: fmt.Sprintf("delete:%d", task.ID)},
+				},
+				RefType: reminderRefType,
+				RefID:   task.ID,
 			}
 
-			// Send Bark notification
-			if s.barkClient != nil {
-				user, err := s.svcCtx.UserModel.FindByID(task.UserID)
-				if err == nil && user != nil && user.BarkKey != "" {
-					barkTitle := fmt.Sprintf("⏰ 任务提醒 - 还剩%s", remainingStr)
-					barkBody := task.Title
-					if task.Description != "" {
-						barkBody = fmt.Sprintf("%s\n%s", task.Title, task.Description)
-					}
-					if err := s.barkClient.PushAlarm(user.BarkKey, barkTitle, barkBody); err != nil {
-						log.Printf("Error sending Bark reminder: %v", err)
-					}
-				}
+			// Reminders are not deferred during quiet hours but skipped without
+			// bookkeeping, so the first one afterwards shows the remaining time
+			held, err := s.notifier.Held(user, n)
+			if err != nil {
+				log.Printf("Error checking quiet hours for user %d: %v", task.UserID, err)
+				continue
+			}
+			if held {
+				continue
 			}
 
-			if err := s.taskModel.UpdateLastReminded(task.ID, now); err != nil {
-				log.Printf("Error updating last reminded: %v", err)
+			// Until it is delivered, later checks refresh the queued reminder
+			// instead of adding another; delivery advances the bookkeeping
+			if err := s.notifier.Send(user, n); err != nil {
+				log.Printf("Error queueing reminder for task #%d: %v", task.ID, err)
 			}
 		}
 	}
 }
 
+// reminderDelivered records when a reminder reached the user, so the next
+// one is due an interval later
+func (s *Scheduler) reminderDelivered(item *model.OutboxItem) {
+	if item.Kind != string(notify.KindTaskReminder) || item.RefType != reminderRefType {
+		return
+	}
+	if err := s.taskModel.UpdateLastReminded(item.RefID, item.SentAt.Time); err != nil {
+		log.Printf("Error updating last reminded: %v", err)
+	}
+}
+
+// reminderRecipient returns the user if any channel delivers task reminders
+// to them, nil otherwise. Lookups are cached in users for one scheduler run.
+func (s *Scheduler) reminderRecipient(userID int64, users map[int64]*model.User) (*model.User, error) {
+	if user, ok := users[userID]; ok {
+		return user, nil
+	}
+
+	user, err := s.svcCtx.UserModel.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		channels, err := s.notifier.Resolve(user, notify.KindTaskReminder)
+		if err != nil {
+			return nil, err
+		}
+		if len(channels) == 0 {
+			user = nil
+		}
+	}
+
+	users[userID] = user
+	return user, nil
+}
+
 // checkDailyReset resets daily completion counts for repeatable tasks at the start of each day
 func (s *Scheduler) checkDailyReset() {
 	today := time.Now().Format("2006-01-02")
@@ -193,6 +259,176 @@ func (s *Scheduler) checkDailyReset() {
 	log.Printf("✅ Daily reset completed for %s", today)
 }
 
+// checkAttributeSnapshots records everyone's attributes once a day, before
+// decay runs, so reports can show how they changed
+func (s *Scheduler) checkAttributeSnapshots() {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	if s.lastSnapshotDate == today {
+		return
+	}
+
+	if _, err := s.charModel.SnapshotAttributes(today); err != nil {
+		log.Printf("Error taking attribute snapshots: %v", err)
+		return
+	}
+	cutoff := now.AddDate(0, 0, -snapshotRetentionDays).Format("2006-01-02")
+	if err := s.charModel.DeleteSnapshotsBefore(cutoff); err != nil {
+		log.Printf("Error pruning attribute snapshots: %v", err)
+	}
+
+	s.lastSnapshotDate = today
+}
+
+// checkDigests sends the morning briefing and the weekly review once the
+// configured local time of each subscribed user has passed
+func (s *Scheduler) checkDigests() {
+	users, err := s.svcCtx.UserModel.FindDigestSubscribers()
+	if err != nil {
+		log.Printf("Error finding digest subscribers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	reports := logic.NewReportLogic(s.svcCtx)
+	for _, user := range users {
+		due, err := notify.ParseClock(user.DigestTime)
+		if err != nil {
+			continue
+		}
+		local := now.In(notify.UserLocation(user))
+		if local.Hour()*60+local.Minute() < due {
+			continue
+		}
+		today := local.Format("2006-01-02")
+
+		if user.DigestDaily && user.LastDailyDigest != today {
+			s.sendDigest(user, reports.DailyDigest, now)
+			if err := s.svcCtx.UserModel.MarkDailyDigest(user.ID, today); err != nil {
+				log.Printf("Error marking daily digest for user %d: %v", user.ID, err)
+			}
+		}
+
+		if user.DigestWeekly && int(local.Weekday()) == user.DigestWeekday && user.LastWeeklyDigest != today {
+			s.sendDigest(user, reports.WeeklyDigest, now)
+			if err := s.svcCtx.UserModel.MarkWeeklyDigest(user.ID, today); err != nil {
+				log.Printf("Error marking weekly digest for user %d: %v", user.ID, err)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) sendDigest(user *model.User, build func(*model.User, time.Time) (*notify.Notification, error), now time.Time) {
+	n, err := build(user, now)
+	if err != nil {
+		log.Printf("Error building digest for user %d: %v", user.ID, err)
+		return
+	}
+	if err := s.notifier.Send(user, n); err != nil {
+		log.Printf("Error sending %s to user %d: %v", n.Kind, user.ID, err)
+	}
+}
+
+// checkExpiredBuffs removes buffs whose duration has run out
+func (s *Scheduler) checkExpiredBuffs() {
+	removed, err := s.svcCtx.BuffModel.DeleteExpired(time.Now())
+	if err != nil {
+		log.Printf("Error removing expired buffs: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("⌛ Removed %d expired buffs", removed)
+	}
+}
+
+// checkShopRestock tops up limited shop items according to their restock rules
+func (s *Scheduler) checkShopRestock() {
+	restocked, err := logic.NewShopLogic(s.svcCtx).RestockItems(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Error restocking shop items: %v", err)
+		return
+	}
+	if restocked > 0 {
+		log.Printf("📦 Restocked %d shop items", restocked)
+	}
+}
+
+// checkExpiringItems removes expired bag items and warns users about items
+// that are about to expire
+func (s *Scheduler) checkExpiringItems() {
+	shopLogic := logic.NewShopLogic(s.svcCtx)
+	now := time.Now()
+
+	expired, err := shopLogic.ExpireItems(context.Background(), now)
+	if err != nil {
+		log.Printf("Error expiring inventory items: %v", err)
+	}
+	for _, notice := range expired {
+		log.Printf("⌛ Removed %d expired item lot(s) for user %d", len(notice.Items), notice.UserID)
+		s.notifyItemExpiry(notice, notify.KindItemExpired, "⌛ 物品已过期", "以下物品已过期，已从背包中移除：")
+	}
+
+	expiring, err := shopLogic.ExpiringItems(context.Background(), now)
+	if err != nil {
+		log.Printf("Error finding expiring inventory items: %v", err)
+	}
+	for _, notice := range expiring {
+		s.notifyItemExpiry(notice, notify.KindItemExpiring, "⏳ 物品即将过期", "以下物品即将过期，记得尽快使用：")
+	}
+}
+
+func (s *Scheduler) notifyItemExpiry(notice *logic.ExpiryNotice, kind notify.Kind, title, intro string) {
+	body := strings.Join(notice.Items, "\n")
+	err := s.notifier.Notify(notice.UserID, &notify.Notification{
+		Kind:    kind,
+		Title:   title,
+		Body:    body,
+		Message: intro + "\n" + body,
+		Level:   notify.LevelPassive,
+	})
+	if err != nil {
+		log.Printf("Error sending expiry notification: %v", err)
+	}
+}
+
+// checkNotificationOutbox delivers queued notifications, retries and those
+// deferred by quiet hours, and prunes finished ones once a day
+func (s *Scheduler) checkNotificationOutbox() {
+	now := time.Now()
+	attempted, err := s.notifier.DeliverDue(now)
+	if err != nil {
+		log.Printf("Error delivering notifications: %v", err)
+	} else if attempted > 0 {
+		log.Printf("📨 Attempted %d notification deliveries", attempted)
+	}
+
+	today := now.Format("2006-01-02")
+	if s.lastOutboxPrune == today {
+		return
+	}
+	if err := s.svcCtx.NotificationModel.DeleteOutboxBefore(now.AddDate(0, 0, -outboxRetentionDays)); err != nil {
+		log.Printf("Error pruning notification outbox: %v", err)
+		return
+	}
+	s.lastOutboxPrune = today
+}
+
+// checkWebhookDeliveries sends queued webhook events and due retries. It
+// runs in the background so slow endpoints cannot hold up the other checks;
+// the queue skips a run while the previous one is still going.
+func (s *Scheduler) checkWebhookDeliveries() {
+	go func() {
+		attempted, err := s.svcCtx.Webhooks.DeliverDue(time.Now())
+		if err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+			return
+		}
+		if attempted > 0 {
+			log.Printf("🪝 Attempted %d webhook deliveries", attempted)
+		}
+	}()
+}
+
 // checkExpiredChallengeTasks finds expired challenge tasks and applies penalties
 func (s *Scheduler) checkExpiredChallengeTasks() {
 	tasks, err := s.taskModel.FindExpiredChallengeTasks()
@@ -220,15 +456,16 @@ func (s *Scheduler) checkExpiredChallengeTasks() {
 		log.Printf("✅ Task #%d '%s' marked as failed with penalties applied", task.ID, task.Title)
 
 		// Send notification
-		if s.bot != nil {
-			user, err := s.svcCtx.UserModel.FindByID(task.UserID)
-			if err == nil && user != nil && user.TgChatID > 0 {
-				message := fmt.Sprintf("❌ 挑战任务「%s」已超过截止时间，自动失败！\n惩罚：-%d灵石",
-					task.Title, task.PenaltySpiritStones)
-				if err := s.bot.SendMessage(user.TgChatID, message); err != nil {
-					log.Printf("Error sending failure notification: %v", err)
-				}
-			}
+		err := s.notifier.Notify(task.UserID, &notify.Notification{
+			Kind:  notify.KindChallengeFailed,
+			Title: "❌ 挑战任务失败",
+			Body:  fmt.Sprintf("「%s」已超过截止时间，自动失败！\n惩罚：-%d灵石", task.Title, task.PenaltySpiritStones),
+			Message: fmt.Sprintf("❌ 挑战任务「%s」已超过截止时间，自动失败！\n惩罚：-%d灵石",
+				task.Title, task.PenaltySpiritStones),
+			Level: notify.LevelTimeSensitive,
+		})
+		if err != nil {
+			log.Printf("Error sending failure notification: %v", err)
 		}
 	}
 }
@@ -267,51 +504,106 @@ func (s *Scheduler) checkAttributeDecay() {
 			decayMultiplier = 0.5
 		}
 
-		// Load attributes and apply decay
-		attrs, err := s.charModel.FindAttributesByUserID(stats.UserID)
-		if err != nil {
-			log.Printf("Error finding attributes for user %d: %v", stats.UserID, err)
-			continue
-		}
-
-		for _, attr := range attrs {
-			if attr.AttrKey == "luck" {
-				continue
+		// Apply decay and bump the activity date atomically so a concurrent
+		// task completion or purchase isn't overwritten.
+		applied := false
+		shielded := false
+		err = s.svcCtx.Transact(func(uow *model.UnitOfWork) error {
+			current, err := uow.Character.FindByUserID(stats.UserID)
+			if err != nil {
+				return err
+			}
+			if current == nil || current.LastActivityDate != stats.LastActivityDate {
+				// Became active since the scan
+				return nil
+			}
+			applied = true
+
+			// A streak shield absorbs this decay entirely
+			if current.StreakShields > 0 {
+				shielded = true
+				current.StreakShields--
+				current.LastActivityDate = today
+				if err := uow.Character.Update(current); err != nil {
+					return err
+				}
+				return webhook.Enqueue(uow.Webhook, stats.UserID, webhook.EventAttributeDecay, map[string]interface{}{
+					"daysInactive": daysInactive,
+					"shielded":     true,
+				})
 			}
 
-			minVal := realm.AttrMin(attr.Realm)
-			newValue := attr.Value * decayMultiplier
-			if newValue < minVal {
-				newValue = minVal
+			attrs, err := uow.Character.FindAttributesByUserID(stats.UserID)
+			if err != nil {
+				return err
 			}
 
-			if newValue != attr.Value {
-				attr.Value = newValue
-				if err := s.charModel.UpdateAttribute(attr); err != nil {
-					log.Printf("Error updating attribute %s for user %d: %v", attr.AttrKey, stats.UserID, err)
+			for _, attr := range attrs {
+				if attr.AttrKey == "luck" {
+					continue
 				}
+
+				minVal := realm.AttrMin(attr.Realm)
+				newValue := attr.Value * decayMultiplier
+				if newValue < minVal {
+					newValue = minVal
+				}
+
+				if newValue != attr.Value {
+					attr.Value = newValue
+					if err := uow.Character.UpdateAttribute(attr); err != nil {
+						return fmt.Errorf("update attribute %s: %w", attr.AttrKey, err)
+					}
+				}
+			}
+
+			// Update last activity date to prevent repeated decay
+			current.LastActivityDate = today
+			if err := uow.Character.Update(current); err != nil {
+				return err
 			}
+			return webhook.Enqueue(uow.Webhook, stats.UserID, webhook.EventAttributeDecay, map[string]interface{}{
+				"daysInactive": daysInactive,
+				"shielded":     false,
+				"multiplier":   decayMultiplier,
+			})
+		})
+		if err != nil {
+			log.Printf("Error applying attribute decay for user %d: %v", stats.UserID, err)
+			continue
+		}
+		if !applied {
+			continue
 		}
 
-		// Update last activity date to prevent repeated decay
-		stats.LastActivityDate = today
-		if err := s.charModel.Update(stats); err != nil {
-			log.Printf("Error updating character stats for user %d: %v", stats.UserID, err)
+		if shielded {
+			log.Printf("🛡  Streak shield absorbed attribute decay for user %d", stats.UserID)
+			err := s.notifier.Notify(stats.UserID, &notify.Notification{
+				Kind:    notify.KindStreakShield,
+				Title:   "🛡 护体生效",
+				Body:    fmt.Sprintf("%d 天未活动的属性衰减已被抵挡。", daysInactive),
+				Message: fmt.Sprintf("🛡 护体生效！%d 天未活动的属性衰减已被抵挡。", daysInactive),
+				Level:   notify.LevelPassive,
+			})
+			if err != nil {
+				log.Printf("Error sending decay notification: %v", err)
+			}
 			continue
 		}
 
 		log.Printf("⚠️  Applied attribute decay to user %d after %d days of inactivity", stats.UserID, daysInactive)
 
 		// Send notification
-		if s.bot != nil {
-			user, err := s.svcCtx.UserModel.FindByID(stats.UserID)
-			if err == nil && user != nil && user.TgChatID > 0 {
-				message := fmt.Sprintf("⚠️ 由于 %d 天未活动，你的属性发生了衰减！\n完成任务来恢复和提升属性吧！",
-					daysInactive)
-				if err := s.bot.SendMessage(user.TgChatID, message); err != nil {
-					log.Printf("Error sending decay notification: %v", err)
-				}
-			}
+		err = s.notifier.Notify(stats.UserID, &notify.Notification{
+			Kind:  notify.KindAttributeDecay,
+			Title: "⚠️ 属性衰减",
+			Body:  fmt.Sprintf("由于 %d 天未活动，你的属性发生了衰减！\n完成任务来恢复和提升属性吧！", daysInactive),
+			Message: fmt.Sprintf("⚠️ 由于 %d 天未活动，你的属性发生了衰减！\n完成任务来恢复和提升属性吧！",
+				daysInactive),
+			Level: notify.LevelActive,
+		})
+		if err != nil {
+			log.Printf("Error sending decay notification: %v", err)
 		}
 	}
 }
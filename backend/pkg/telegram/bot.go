@@ -3,6 +3,7 @@ package telegram
 import (
 	"database/sql"
 	"fmt"
+	"html"
 	"log"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -11,10 +12,31 @@ import (
 
 // TaskCompleter interface to avoid circular dependency with logic package
 type TaskCompleter interface {
-	CompleteTask(userID int64, taskID int64) (expGained int, spiritStonesGained int, realmTitle string, spiritStones int, err error)
+	CompleteTask(userID int64, taskID int64) (expGained int, spiritStonesGained int, realmTitle string, spiritStones int, drops []string, err error)
 	DeleteTask(userID int64, taskID int64) error
 }
 
+// TaskManager interface backs /add and /done without depending on the logic package
+type TaskManager interface {
+	AddTask(userID int64, draft TaskDraft) (message string, err error)
+	DoneTask(userID int64, draft TaskDraft) (message string, err error)
+}
+
+// CharacterViewer interface backs /status
+type CharacterViewer interface {
+	CharacterStatus(userID int64) (*CharacterStatus, error)
+}
+
+// ShopService interface backs /shop, /bag and their buttons. Each button
+// buys, uses or sells one unit.
+type ShopService interface {
+	ShopItems(userID int64) ([]ShopEntry, error)
+	Inventory(userID int64) ([]BagEntry, error)
+	Buy(userID int64, kind string, id int64) (message string, err error)
+	UseItem(userID int64, itemID int64) (message string, err error)
+	SellItem(userID int64, itemID int64) (message string, err error)
+}
+
 // ServiceContextInterface defines the interface for service context to avoid circular imports
 type ServiceContextInterface interface {
 	GetDB() *sql.DB
@@ -31,41 +53,78 @@ type Bot struct {
 	charModel     *model.CharacterModel
 	svcCtx        ServiceContextInterface
 	taskCompleter TaskCompleter
+	taskManager   TaskManager
+	charViewer    CharacterViewer
+	shopService   ShopService
+
+	webhookURL     string
+	webhookSecret  string
+	webhookUpdates chan tgbotapi.Update // Filled by ServeWebhook in webhook mode
 }
 
-func NewBot(token string, db *sql.DB) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+func NewBot(token string, db *sql.DB, opts Options) (*Bot, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	endpoint := opts.APIEndpoint
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	bot := &Bot{
-		api:       api,
-		db:        db,
-		userModel: model.NewUserModel(db),
-		taskModel: model.NewTaskModel(db),
-		charModel: model.NewCharacterModel(db),
+		api:           api,
+		db:            db,
+		userModel:     model.NewUserModel(db),
+		taskModel:     model.NewTaskModel(db),
+		charModel:     model.NewCharacterModel(db),
+		webhookURL:    opts.WebhookURL,
+		webhookSecret: opts.WebhookSecret,
 	}
 
 	return bot, nil
 }
 
-func (b *Bot) Start() {
-	go b.pollUpdates()
-}
+// Start receives updates by long polling, or registers the webhook and
+// handles what ServeWebhook receives. Polling first removes a webhook left
+// from an earlier run, since Telegram refuses to poll while one is set.
+func (b *Bot) Start() error {
+	if b.Webhook() {
+		if err := b.setWebhook(); err != nil {
+			return fmt.Errorf("set webhook: %w", err)
+		}
+		b.webhookUpdates = make(chan tgbotapi.Update, webhookBuffer)
+		go b.dispatch(b.webhookUpdates)
+		return nil
+	}
+
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
 
-func (b *Bot) pollUpdates() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
+	go b.dispatch(b.api.GetUpdatesChan(u))
+	return nil
+}
 
-	updates := b.api.GetUpdatesChan(u)
-
+// dispatch handles updates one at a time, in the order they arrive
+func (b *Bot) dispatch(updates <-chan tgbotapi.Update) {
 	for update := range updates {
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			b.handleCallback(update.CallbackQuery)
-		}
+		b.HandleUpdate(update)
+	}
+}
+
+// HandleUpdate routes one update to the command, text or button handlers
+func (b *Bot) HandleUpdate(update tgbotapi.Update) {
+	if update.Message != nil {
+		b.handleMessage(update.Message)
+	} else if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
 	}
 }
 
@@ -106,7 +165,7 @@ func (b *Bot) handlePlainText(chatID int64, text string) {
 			log.Printf("Error clearing bind code: %v", err)
 		}
 
-		msg := fmt.Sprintf("✅ 绑定成功！你的账号 %s 已关联。\n使用 /tasks 查看任务，/help 查看帮助。", user.Username)
+		msg := fmt.Sprintf("✅ 绑定成功！你的账号 %s 已关联。\n使用 /tasks 查看任务，/help 查看帮助。", html.EscapeString(user.Username))
 		b.SendMessage(chatID, msg)
 	} else {
 		b.SendMessage(chatID, "未知命令。使用 /help 查看帮助，或发送有效的绑定码进行账号绑定。")
@@ -123,6 +182,16 @@ func (b *Bot) handleCommand(chatID int64, command, args string) {
 		}
 	case "tasks":
 		b.handleTasks(chatID)
+	case "add":
+		b.handleAdd(chatID, args)
+	case "done":
+		b.handleDone(chatID, args)
+	case "status":
+		b.handleStatus(chatID)
+	case "shop":
+		b.handleShop(chatID)
+	case "bag":
+		b.handleBag(chatID)
 	case "help":
 		b.handleHelp(chatID)
 	default:
@@ -162,7 +231,7 @@ func (b *Bot) handleStartWithCode(chatID int64, code string) {
 		log.Printf("Error clearing bind code: %v", err)
 	}
 
-	message := fmt.Sprintf("✅ 绑定成功！你的账号 %s 已关联。\n使用 /tasks 查看任务，/help 查看帮助。", user.Username)
+	message := fmt.Sprintf("✅ 绑定成功！你的账号 %s 已关联。\n使用 /tasks 查看任务，/help 查看帮助。", html.EscapeString(user.Username))
 	b.SendMessage(chatID, message)
 }
 
@@ -175,10 +244,14 @@ func (b *Bot) handleStartNoCode(chatID int64) {
 1. 访问 Web 应用
 2. 进入设置页面
 3. 生成绑定码
-4. 使用 /start <绑定码> 命令
+4. 使用 /start &lt;绑定码&gt; 命令
 
 绑定后，你可以：
 - 📋 使用 /tasks 查看任务
+- ➕ 使用 /add 创建任务
+- ⚡ 使用 /done 快速完成任务
+- 🧘 使用 /status 查看角色
+- 🏪 使用 /shop 购买物品，/bag 使用或出售
 - ✅ 点击按钮完成任务
 - 🗑 删除任务
 
@@ -197,7 +270,7 @@ func (b *Bot) handleTasks(chatID int64) {
 	}
 
 	if user == nil {
-		b.SendMessage(chatID, "❌ 账号未绑定。请使用 /start <绑定码> 进行绑定。")
+		b.SendMessage(chatID, "❌ 账号未绑定。请使用 /start &lt;绑定码&gt; 进行绑定。")
 		return
 	}
 
@@ -219,9 +292,9 @@ func (b *Bot) handleTasks(chatID int64) {
 	var keyboard [][]tgbotapi.InlineKeyboardButton
 
 	for _, task := range tasks {
-		message += fmt.Sprintf("• %s\n", task.Title)
+		message += fmt.Sprintf("• %s\n", html.EscapeString(task.Title))
 		if task.Description != "" {
-			message += fmt.Sprintf("  📝 %s\n", task.Description)
+			message += fmt.Sprintf("  📝 %s\n", html.EscapeString(task.Description))
 		}
 
 		completeBtn := tgbotapi.NewInlineKeyboardButtonData("✅ 完成", fmt.Sprintf("complete:%d", task.ID))
@@ -237,13 +310,27 @@ func (b *Bot) handleHelp(chatID int64) {
 	message := `🆘 帮助菜单
 
 可用命令：
-/start <绑定码> - 使用绑定码进行账号绑定
+/start &lt;绑定码&gt; - 使用绑定码进行账号绑定
 /tasks - 查看你的所有任务
+/add &lt;标题&gt; [★n] [#标签] [due:截止] - 创建任务
+/done &lt;标题或难度&gt; [#标签] - 完成同名任务，没有则按难度模板记录一次
+/status - 查看境界、疲劳和灵石
+/shop - 浏览商店，点击按钮购买
+/bag - 查看背包，点击按钮使用或出售
 /help - 显示此帮助信息
 
+示例：
+/add 读完第三章 ★2 #intelligence due:tomorrow
+/done 晨跑 ★1 #体魄
+/done ★3
+
+难度 ★0-★5 也可写作 *2，标签可用属性 key、属性名或自定义标签。
+截止支持 today、tomorrow、2026-03-01、2026-03-01 18:00、tomorrow 18:00、2026-03-01T18:00、18:00、3h、2d。
+
 按钮操作：
 ✅ 完成 - 标记任务为已完成，获得奖励
 🗑 删除 - 删除任务
+🛒 购买 / ✨ 使用 / 💰 出售 - 每次一个
 
 需要更多帮助，请访问 Web 应用设置。`
 
@@ -271,8 +358,12 @@ func (b *Bot) GetUsername() string {
 	return b.api.Self.UserName
 }
 
+// Stop ends long polling. The webhook stays registered for the other
+// instances sharing it.
 func (b *Bot) Stop() {
-	b.api.StopReceivingUpdates()
+	if !b.Webhook() {
+		b.api.StopReceivingUpdates()
+	}
 }
 
 // SetServiceContext sets the service context after bot initialization
@@ -284,3 +375,18 @@ func (b *Bot) SetServiceContext(svcCtx ServiceContextInterface) {
 func (b *Bot) SetTaskCompleter(completer TaskCompleter) {
 	b.taskCompleter = completer
 }
+
+// SetTaskManager sets the task manager used by /add and /done
+func (b *Bot) SetTaskManager(manager TaskManager) {
+	b.taskManager = manager
+}
+
+// SetCharacterViewer sets the character viewer used by /status
+func (b *Bot) SetCharacterViewer(viewer CharacterViewer) {
+	b.charViewer = viewer
+}
+
+// SetShopService sets the shop service used by /shop and /bag
+func (b *Bot) SetShopService(service ShopService) {
+	b.shopService = service
+}
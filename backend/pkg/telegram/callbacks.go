@@ -2,31 +2,87 @@ package telegram
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"life-system-backend/internal/model"
 )
 
+// callback is one pressed inline button, bound to its route
+type callback struct {
+	chatID    int64
+	messageID int
+	user      *model.User
+	ids       []int64 // Values of the {id} placeholders, in order
+}
+
+// callbackRoute handles the callback data matching pattern. Data is split at
+// colons; pattern segments match literally, except {id} which matches a
+// positive integer.
+type callbackRoute struct {
+	pattern []string
+	handle  func(b *Bot, c *callback)
+}
+
+func route(pattern string, handle func(b *Bot, c *callback)) callbackRoute {
+	return callbackRoute{pattern: strings.Split(pattern, ":"), handle: handle}
+}
+
+var callbackRoutes = []callbackRoute{
+	route("complete:{id}", (*Bot).handleCompleteCallback),
+	route("delete:{id}", (*Bot).handleDeleteCallback),
+	route("buy:item:{id}", (*Bot).handleBuyItemCallback),
+	route("buy:bundle:{id}", (*Bot).handleBuyBundleCallback),
+	route("use:{id}", (*Bot).handleUseCallback),
+	route("sell:{id}", (*Bot).handleSellCallback),
+}
+
+// match returns the IDs in parts when they fit the route
+func (r callbackRoute) match(parts []string) ([]int64, bool) {
+	if len(parts) != len(r.pattern) {
+		return nil, false
+	}
+
+	var ids []int64
+	for i, segment := range r.pattern {
+		if segment != "{id}" {
+			if parts[i] != segment {
+				return nil, false
+			}
+			continue
+		}
+		id, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil || id <= 0 {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
 func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
-	chatID := query.Message.Chat.ID
-	messageID := query.Message.MessageID
-	data := query.Data
+	// Answer callback query (dismiss the loading state)
+	defer b.api.Request(tgbotapi.NewCallback(query.ID, ""))
 
-	// Parse callback data
-	parts := strings.SplitN(data, ":", 2)
-	if len(parts) != 2 {
-		b.SendMessage(chatID, "❌ 无效的操作")
+	if query.Message == nil {
 		return
 	}
+	chatID := query.Message.Chat.ID
 
-	action := parts[0]
-	taskIDStr := parts[1]
-
-	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
-	if err != nil {
-		b.SendMessage(chatID, "❌ 无效的任务ID")
+	parts := strings.Split(query.Data, ":")
+	var handle func(b *Bot, c *callback)
+	var ids []int64
+	for _, r := range callbackRoutes {
+		if matched, ok := r.match(parts); ok {
+			handle, ids = r.handle, matched
+			break
+		}
+	}
+	if handle == nil {
+		b.SendMessage(chatID, "❌ 无效的操作")
 		return
 	}
 
@@ -43,21 +99,17 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	switch action {
-	case "complete":
-		b.handleCompleteCallback(chatID, messageID, user.ID, taskID)
-	case "delete":
-		b.handleDeleteCallback(chatID, messageID, user.ID, taskID)
-	default:
-		b.SendMessage(chatID, "❌ 未知操作")
-	}
-
-	// Answer callback query (dismiss the loading state)
-	answerCfg := tgbotapi.NewCallback(query.ID, "")
-	b.api.Request(answerCfg)
+	handle(b, &callback{
+		chatID:    chatID,
+		messageID: query.Message.MessageID,
+		user:      user,
+		ids:       ids,
+	})
 }
 
-func (b *Bot) handleCompleteCallback(chatID int64, messageID int, userID int64, taskID int64) {
+func (b *Bot) handleCompleteCallback(c *callback) {
+	chatID, userID, taskID := c.chatID, c.user.ID, c.ids[0]
+
 	// Get task to verify ownership
 	task, err := b.taskModel.FindByID(taskID)
 	if err != nil {
@@ -83,9 +135,9 @@ func (b *Bot) handleCompleteCallback(chatID int64, messageID int, userID int64,
 		return
 	}
 
-	expGained, spiritStonesGained, realmTitle, totalSpiritStones, err := b.taskCompleter.CompleteTask(userID, taskID)
+	expGained, spiritStonesGained, realmTitle, totalSpiritStones, drops, err := b.taskCompleter.CompleteTask(userID, taskID)
 	if err != nil {
-		b.SendMessage(chatID, fmt.Sprintf("❌ 完成失败：%s", err.Error()))
+		b.SendMessage(chatID, fmt.Sprintf("❌ 完成失败：%s", html.EscapeString(err.Error())))
 		log.Printf("Error completing task: %v", err)
 		return
 	}
@@ -93,14 +145,19 @@ func (b *Bot) handleCompleteCallback(chatID int64, messageID int, userID int64,
 	// Send success message
 	_ = expGained
 	msg := fmt.Sprintf("✅ 任务「%s」已完成！\n获得 %d灵石\n\n境界：%s | 灵石：%d",
-		task.Title, spiritStonesGained, realmTitle, totalSpiritStones)
+		html.EscapeString(task.Title), spiritStonesGained, html.EscapeString(realmTitle), totalSpiritStones)
+	if len(drops) > 0 {
+		msg += "\n\n🎁 掉落：" + html.EscapeString(strings.Join(drops, "、"))
+	}
 	b.SendMessage(chatID, msg)
 
 	// Update the original task list message to reflect completion
-	b.refreshTaskListMessage(chatID, messageID, userID)
+	b.refreshTaskListMessage(chatID, c.messageID, userID)
 }
 
-func (b *Bot) handleDeleteCallback(chatID int64, messageID int, userID int64, taskID int64) {
+func (b *Bot) handleDeleteCallback(c *callback) {
+	chatID, userID, taskID := c.chatID, c.user.ID, c.ids[0]
+
 	// Get task to verify ownership
 	task, err := b.taskModel.FindByID(taskID)
 	if err != nil {
@@ -127,15 +184,15 @@ func (b *Bot) handleDeleteCallback(chatID int64, messageID int, userID int64, ta
 	}
 
 	if err := b.taskCompleter.DeleteTask(userID, taskID); err != nil {
-		b.SendMessage(chatID, fmt.Sprintf("❌ 删除失败：%s", err.Error()))
+		b.SendMessage(chatID, fmt.Sprintf("❌ 删除失败：%s", html.EscapeString(err.Error())))
 		log.Printf("Error deleting task: %v", err)
 		return
 	}
 
-	b.SendMessage(chatID, fmt.Sprintf("🗑 任务「%s」已删除", task.Title))
+	b.SendMessage(chatID, fmt.Sprintf("🗑 任务「%s」已删除", html.EscapeString(task.Title)))
 
 	// Update the original task list message to reflect deletion
-	b.refreshTaskListMessage(chatID, messageID, userID)
+	b.refreshTaskListMessage(chatID, c.messageID, userID)
 }
 
 // refreshTaskListMessage edits the original /tasks message to show updated task list
@@ -159,9 +216,9 @@ func (b *Bot) refreshTaskListMessage(chatID int64, messageID int, userID int64)
 	var keyboard [][]tgbotapi.InlineKeyboardButton
 
 	for _, task := range tasks {
-		text += fmt.Sprintf("• %s\n", task.Title)
+		text += fmt.Sprintf("• %s\n", html.EscapeString(task.Title))
 		if task.Description != "" {
-			text += fmt.Sprintf("  📝 %s\n", task.Description)
+			text += fmt.Sprintf("  📝 %s\n", html.EscapeString(task.Description))
 		}
 
 		completeBtn := tgbotapi.NewInlineKeyboardButtonData("✅ 完成", fmt.Sprintf("complete:%d", task.ID))
@@ -0,0 +1,250 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"life-system-backend/internal/model"
+)
+
+// TaskDraft is a task described in a command: `<title> [★n] [#tag] [due:...]`
+type TaskDraft struct {
+	Title      string
+	Difficulty int      // 0-5, -1 when not given
+	Tags       []string // Attribute keys, attribute names or plain tags
+	Due        string   // As typed, read in the user's timezone
+}
+
+// CharacterStatus is the character shown by /status
+type CharacterStatus struct {
+	Title             string
+	SpiritStones      int
+	SavedSpiritStones int
+	Fatigue           int
+	FatigueCap        int
+	Attributes        []AttributeStatus
+	Buffs             []string
+}
+
+// AttributeStatus is one attribute line of /status
+type AttributeStatus struct {
+	Emoji      string
+	Name       string
+	Value      float64
+	TodayGain  float64
+	Realm      string // e.g. "筑基·中期", empty for attributes without realms
+	Bottleneck bool
+}
+
+// ParseTaskDraft reads the arguments of /add and /done. Options may appear
+// anywhere, the remaining words form the title. A title that is only a
+// number from 0 to 5 is taken as the difficulty. A due day may be followed
+// by a time, as in `due:2026-10-20 18:00`.
+func ParseTaskDraft(args string) (TaskDraft, error) {
+	draft := TaskDraft{Difficulty: -1}
+	var words []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		word := fields[i]
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			draft.Tags = append(draft.Tags, word[1:])
+		case strings.HasPrefix(strings.ToLower(word), "due:"):
+			draft.Due = word[len("due:"):]
+			if draft.Due == "" {
+				return draft, fmt.Errorf("due: 后需要填写截止时间")
+			}
+			if i+1 < len(fields) && !strings.Contains(draft.Due, ":") && isClock(fields[i+1]) {
+				draft.Due += " " + fields[i+1]
+				i++
+			}
+		case isDifficulty(word):
+			n, err := parseDifficulty(word)
+			if err != nil {
+				return draft, err
+			}
+			draft.Difficulty = n
+		default:
+			words = append(words, word)
+		}
+	}
+
+	draft.Title = strings.Join(words, " ")
+	if draft.Difficulty < 0 {
+		if n, err := strconv.Atoi(draft.Title); err == nil && n >= 0 && n <= 5 {
+			draft.Difficulty = n
+			draft.Title = ""
+		}
+	}
+	return draft, nil
+}
+
+// isClock reports whether word is a time of day such as 18:00
+func isClock(word string) bool {
+	_, err := time.Parse("15:04", word)
+	return err == nil
+}
+
+// isDifficulty reports whether word is a difficulty: ★n, *n or a run of ★
+func isDifficulty(word string) bool {
+	return strings.HasPrefix(word, "★") || (len(word) > 1 && word[0] == '*' && word[1] >= '0' && word[1] <= '9')
+}
+
+func parseDifficulty(word string) (int, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(word, "★"), "*")
+	n := 1
+	if rest != "" {
+		if strings.Trim(rest, "★") == "" {
+			n += utf8.RuneCountInString(rest)
+		} else {
+			var err error
+			if n, err = strconv.Atoi(rest); err != nil {
+				return 0, fmt.Errorf("无法识别难度 %s", word)
+			}
+		}
+	}
+	if n < 0 || n > 5 {
+		return 0, fmt.Errorf("难度须为 0-5 星")
+	}
+	return n, nil
+}
+
+// boundUser returns the user bound to chatID, telling the chat when there is none
+func (b *Bot) boundUser(chatID int64) *model.User {
+	user, err := b.userModel.FindByTgChatID(chatID)
+	if err != nil {
+		b.SendMessage(chatID, "❌ 数据库查询失败")
+		log.Printf("Error finding user by chat ID: %v", err)
+		return nil
+	}
+
+	if user == nil {
+		b.SendMessage(chatID, "❌ 账号未绑定。请使用 /start &lt;绑定码&gt; 进行绑定。")
+	}
+	return user
+}
+
+func (b *Bot) handleAdd(chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		b.SendMessage(chatID, "用法：/add &lt;标题&gt; [★n] [#标签] [due:截止]\n例如：/add 读完第三章 ★2 #intelligence due:tomorrow 18:00")
+		return
+	}
+
+	draft, err := ParseTaskDraft(args)
+	if err != nil {
+		b.SendMessage(chatID, "❌ "+html.EscapeString(err.Error()))
+		return
+	}
+	if draft.Title == "" {
+		b.SendMessage(chatID, "❌ 请填写任务标题")
+		return
+	}
+
+	user := b.boundUser(chatID)
+	if user == nil {
+		return
+	}
+
+	if b.taskManager == nil {
+		b.SendMessage(chatID, "❌ 系统未就绪")
+		log.Printf("Task manager not set")
+		return
+	}
+
+	message, err := b.taskManager.AddTask(user.ID, draft)
+	if err != nil {
+		b.SendMessage(chatID, fmt.Sprintf("❌ 创建失败：%s", html.EscapeString(err.Error())))
+		log.Printf("Error adding task: %v", err)
+		return
+	}
+
+	b.SendMessage(chatID, html.EscapeString(message))
+}
+
+func (b *Bot) handleDone(chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		b.SendMessage(chatID, "用法：/done &lt;标题或难度&gt; [#标签]\n例如：/done 晨跑 ★1 #体魄，或 /done ★3")
+		return
+	}
+
+	draft, err := ParseTaskDraft(args)
+	if err != nil {
+		b.SendMessage(chatID, "❌ "+html.EscapeString(err.Error()))
+		return
+	}
+
+	user := b.boundUser(chatID)
+	if user == nil {
+		return
+	}
+
+	if b.taskManager == nil {
+		b.SendMessage(chatID, "❌ 系统未就绪")
+		log.Printf("Task manager not set")
+		return
+	}
+
+	message, err := b.taskManager.DoneTask(user.ID, draft)
+	if err != nil {
+		b.SendMessage(chatID, fmt.Sprintf("❌ 完成失败：%s", html.EscapeString(err.Error())))
+		log.Printf("Error completing task: %v", err)
+		return
+	}
+
+	b.SendMessage(chatID, html.EscapeString(message))
+}
+
+func (b *Bot) handleStatus(chatID int64) {
+	user := b.boundUser(chatID)
+	if user == nil {
+		return
+	}
+
+	if b.charViewer == nil {
+		b.SendMessage(chatID, "❌ 系统未就绪")
+		log.Printf("Character viewer not set")
+		return
+	}
+
+	status, err := b.charViewer.CharacterStatus(user.ID)
+	if err != nil {
+		b.SendMessage(chatID, "❌ 获取角色失败")
+		log.Printf("Error getting character: %v", err)
+		return
+	}
+
+	b.SendMessage(chatID, formatStatus(status))
+}
+
+func formatStatus(status *CharacterStatus) string {
+	text := fmt.Sprintf("🧘 %s\n\n", html.EscapeString(status.Title))
+	text += fmt.Sprintf("💎 灵石：%d", status.SpiritStones)
+	if status.SavedSpiritStones > 0 {
+		text += fmt.Sprintf("（储蓄中 %d）", status.SavedSpiritStones)
+	}
+	text += fmt.Sprintf("\n😮‍💨 疲劳：%d/%d\n\n", status.Fatigue, status.FatigueCap)
+
+	for _, attr := range status.Attributes {
+		text += fmt.Sprintf("%s %s %.1f", attr.Emoji, html.EscapeString(attr.Name), attr.Value)
+		if attr.Realm != "" {
+			text += " · " + html.EscapeString(attr.Realm)
+		}
+		if attr.Bottleneck {
+			text += " ⛰ 瓶颈"
+		}
+		if attr.TodayGain > 0 {
+			text += fmt.Sprintf("（今日 +%.2f）", attr.TodayGain)
+		}
+		text += "\n"
+	}
+
+	if len(status.Buffs) > 0 {
+		text += "\n✨ 增益：" + html.EscapeString(strings.Join(status.Buffs, "、"))
+	}
+	return strings.TrimRight(text, "\n")
+}
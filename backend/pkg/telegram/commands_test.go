@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTaskDraft(t *testing.T) {
+	tests := []struct {
+		args string
+		want TaskDraft
+	}{
+		{"读完第三章 ★2 #intelligence due:tomorrow", TaskDraft{Title: "读完第三章", Difficulty: 2, Tags: []string{"intelligence"}, Due: "tomorrow"}},
+		{"交报告 due:2026-10-20 18:00", TaskDraft{Title: "交报告", Difficulty: -1, Due: "2026-10-20 18:00"}},
+		{"due:明天 9:30 晨跑", TaskDraft{Title: "晨跑", Difficulty: -1, Due: "明天 9:30"}},
+		{"交报告 due:2026-10-20T18:00", TaskDraft{Title: "交报告", Difficulty: -1, Due: "2026-10-20T18:00"}},
+		{"due:18:00 12:00 午饭", TaskDraft{Title: "12:00 午饭", Difficulty: -1, Due: "18:00"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTaskDraft(tt.args)
+		if err != nil {
+			t.Errorf("ParseTaskDraft(%q) error = %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTaskDraft(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// What a ShopEntry sells
+const (
+	ShopItem   = "item"
+	ShopBundle = "bundle"
+)
+
+// ShopEntry is one item or bundle listed by /shop
+type ShopEntry struct {
+	Kind          string // ShopItem or ShopBundle
+	ID            int64
+	Name          string
+	Icon          string
+	Price         int // Charged now
+	OriginalPrice int // Before the discount, or of the bundled items bought separately
+	Stock         int // -1 for unlimited
+}
+
+// BagEntry is one inventory item listed by /bag
+type BagEntry struct {
+	ItemID           int64
+	Name             string
+	Icon             string
+	Quantity         int
+	Usable           bool
+	SellPrice        int // 0 when it can't be sold
+	Equipped         bool
+	ExpiresAt        time.Time // Earliest expiry in the user's timezone, zero when nothing expires
+	ExpiringQuantity int
+}
+
+func (b *Bot) handleShop(chatID int64) {
+	user := b.boundUser(chatID)
+	if user == nil {
+		return
+	}
+
+	if b.shopService == nil {
+		b.SendMessage(chatID, "❌ 系统未就绪")
+		log.Printf("Shop service not set")
+		return
+	}
+
+	text, keyboard, err := b.shopMessage(user.ID)
+	if err != nil {
+		b.SendMessage(chatID, "❌ 获取商店失败")
+		log.Printf("Error getting shop items: %v", err)
+		return
+	}
+	b.sendList(chatID, text, keyboard)
+}
+
+func (b *Bot) handleBag(chatID int64) {
+	user := b.boundUser(chatID)
+	if user == nil {
+		return
+	}
+
+	if b.shopService == nil {
+		b.SendMessage(chatID, "❌ 系统未就绪")
+		log.Printf("Shop service not set")
+		return
+	}
+
+	text, keyboard, err := b.bagMessage(user.ID)
+	if err != nil {
+		b.SendMessage(chatID, "❌ 获取背包失败")
+		log.Printf("Error getting inventory: %v", err)
+		return
+	}
+	b.sendList(chatID, text, keyboard)
+}
+
+// sendList sends a list, with its buttons when it has any
+func (b *Bot) sendList(chatID int64, text string, keyboard [][]tgbotapi.InlineKeyboardButton) {
+	if len(keyboard) == 0 {
+		b.SendMessage(chatID, text)
+		return
+	}
+	b.SendMessageWithKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// editList replaces a list sent by sendList after one of its buttons was used
+func (b *Bot) editList(chatID int64, messageID int, text string, keyboard [][]tgbotapi.InlineKeyboardButton) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = tgbotapi.ModeHTML
+	if len(keyboard) > 0 {
+		markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+		edit.ReplyMarkup = &markup
+	}
+	b.api.Send(edit)
+}
+
+func (b *Bot) shopMessage(userID int64) (string, [][]tgbotapi.InlineKeyboardButton, error) {
+	entries, err := b.shopService.ShopItems(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	text := "🏪 商店"
+	if stats, err := b.charModel.FindByUserID(userID); err == nil && stats != nil {
+		text += fmt.Sprintf("（💎 %d灵石）", stats.SpiritStones)
+	}
+	text += "\n\n"
+	if len(entries) == 0 {
+		return text + "📭 商店里还没有商品。", nil, nil
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, e := range entries {
+		name := html.EscapeString(e.Icon + e.Name)
+		if e.Kind == ShopBundle {
+			name = "🎁 " + name
+		}
+		text += fmt.Sprintf("• %s — %d灵石", name, e.Price)
+		if e.OriginalPrice > e.Price {
+			text += fmt.Sprintf(" <s>%d</s>", e.OriginalPrice)
+		}
+		switch {
+		case e.Stock == 0:
+			text += "（售罄）"
+		case e.Stock > 0:
+			text += fmt.Sprintf("（剩 %d）", e.Stock)
+		}
+		text += "\n"
+
+		if e.Stock != 0 {
+			buyBtn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🛒 %s%s", e.Icon, e.Name), fmt.Sprintf("buy:%s:%d", e.Kind, e.ID))
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(buyBtn))
+		}
+	}
+
+	return text, keyboard, nil
+}
+
+func (b *Bot) bagMessage(userID int64) (string, [][]tgbotapi.InlineKeyboardButton, error) {
+	entries, err := b.shopService.Inventory(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	text := "🎒 背包\n\n"
+	if len(entries) == 0 {
+		return text + "📭 背包是空的。使用 /shop 逛逛商店。", nil, nil
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, e := range entries {
+		text += fmt.Sprintf("• %s ×%d", html.EscapeString(e.Icon+e.Name), e.Quantity)
+		if e.Equipped {
+			text += "（已装备）"
+		}
+		text += "\n"
+		if !e.ExpiresAt.IsZero() {
+			text += fmt.Sprintf("  ⏳ %d 个于 %s 过期\n", e.ExpiringQuantity, e.ExpiresAt.Format("01-02 15:04"))
+		}
+
+		var row []tgbotapi.InlineKeyboardButton
+		if e.Usable {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✨ 使用 %s", e.Name), fmt.Sprintf("use:%d", e.ItemID)))
+		}
+		if e.SellPrice > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("💰 出售 +%d", e.SellPrice), fmt.Sprintf("sell:%d", e.ItemID)))
+		}
+		if len(row) > 0 {
+			keyboard = append(keyboard, row)
+		}
+	}
+
+	return text, keyboard, nil
+}
+
+func (b *Bot) handleBuyItemCallback(c *callback) {
+	b.handleBuy(c, ShopItem)
+}
+
+func (b *Bot) handleBuyBundleCallback(c *callback) {
+	b.handleBuy(c, ShopBundle)
+}
+
+func (b *Bot) handleBuy(c *callback, kind string) {
+	if b.shopService == nil {
+		b.SendMessage(c.chatID, "❌ 系统未就绪")
+		log.Printf("Shop service not set")
+		return
+	}
+
+	message, err := b.shopService.Buy(c.user.ID, kind, c.ids[0])
+	if err != nil {
+		b.SendMessage(c.chatID, fmt.Sprintf("❌ 购买失败：%s", html.EscapeString(err.Error())))
+		log.Printf("Error buying %s %d: %v", kind, c.ids[0], err)
+		return
+	}
+	b.SendMessage(c.chatID, "🛒 "+html.EscapeString(message))
+
+	// Stock and balance changed
+	if text, keyboard, err := b.shopMessage(c.user.ID); err == nil {
+		b.editList(c.chatID, c.messageID, text, keyboard)
+	}
+}
+
+func (b *Bot) handleUseCallback(c *callback) {
+	if b.shopService == nil {
+		b.SendMessage(c.chatID, "❌ 系统未就绪")
+		log.Printf("Shop service not set")
+		return
+	}
+
+	message, err := b.shopService.UseItem(c.user.ID, c.ids[0])
+	if err != nil {
+		b.SendMessage(c.chatID, fmt.Sprintf("❌ 使用失败：%s", html.EscapeString(err.Error())))
+		log.Printf("Error using item %d: %v", c.ids[0], err)
+		return
+	}
+	b.SendMessage(c.chatID, "✨ "+html.EscapeString(message))
+	b.refreshBagMessage(c)
+}
+
+func (b *Bot) handleSellCallback(c *callback) {
+	if b.shopService == nil {
+		b.SendMessage(c.chatID, "❌ 系统未就绪")
+		log.Printf("Shop service not set")
+		return
+	}
+
+	message, err := b.shopService.SellItem(c.user.ID, c.ids[0])
+	if err != nil {
+		b.SendMessage(c.chatID, fmt.Sprintf("❌ 出售失败：%s", html.EscapeString(err.Error())))
+		log.Printf("Error selling item %d: %v", c.ids[0], err)
+		return
+	}
+	b.SendMessage(c.chatID, "💰 "+html.EscapeString(message))
+	b.refreshBagMessage(c)
+}
+
+// refreshBagMessage edits the original /bag message to show the remaining items
+func (b *Bot) refreshBagMessage(c *callback) {
+	text, keyboard, err := b.bagMessage(c.user.ID)
+	if err != nil {
+		log.Printf("Error refreshing inventory: %v", err)
+		return
+	}
+	b.editList(c.chatID, c.messageID, text, keyboard)
+}
@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// WebhookPath is where the API server receives updates in webhook mode
+const WebhookPath = "/api/telegram/webhook"
+
+// SecretTokenHeader carries the secret token Telegram sends with every webhook request
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Telegram accepts 1-256 characters from this set as a secret token
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// webhookBuffer is how many received updates may wait for the dispatcher
+const webhookBuffer = 100
+
+// Options configures how the bot reaches Telegram and receives updates
+type Options struct {
+	APIEndpoint   string // Bot API endpoint format, defaults to tgbotapi.APIEndpoint
+	WebhookURL    string // Public URL of the webhook route; empty for long polling
+	WebhookSecret string // Required with WebhookURL
+}
+
+func (o Options) validate() error {
+	if o.WebhookURL == "" {
+		return nil
+	}
+	if !secretTokenPattern.MatchString(o.WebhookSecret) {
+		return fmt.Errorf("webhook secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	return nil
+}
+
+// Webhook reports whether updates arrive by webhook instead of long polling
+func (b *Bot) Webhook() bool {
+	return b.webhookURL != ""
+}
+
+// setWebhook points Telegram at the webhook route. The vendored client has no
+// secret token in its webhook config, so the request is made directly.
+func (b *Bot) setWebhook() error {
+	params := tgbotapi.Params{
+		"url":             b.webhookURL,
+		"secret_token":    b.webhookSecret,
+		"allowed_updates": `["message","callback_query"]`,
+	}
+	_, err := b.api.MakeRequest("setWebhook", params)
+	return err
+}
+
+// ServeWebhook receives one update posted by Telegram and queues it for the
+// same handlers long polling feeds. Requests without the secret token are
+// rejected; when the queue is full Telegram is told to retry later.
+func (b *Bot) ServeWebhook(w http.ResponseWriter, r *http.Request) {
+	if !b.Webhook() || b.webhookUpdates == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(SecretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(b.webhookSecret)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case b.webhookUpdates <- update:
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
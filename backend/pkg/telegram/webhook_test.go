@@ -0,0 +1,211 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testToken   = "123:test-token"
+	testSecret  = "webhook_secret-1"
+	testWebhook = "https://life.example.com" + WebhookPath
+)
+
+// apiCall is one request the fake Bot API received
+type apiCall struct {
+	method string
+	params url.Values
+}
+
+// newFakeAPI serves the Bot API methods the bot calls and reports each call
+func newFakeAPI(t *testing.T) (endpoint string, calls <-chan apiCall) {
+	t.Helper()
+
+	ch := make(chan apiCall, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/bot" + testToken + "/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, prefix)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch method {
+		case "getMe":
+			w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"Life","username":"life_bot"}}`))
+			return
+		case "sendMessage":
+			w.Write([]byte(`{"ok":true,"result":{"message_id":2,"chat":{"id":99,"type":"private"},"date":0}}`))
+		default:
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+		ch <- apiCall{method: method, params: r.PostForm}
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/bot%s/%s", ch
+}
+
+func nextCall(t *testing.T, calls <-chan apiCall) apiCall {
+	t.Helper()
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(5 * time.Second):
+		t.Fatal("no Bot API call")
+		return apiCall{}
+	}
+}
+
+// newWebhookBot starts a bot in webhook mode against the fake API, with its
+// webhook route served over HTTP
+func newWebhookBot(t *testing.T) (*Bot, *httptest.Server, <-chan apiCall) {
+	t.Helper()
+
+	endpoint, calls := newFakeAPI(t)
+	bot, err := NewBot(testToken, nil, Options{
+		APIEndpoint:   endpoint,
+		WebhookURL:    testWebhook,
+		WebhookSecret: testSecret,
+	})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	webhook := httptest.NewServer(http.HandlerFunc(bot.ServeWebhook))
+	t.Cleanup(webhook.Close)
+
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return bot, webhook, calls
+}
+
+func postUpdate(t *testing.T, webhook *httptest.Server, secret, body string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(SecretTokenHeader, secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post update: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+const helpUpdate = `{"update_id":1,"message":{"message_id":1,"date":0,"chat":{"id":99,"type":"private"},` +
+	`"text":"/help","entities":[{"type":"bot_command","offset":0,"length":5}]}}`
+
+func TestStartSetsWebhook(t *testing.T) {
+	bot, _, calls := newWebhookBot(t)
+
+	if !bot.Webhook() {
+		t.Fatal("bot is not in webhook mode")
+	}
+	call := nextCall(t, calls)
+	if call.method != "setWebhook" {
+		t.Fatalf("first call = %s, want setWebhook", call.method)
+	}
+	if got := call.params.Get("url"); got != testWebhook {
+		t.Errorf("url = %q, want %q", got, testWebhook)
+	}
+	if got := call.params.Get("secret_token"); got != testSecret {
+		t.Errorf("secret_token = %q, want %q", got, testSecret)
+	}
+}
+
+func TestServeWebhookRejectsWrongSecret(t *testing.T) {
+	_, webhook, calls := newWebhookBot(t)
+	nextCall(t, calls) // setWebhook
+
+	for _, secret := range []string{"", "wrong", testSecret + "x"} {
+		if status := postUpdate(t, webhook, secret, helpUpdate); status != http.StatusUnauthorized {
+			t.Errorf("secret %q: status %d, want 401", secret, status)
+		}
+	}
+
+	select {
+	case call := <-calls:
+		t.Errorf("rejected update reached the bot, which called %s", call.method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServeWebhookHandlesCommand(t *testing.T) {
+	_, webhook, calls := newWebhookBot(t)
+	nextCall(t, calls) // setWebhook
+
+	if status := postUpdate(t, webhook, testSecret, helpUpdate); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+
+	call := nextCall(t, calls)
+	if call.method != "sendMessage" {
+		t.Fatalf("call = %s, want sendMessage", call.method)
+	}
+	if got := call.params.Get("chat_id"); got != "99" {
+		t.Errorf("chat_id = %q, want 99", got)
+	}
+	if got := call.params.Get("text"); !strings.Contains(got, "帮助菜单") {
+		t.Errorf("text = %q, want the help menu", got)
+	}
+}
+
+func TestServeWebhookRejectsBadRequests(t *testing.T) {
+	_, webhook, calls := newWebhookBot(t)
+	nextCall(t, calls) // setWebhook
+
+	if status := postUpdate(t, webhook, testSecret, "{"); status != http.StatusBadRequest {
+		t.Errorf("malformed update: status %d, want 400", status)
+	}
+
+	resp, err := http.Get(webhook.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestServeWebhookBeforeStart(t *testing.T) {
+	endpoint, _ := newFakeAPI(t)
+	bot, err := NewBot(testToken, nil, Options{APIEndpoint: endpoint, WebhookURL: testWebhook, WebhookSecret: testSecret})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(helpUpdate))
+	req.Header.Set(SecretTokenHeader, testSecret)
+	rec := httptest.NewRecorder()
+	bot.ServeWebhook(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+}
+
+func TestNewBotRequiresWebhookSecret(t *testing.T) {
+	for _, secret := range []string{"", "has space", strings.Repeat("a", 257)} {
+		_, err := NewBot(testToken, nil, Options{WebhookURL: testWebhook, WebhookSecret: secret})
+		if err == nil {
+			t.Errorf("secret %q accepted", secret)
+		}
+	}
+}
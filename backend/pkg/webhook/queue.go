@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// Request headers
+const (
+	HeaderEvent     = "X-Life-Event"
+	HeaderDelivery  = "X-Life-Delivery"
+	HeaderTimestamp = "X-Life-Timestamp"
+	HeaderSignature = "X-Life-Signature"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is marked failed
+	MaxAttempts = 8
+	// firstRetry doubles after every failed attempt: 1m, 2m, 4m ... ~2h in total
+	firstRetry = time.Minute
+	batchSize  = 50
+	// workers bounds the deliveries posted at once, and requestTimeout how
+	// long each may take, so dead endpoints cannot stall a run
+	workers        = 4
+	requestTimeout = 5 * time.Second
+)
+
+// Queue delivers the persisted webhook deliveries, retrying failures with
+// exponential backoff.
+type Queue struct {
+	store  *model.WebhookModel
+	client *http.Client
+
+	mu      sync.Mutex
+	running bool           // A DeliverDue run is in progress
+	sending map[int64]bool // Deliveries being posted, so none is sent twice
+}
+
+func NewQueue(store *model.WebhookModel) *Queue {
+	return &Queue{
+		store:   store,
+		client:  &http.Client{Timeout: requestTimeout},
+		sending: make(map[int64]bool),
+	}
+}
+
+// Kick delivers due deliveries in the background, e.g. right after events
+// were enqueued instead of waiting for the scheduler. When a run is already
+// in progress the new deliveries wait for the next one.
+func (q *Queue) Kick() {
+	go func() {
+		if _, err := q.DeliverDue(time.Now()); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+	}()
+}
+
+// DeliverDue attempts every pending delivery that is due and returns how
+// many were attempted. It returns right away when another run is in
+// progress. A delivery that fails is logged and retried later without
+// holding up the others.
+func (q *Queue) DeliverDue(now time.Time) (int, error) {
+	q.mu.Lock()
+	if q.running {
+		q.mu.Unlock()
+		return 0, nil
+	}
+	q.running = true
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.running = false
+		q.mu.Unlock()
+	}()
+
+	due, err := q.store.FindDue(now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	hooks := make(map[int64]*model.Webhook)
+	attempted := 0
+	for _, d := range due {
+		hook, ok := hooks[d.WebhookID]
+		if !ok {
+			if hook, err = q.store.FindByID(d.WebhookID); err != nil {
+				log.Printf("Error loading webhook %d for delivery %d: %v", d.WebhookID, d.ID, err)
+				continue
+			}
+			hooks[d.WebhookID] = hook
+		}
+		if !q.claim(d.ID) {
+			continue
+		}
+		attempted++
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(hook *model.Webhook, d *model.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer q.release(d.ID)
+
+			if err := q.deliver(hook, d, true); err != nil {
+				log.Printf("Error recording webhook delivery %d: %v", d.ID, err)
+			}
+		}(hook, d)
+	}
+	wg.Wait()
+
+	return attempted, nil
+}
+
+// DeliverOnce attempts a delivery right away without retrying on failure.
+// Used for test deliveries, whose result is shown to the user.
+func (q *Queue) DeliverOnce(hook *model.Webhook, d *model.WebhookDelivery) error {
+	if !q.claim(d.ID) {
+		return fmt.Errorf("delivery %d is already being sent", d.ID)
+	}
+	defer q.release(d.ID)
+
+	return q.deliver(hook, d, false)
+}
+
+// claim marks a delivery as being sent. It reports false when it already is.
+func (q *Queue) claim(id int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.sending[id] {
+		return false
+	}
+	q.sending[id] = true
+	return true
+}
+
+func (q *Queue) release(id int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.sending, id)
+}
+
+// deliver posts d and records the attempt on d and in the store
+func (q *Queue) deliver(hook *model.Webhook, d *model.WebhookDelivery, retry bool) error {
+	var code int
+	var sendErr error
+	if hook == nil || !hook.Enabled {
+		// Webhook disabled since the event was queued
+		sendErr = fmt.Errorf("webhook disabled")
+		retry = false
+	} else {
+		code, sendErr = q.post(hook, d)
+	}
+
+	d.Attempts++
+	d.ResponseCode = code
+	d.Error = ""
+	d.NextAttemptAt.Valid = false
+	switch {
+	case sendErr == nil:
+		d.Status = model.WebhookDeliverySuccess
+		d.DeliveredAt.Time, d.DeliveredAt.Valid = time.Now(), true
+	case retry && d.Attempts < MaxAttempts:
+		d.Status = model.WebhookDeliveryPending
+		d.Error = sendErr.Error()
+		d.NextAttemptAt.Time, d.NextAttemptAt.Valid = time.Now().Add(firstRetry<<(d.Attempts-1)), true
+	default:
+		d.Status = model.WebhookDeliveryFailed
+		d.Error = sendErr.Error()
+	}
+
+	return q.store.RecordAttempt(d.ID, d.Status, d.ResponseCode, d.Error, d.NextAttemptAt.Time)
+}
+
+// post sends the signed payload. Any 2xx response counts as delivered.
+func (q *Queue) post(hook *model.Webhook, d *model.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LifeSystem-Webhook/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
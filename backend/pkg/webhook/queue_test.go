@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+func newTestStore(t *testing.T) *model.WebhookModel {
+	t.Helper()
+
+	db, err := model.NewDB(filepath.Join(t.TempDir(), "life.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := model.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, username, password_hash) VALUES (1, 'tester', 'x')`); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return model.NewWebhookModel(db)
+}
+
+func enqueue(t *testing.T, store *model.WebhookModel, url string, n int) int64 {
+	t.Helper()
+
+	hookID, err := store.Create(&model.Webhook{UserID: 1, Name: url, URL: url, Secret: "secret", Events: []string{"ping"}, Enabled: true})
+	if err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := store.Enqueue(&model.WebhookDelivery{WebhookID: hookID, UserID: 1, Event: "ping", Payload: `{}`}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	return hookID
+}
+
+func TestDeliverDueDoesNotWaitForDeadEndpoints(t *testing.T) {
+	release := make(chan struct{})
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer dead.Close()
+	defer close(release)
+	alive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer alive.Close()
+
+	store := newTestStore(t)
+	deadID := enqueue(t, store, dead.URL, workers*2)
+	aliveID := enqueue(t, store, alive.URL, 3)
+
+	queue := NewQueue(store)
+	queue.client.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	attempted, err := queue.DeliverDue(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+	if attempted != workers*2+3 {
+		t.Errorf("attempted %d deliveries, want %d", attempted, workers*2+3)
+	}
+	// Sequential posting would take one timeout per dead delivery
+	if elapsed := time.Since(start); elapsed > time.Duration(workers*2)*queue.client.Timeout {
+		t.Errorf("DeliverDue() took %v", elapsed)
+	}
+
+	deliveries, err := store.FindDeliveries(aliveID, 10)
+	if err != nil {
+		t.Fatalf("find deliveries: %v", err)
+	}
+	for _, d := range deliveries {
+		if d.Status != model.WebhookDeliverySuccess {
+			t.Errorf("delivery %d to the live endpoint is %s, want success", d.ID, d.Status)
+		}
+	}
+
+	deliveries, err = store.FindDeliveries(deadID, 10)
+	if err != nil {
+		t.Fatalf("find deliveries: %v", err)
+	}
+	for _, d := range deliveries {
+		if d.Status != model.WebhookDeliveryPending || d.Attempts != 1 || !d.NextAttemptAt.Valid {
+			t.Errorf("delivery %d to the dead endpoint is %s after %d attempts, want a scheduled retry", d.ID, d.Status, d.Attempts)
+		}
+	}
+}
+
+func TestDeliverDueSkipsOverlappingRuns(t *testing.T) {
+	store := newTestStore(t)
+	queue := NewQueue(store)
+
+	queue.running = true
+	attempted, err := queue.DeliverDue(time.Now())
+	if err != nil || attempted != 0 {
+		t.Errorf("DeliverDue() = %d, %v during another run, want 0, nil", attempted, err)
+	}
+}
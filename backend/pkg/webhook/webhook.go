@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"life-system-backend/internal/model"
+)
+
+// Event is a game event webhooks can subscribe to.
+type Event string
+
+const (
+	EventTaskCompleted     Event = "task_completed"
+	EventTaskFailed        Event = "task_failed"        // Challenge task missed its deadline
+	EventRealmBreakthrough Event = "realm_breakthrough" // An attribute reached the next realm
+	EventPurchase          Event = "purchase"           // Shop item or bundle bought
+	EventAttributeDecay    Event = "attribute_decay"    // Inactivity decay, or a shield absorbing it
+	EventPing              Event = "ping"               // Test delivery, always sent
+)
+
+// EventInfo describes a subscribable event.
+type EventInfo struct {
+	Event Event
+	Name  string
+}
+
+// Events lists the subscribable events in display order.
+var Events = []EventInfo{
+	{Event: EventTaskCompleted, Name: "任务完成"},
+	{Event: EventTaskFailed, Name: "挑战失败"},
+	{Event: EventRealmBreakthrough, Name: "境界突破"},
+	{Event: EventPurchase, Name: "商店购买"},
+	{Event: EventAttributeDecay, Name: "属性衰减"},
+}
+
+// LookupEvent returns the description of a subscribable event
+func LookupEvent(event Event) (EventInfo, bool) {
+	for _, info := range Events {
+		if info.Event == event {
+			return info, true
+		}
+	}
+	return EventInfo{}, false
+}
+
+// Payload is the JSON body posted to webhooks
+type Payload struct {
+	Event     Event       `json:"event"`
+	UserID    int64       `json:"userId"`
+	CreatedAt string      `json:"createdAt"` // RFC 3339
+	Data      interface{} `json:"data"`
+}
+
+// Enqueue queues event for every enabled webhook of the user subscribed to
+// it. Pass the model of a unit of work so the event commits together with
+// the change that caused it.
+func Enqueue(store *model.WebhookModel, userID int64, event Event, data interface{}) error {
+	hooks, err := store.FindSubscribed(userID, string(event))
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	payload, err := NewPayload(userID, event, data)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		_, err := store.Enqueue(&model.WebhookDelivery{
+			WebhookID: hook.ID,
+			UserID:    userID,
+			Event:     string(event),
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewPayload renders the JSON body of an event
+func NewPayload(userID int64, event Event, data interface{}) (string, error) {
+	body, err := json.Marshal(&Payload{
+		Event:     event,
+		UserID:    userID,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Data:      data,
+	})
+	return string(body), err
+}
+
+// Sign returns the signature header value for a payload: HMAC-SHA256 over
+// "<timestamp>.<body>" keyed with the webhook secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign, for receivers written in Go
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}